@@ -34,6 +34,7 @@ func main() {
 		database.DatabaseModule(),
 		fxModules.NATSModule,
 		fxModules.EventsModule,
+		fxModules.WebhooksModule,
 		temporal.TemporalModule(),
 		fxModules.MiddlewareModule,
 		fxModules.HandlersModule,
@@ -3,6 +3,9 @@ package configs
 import (
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
@@ -38,10 +41,73 @@ type AppConfig struct {
 	TemporalNamespace string `env:"APP_TEMPORAL_NAMESPACE,required"`
 	TemporalTLS       bool   `env:"APP_TEMPORAL_TLS,required"`
 
+	// Task queue of catalog workflows that do not declare one
+	TemporalTaskQueue string `env:"APP_TEMPORAL_TASK_QUEUE" envDefault:"default"`
+	// Task queue of the notification activities; empty keeps them on the workflow's queue
+	TemporalNotificationTaskQueue string `env:"APP_TEMPORAL_NOTIFICATION_TASK_QUEUE"`
+	// Workers this process runs, one per task queue, as queue:option=value,... entries separated by ";"
+	TemporalWorkers []string `env:"APP_TEMPORAL_WORKERS" envSeparator:";" envDefault:"default"`
+	// Worker Deployment Versioning; without a build id the workers are unversioned
+	TemporalDeploymentName string `env:"APP_TEMPORAL_DEPLOYMENT_NAME" envDefault:"core-api"`
+	TemporalBuildID        string `env:"APP_TEMPORAL_BUILD_ID"`
+	// pinned keeps a run on the build that started it; auto_upgrade moves it to the current build
+	TemporalVersioningBehavior string `env:"APP_TEMPORAL_VERSIONING_BEHAVIOR" envDefault:"pinned"`
+
 	NATSUrl string `env:"APP_NATS_URL,required"`
 
+	OutboxStream       string        `env:"APP_OUTBOX_STREAM" envDefault:"user_events"`
+	OutboxPollInterval time.Duration `env:"APP_OUTBOX_POLL_INTERVAL" envDefault:"1s"`
+	OutboxBatchSize    int           `env:"APP_OUTBOX_BATCH_SIZE" envDefault:"100"`
+	OutboxMaxBackoff   time.Duration `env:"APP_OUTBOX_MAX_BACKOFF" envDefault:"5m"`
+	OutboxRetention    time.Duration `env:"APP_OUTBOX_RETENTION" envDefault:"168h"`
+
+	// Stream of the workflow.> events, such as workflow.completed
+	WorkflowEventsStream string `env:"APP_WORKFLOW_EVENTS_STREAM" envDefault:"workflow_events"`
+
+	// Outbound webhooks to user endpoints; a delivery is retried with doubling backoff until WebhookMaxAttempts
+	WebhookPollInterval time.Duration `env:"APP_WEBHOOK_POLL_INTERVAL" envDefault:"2s"`
+	WebhookBatchSize    int           `env:"APP_WEBHOOK_BATCH_SIZE" envDefault:"20"`
+	WebhookTimeout      time.Duration `env:"APP_WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookMaxAttempts  int           `env:"APP_WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+	WebhookRetryBackoff time.Duration `env:"APP_WEBHOOK_RETRY_BACKOFF" envDefault:"30s"`
+	WebhookMaxBackoff   time.Duration `env:"APP_WEBHOOK_MAX_BACKOFF" envDefault:"1h"`
+	// Lets endpoints resolve to loopback and private addresses; only for local development
+	WebhookAllowPrivateNetworks bool `env:"APP_WEBHOOK_ALLOW_PRIVATE_NETWORKS" envDefault:"false"`
+
+	MastraBaseURL        string        `env:"APP_MASTRA_BASE_URL" envDefault:"http://localhost:4111"`
+	MastraAPIKey         string        `env:"APP_MASTRA_API_KEY"`
+	MastraWorkflowID     string        `env:"APP_MASTRA_WORKFLOW_ID" envDefault:"financialWorkflow"`
+	MastraRequestTimeout time.Duration `env:"APP_MASTRA_REQUEST_TIMEOUT" envDefault:"4m"`
+	MastraBreakerTimeout time.Duration `env:"APP_MASTRA_BREAKER_TIMEOUT" envDefault:"60s"`
+	// Run Mastra workflows through its stream endpoint to relay steps and tokens while they run;
+	// off until the Mastra deployment serves it
+	MastraStream bool `env:"APP_MASTRA_STREAM" envDefault:"false"`
+
+	// Base URL of the web app; notifications link to the execution under it
+	AppPublicURL string `env:"APP_PUBLIC_URL" envDefault:"http://localhost:3000"`
+
+	// Preview warns when a schedule runs more often than the user's plan allows, as plan:duration pairs;
+	// unknown plans get the free interval, and ScheduleMinInterval applies when there is none
+	ScheduleMinIntervals []string      `env:"APP_SCHEDULE_MIN_INTERVALS" envSeparator:"," envDefault:"free:1h,pro:15m,enterprise:5m"`
+	ScheduleMinInterval  time.Duration `env:"APP_SCHEDULE_MIN_INTERVAL" envDefault:"15m"`
+
+	// Reconciler between kainos_user_workflow and Temporal schedules; an interval of 0 only runs it on startup
+	ScheduleReconcileInterval time.Duration `env:"APP_SCHEDULE_RECONCILE_INTERVAL" envDefault:"10m"`
+	ScheduleReconcileDryRun   bool          `env:"APP_SCHEDULE_RECONCILE_DRY_RUN" envDefault:"false"`
+
+	// Run-now requests are refused while a user has this many executions RUNNING
+	MaxRunningWorkflowsPerUser int `env:"APP_MAX_RUNNING_WORKFLOWS_PER_USER" envDefault:"3"`
+
+	// How many user workflows each plan may have, as plan:limit pairs; unknown plans get the free limit
+	WorkflowInstanceLimits []string `env:"APP_WORKFLOW_INSTANCE_LIMITS" envSeparator:"," envDefault:"free:5,pro:20,enterprise:100"`
+
+	// Clerk user ids allowed on /api/v1/admin routes
+	AdminUserIDs []string `env:"APP_ADMIN_USER_IDS" envSeparator:","`
+
 	SvixSecret string `env:"APP_SVIX_SECRET,required"`
 	SvixAppID  string `env:"APP_SVIX_APP_ID,required"`
+	// How long Svix message ids are kept to skip replayed deliveries; Svix retries for about a day
+	WebhookMessageRetention time.Duration `env:"APP_WEBHOOK_MESSAGE_RETENTION" envDefault:"168h"`
 
 	CORSAllowOrigins     []string `env:"CORS_ALLOW_ORIGINS,required" envSeparator:","`
 	CORSAllowMethods     []string `env:"CORS_ALLOW_METHODS,required" envSeparator:","`
@@ -89,3 +155,39 @@ func (c *AppConfig) GetDatabaseDSN() string {
 func (c *AppConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.RedisHost, c.RedisPort)
 }
+
+// WorkflowInstanceLimit returns how many user workflows the plan allows; with no free limit configured it is 0
+func (c *AppConfig) WorkflowInstanceLimit(plan string) int {
+	limit, _ := planValue(c.WorkflowInstanceLimits, plan, strconv.Atoi)
+	return limit
+}
+
+// ScheduleMinIntervalFor returns how often schedules of the plan may run at most
+func (c *AppConfig) ScheduleMinIntervalFor(plan string) time.Duration {
+	if interval, ok := planValue(c.ScheduleMinIntervals, plan, time.ParseDuration); ok {
+		return interval
+	}
+	return c.ScheduleMinInterval
+}
+
+// planValue looks plan up in plan:value entries, falling back to free; malformed entries are skipped
+func planValue[T any](entries []string, plan string, parse func(string) (T, error)) (T, bool) {
+	values := make(map[string]T, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			continue
+		}
+		parsed, err := parse(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(name)] = parsed
+	}
+
+	if value, ok := values[plan]; ok {
+		return value, true
+	}
+	value, ok := values["free"]
+	return value, ok
+}
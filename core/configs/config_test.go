@@ -0,0 +1,56 @@
+package configs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkflowInstanceLimit(t *testing.T) {
+	cfg := &AppConfig{WorkflowInstanceLimits: []string{"free:5", " pro : 20 ", "broken", "team:x"}}
+
+	tests := []struct {
+		plan string
+		want int
+	}{
+		{"free", 5},
+		{"pro", 20},
+		{"team", 5},
+		{"unknown", 5},
+		{"", 5},
+	}
+	for _, tt := range tests {
+		if got := cfg.WorkflowInstanceLimit(tt.plan); got != tt.want {
+			t.Errorf("WorkflowInstanceLimit(%q) = %d, want %d", tt.plan, got, tt.want)
+		}
+	}
+
+	if got := (&AppConfig{}).WorkflowInstanceLimit("free"); got != 0 {
+		t.Errorf("WorkflowInstanceLimit with no limits = %d, want 0", got)
+	}
+}
+
+func TestScheduleMinIntervalFor(t *testing.T) {
+	cfg := &AppConfig{
+		ScheduleMinIntervals: []string{"free:1h", "pro: 15m", "team:often"},
+		ScheduleMinInterval:  30 * time.Minute,
+	}
+
+	tests := []struct {
+		plan string
+		want time.Duration
+	}{
+		{"free", time.Hour},
+		{"pro", 15 * time.Minute},
+		{"team", time.Hour},
+		{"unknown", time.Hour},
+	}
+	for _, tt := range tests {
+		if got := cfg.ScheduleMinIntervalFor(tt.plan); got != tt.want {
+			t.Errorf("ScheduleMinIntervalFor(%q) = %s, want %s", tt.plan, got, tt.want)
+		}
+	}
+
+	if got := (&AppConfig{ScheduleMinInterval: 30 * time.Minute}).ScheduleMinIntervalFor("pro"); got != 30*time.Minute {
+		t.Errorf("ScheduleMinIntervalFor with no intervals = %s, want APP_SCHEDULE_MIN_INTERVAL", got)
+	}
+}
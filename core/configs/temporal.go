@@ -0,0 +1,116 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TemporalWorkerConfig holds the options of the worker polling one task queue
+type TemporalWorkerConfig struct {
+	TaskQueue string
+
+	MaxConcurrentActivities      int
+	MaxConcurrentWorkflowTasks   int
+	MaxConcurrentLocalActivities int
+	// 0 keeps the SDK's default number of pollers
+	ActivityPollers int
+	WorkflowPollers int
+	// Activities started per second by this worker and by all workers of the queue; 0 is unlimited
+	ActivitiesPerSecond          float64
+	TaskQueueActivitiesPerSecond float64
+}
+
+// defaultTemporalWorkerConfig is what options missing from an APP_TEMPORAL_WORKERS entry fall back to
+func defaultTemporalWorkerConfig(taskQueue string) TemporalWorkerConfig {
+	return TemporalWorkerConfig{
+		TaskQueue:                    taskQueue,
+		MaxConcurrentActivities:      10,
+		MaxConcurrentWorkflowTasks:   10,
+		MaxConcurrentLocalActivities: 10,
+	}
+}
+
+// TemporalWorkerConfigs parses APP_TEMPORAL_WORKERS. Each entry is a task queue optionally followed
+// by options, e.g. "reports:activities=2,activity_pollers=2,activities_per_second=0.5".
+func (c *AppConfig) TemporalWorkerConfigs() ([]TemporalWorkerConfig, error) {
+	workers := make([]TemporalWorkerConfig, 0, len(c.TemporalWorkers))
+	seen := make(map[string]bool, len(c.TemporalWorkers))
+
+	for _, entry := range c.TemporalWorkers {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		taskQueue, options, _ := strings.Cut(entry, ":")
+		taskQueue = strings.TrimSpace(taskQueue)
+		if taskQueue == "" {
+			return nil, fmt.Errorf("APP_TEMPORAL_WORKERS entry %q has no task queue", entry)
+		}
+		if seen[taskQueue] {
+			return nil, fmt.Errorf("APP_TEMPORAL_WORKERS lists task queue %q twice", taskQueue)
+		}
+		seen[taskQueue] = true
+
+		worker := defaultTemporalWorkerConfig(taskQueue)
+		if strings.TrimSpace(options) != "" {
+			for _, option := range strings.Split(options, ",") {
+				if err := worker.set(option); err != nil {
+					return nil, fmt.Errorf("APP_TEMPORAL_WORKERS task queue %q: %w", taskQueue, err)
+				}
+			}
+		}
+		workers = append(workers, worker)
+	}
+
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("APP_TEMPORAL_WORKERS has no task queue")
+	}
+	return workers, nil
+}
+
+func (w *TemporalWorkerConfig) set(option string) error {
+	key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+	if !ok {
+		return fmt.Errorf("option %q is not key=value", option)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+	var target *int
+	var rate *float64
+	switch key {
+	case "activities":
+		target = &w.MaxConcurrentActivities
+	case "workflow_tasks":
+		target = &w.MaxConcurrentWorkflowTasks
+	case "local_activities":
+		target = &w.MaxConcurrentLocalActivities
+	case "activity_pollers":
+		target = &w.ActivityPollers
+	case "workflow_pollers":
+		target = &w.WorkflowPollers
+	case "activities_per_second":
+		rate = &w.ActivitiesPerSecond
+	case "task_queue_activities_per_second":
+		rate = &w.TaskQueueActivitiesPerSecond
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+
+	if target != nil {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("option %s must be a non-negative integer", key)
+		}
+		*target = n
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return fmt.Errorf("option %s must be a non-negative number", key)
+	}
+	*rate = f
+	return nil
+}
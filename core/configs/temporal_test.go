@@ -0,0 +1,52 @@
+package configs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemporalWorkerConfigs(t *testing.T) {
+	cfg := &AppConfig{TemporalWorkers: []string{
+		"default",
+		" reports:activities=2, activity_pollers=4,activities_per_second=0.5",
+		"",
+	}}
+
+	workers, err := cfg.TemporalWorkerConfigs()
+	if err != nil {
+		t.Fatalf("TemporalWorkerConfigs returned error: %v", err)
+	}
+	if len(workers) != 2 {
+		t.Fatalf("expected 2 workers, got %+v", workers)
+	}
+
+	if workers[0] != defaultTemporalWorkerConfig("default") {
+		t.Errorf("expected the defaults for a bare queue, got %+v", workers[0])
+	}
+
+	reports := workers[1]
+	if reports.TaskQueue != "reports" || reports.MaxConcurrentActivities != 2 || reports.ActivityPollers != 4 {
+		t.Errorf("unexpected reports worker %+v", reports)
+	}
+	if reports.ActivitiesPerSecond != 0.5 || reports.MaxConcurrentWorkflowTasks != 10 {
+		t.Errorf("unexpected reports worker %+v", reports)
+	}
+}
+
+func TestTemporalWorkerConfigs_Invalid(t *testing.T) {
+	cases := map[string][]string{
+		"unknown option":    {"default:threads=2"},
+		"not key=value":     {"default:activities"},
+		"negative":          {"default:activities=-1"},
+		"duplicate queue":   {"default", "default:activities=2"},
+		"no queue":          {":activities=2"},
+		"no entries at all": {" "},
+	}
+
+	for name, entries := range cases {
+		_, err := (&AppConfig{TemporalWorkers: entries}).TemporalWorkerConfigs()
+		if err == nil || !strings.Contains(err.Error(), "APP_TEMPORAL_WORKERS") {
+			t.Errorf("%s: expected an APP_TEMPORAL_WORKERS error, got %v", name, err)
+		}
+	}
+}
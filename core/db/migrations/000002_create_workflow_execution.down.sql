@@ -0,0 +1 @@
+DROP TABLE kainos_workflow_execution cascade;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS kainos_workflow_execution (
+    id uuid primary key,
+    user_workflow_id uuid not null references kainos_user_workflow(id),
+    temporal_workflow_id varchar not null,
+    temporal_run_id varchar not null,
+    status varchar not null default 'RUNNING',
+    started_at timestamp not null default now(),
+    finished_at timestamp,
+    output jsonb,
+    error text,
+    attempt int not null default 0,
+    created_at timestamp not null default now(),
+    updated_at timestamp,
+    unique (temporal_workflow_id, temporal_run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_user_workflow_started
+    ON kainos_workflow_execution (user_workflow_id, started_at desc);
@@ -0,0 +1 @@
+DROP TABLE kainos_webhook_message cascade;
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS kainos_webhook_message (
+    svix_id varchar primary key,
+    received_at timestamp not null default now()
+);
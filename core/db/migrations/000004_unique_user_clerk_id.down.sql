@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_kainos_user_clerk_id;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kainos_user_clerk_id ON kainos_user (clerk_id);
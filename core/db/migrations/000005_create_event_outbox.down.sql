@@ -0,0 +1 @@
+DROP TABLE IF EXISTS kainos_event_outbox;
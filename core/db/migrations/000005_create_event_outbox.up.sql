@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS kainos_event_outbox (
+    id uuid primary key,
+    subject varchar not null,
+    payload jsonb not null,
+    attempts int not null default 0,
+    last_error text,
+    next_attempt_at timestamp not null default now(),
+    sent_at timestamp,
+    created_at timestamp not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+    ON kainos_event_outbox (next_attempt_at) WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE kainos_user_workflow
+    DROP COLUMN IF EXISTS schedule_preset,
+    DROP COLUMN IF EXISTS time_zone;
@@ -0,0 +1,6 @@
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS schedule_preset varchar,
+    ADD COLUMN IF NOT EXISTS time_zone varchar;
+
+-- Schedules created before time zones were supported ran in UTC
+UPDATE kainos_user_workflow SET time_zone = 'UTC' WHERE cron_time IS NOT NULL AND time_zone IS NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE kainos_workflow
+    DROP COLUMN IF EXISTS mastra_workflow_id,
+    DROP COLUMN IF EXISTS default_cron_time,
+    DROP COLUMN IF EXISTS default_schedule_preset,
+    DROP COLUMN IF EXISTS default_time_zone,
+    DROP COLUMN IF EXISTS input_schema;
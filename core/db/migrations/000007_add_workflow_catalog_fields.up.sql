@@ -0,0 +1,6 @@
+ALTER TABLE kainos_workflow
+    ADD COLUMN IF NOT EXISTS mastra_workflow_id varchar,
+    ADD COLUMN IF NOT EXISTS default_cron_time varchar,
+    ADD COLUMN IF NOT EXISTS default_schedule_preset varchar,
+    ADD COLUMN IF NOT EXISTS default_time_zone varchar,
+    ADD COLUMN IF NOT EXISTS input_schema jsonb;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_workflow_customer;
+
+ALTER TABLE kainos_user_workflow
+    DROP COLUMN IF EXISTS name;
+
+ALTER TABLE kainos_user
+    DROP COLUMN IF EXISTS plan;
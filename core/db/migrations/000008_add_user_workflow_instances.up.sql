@@ -0,0 +1,9 @@
+ALTER TABLE kainos_user
+    ADD COLUMN IF NOT EXISTS plan varchar not null default 'free';
+
+-- Instances of the same catalog workflow are told apart by name; NULL shows the catalog name
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS name varchar;
+
+CREATE INDEX IF NOT EXISTS idx_user_workflow_customer
+    ON kainos_user_workflow (customer_id);
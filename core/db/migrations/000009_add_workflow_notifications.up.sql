@@ -0,0 +1,4 @@
+-- When a finished run is emailed to the user: always, failure (only FAILED runs) or never
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS notify_on varchar not null default 'always'
+        CHECK (notify_on IN ('always', 'failure', 'never'));
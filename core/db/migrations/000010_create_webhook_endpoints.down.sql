@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS kainos_webhook_delivery;
+DROP TABLE IF EXISTS kainos_webhook_endpoint;
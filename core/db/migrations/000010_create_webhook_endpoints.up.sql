@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS kainos_webhook_endpoint (
+    id uuid primary key,
+    customer_id uuid not null references kainos_user(id),
+    url varchar not null,
+    secret varchar not null,
+    events text[] not null,
+    active boolean not null default true,
+    created_at timestamp not null default now(),
+    updated_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoint_customer
+    ON kainos_webhook_endpoint (customer_id);
+
+-- One row per endpoint and finished execution; the row is the delivery log of its attempts
+CREATE TABLE IF NOT EXISTS kainos_webhook_delivery (
+    id uuid primary key,
+    endpoint_id uuid not null references kainos_webhook_endpoint(id) on delete cascade,
+    execution_id uuid not null references kainos_workflow_execution(id) on delete cascade,
+    event_type varchar not null,
+    payload jsonb not null,
+    status varchar not null default 'PENDING',
+    attempts int not null default 0,
+    next_attempt_at timestamp not null default now(),
+    last_status_code int,
+    last_error text,
+    delivered_at timestamp,
+    created_at timestamp not null default now(),
+    updated_at timestamp,
+    unique (endpoint_id, execution_id, event_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due
+    ON kainos_webhook_delivery (next_attempt_at) WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_endpoint_created
+    ON kainos_webhook_delivery (endpoint_id, created_at desc);
@@ -0,0 +1,2 @@
+ALTER TABLE kainos_workflow
+    DROP COLUMN IF EXISTS task_queue;
@@ -0,0 +1,3 @@
+-- Task queue the catalog workflow's runs go to; NULL runs on APP_TEMPORAL_TASK_QUEUE
+ALTER TABLE kainos_workflow
+    ADD COLUMN IF NOT EXISTS task_queue varchar;
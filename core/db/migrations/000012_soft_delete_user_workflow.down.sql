@@ -0,0 +1,2 @@
+ALTER TABLE kainos_user_workflow
+    DROP COLUMN IF EXISTS deleted_at;
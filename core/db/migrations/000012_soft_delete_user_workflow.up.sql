@@ -0,0 +1,3 @@
+-- Deleted user workflows are only marked, so their executions and webhook deliveries are kept
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp;
@@ -0,0 +1,5 @@
+-- Fails while a deleted user and a live one share an email
+DROP INDEX IF EXISTS idx_kainos_user_email_active;
+
+ALTER TABLE kainos_user
+    ADD CONSTRAINT kainos_user_email_key UNIQUE (email);
@@ -0,0 +1,7 @@
+-- A user deleted in Clerk can sign up again with the same email under a new clerk_id,
+-- so only users that are not soft-deleted keep their email unique
+ALTER TABLE kainos_user
+    DROP CONSTRAINT IF EXISTS kainos_user_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kainos_user_email_active
+    ON kainos_user (email) WHERE deleted_at IS NULL;
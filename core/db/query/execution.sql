@@ -0,0 +1,73 @@
+-- name: CreateWorkflowExecution :one
+INSERT INTO kainos_workflow_execution (id, user_workflow_id, temporal_workflow_id, temporal_run_id, status)
+VALUES (@id, @user_workflow_id, @temporal_workflow_id, @temporal_run_id, @status)
+ON CONFLICT (temporal_workflow_id, temporal_run_id) DO UPDATE
+SET status = EXCLUDED.status, updated_at = NOW()
+returning *;
+
+-- name: AttachWorkflowExecutionRunID :execrows
+-- Gives a row reserved by run-now, which has no run id yet, the run id Temporal assigned.
+-- A row failed because the start looked failed is RUNNING again once the run shows up.
+UPDATE kainos_workflow_execution
+SET temporal_run_id = @temporal_run_id, status = 'RUNNING', error = NULL, finished_at = NULL, updated_at = NOW()
+WHERE temporal_workflow_id = @temporal_workflow_id AND temporal_run_id = '';
+
+-- name: UpdateWorkflowExecutionAttempt :one
+UPDATE kainos_workflow_execution
+SET attempt = @attempt, updated_at = NOW()
+WHERE temporal_workflow_id = @temporal_workflow_id AND temporal_run_id = @temporal_run_id
+returning *;
+
+-- name: FinishWorkflowExecution :one
+UPDATE kainos_workflow_execution
+SET
+    status = @status,
+    output = @output,
+    error = @error,
+    finished_at = NOW(),
+    updated_at = NOW()
+WHERE temporal_workflow_id = @temporal_workflow_id AND temporal_run_id = @temporal_run_id
+returning *;
+
+-- name: GetWorkflowExecutionByRunID :one
+SELECT * FROM kainos_workflow_execution
+WHERE temporal_workflow_id = @temporal_workflow_id AND temporal_run_id = @temporal_run_id;
+
+-- name: ListWorkflowExecutions :many
+SELECT * FROM kainos_workflow_execution
+WHERE user_workflow_id = @user_workflow_id
+  AND (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(started_after)::timestamp IS NULL OR started_at >= sqlc.narg(started_after))
+  AND (sqlc.narg(started_before)::timestamp IS NULL OR started_at < sqlc.narg(started_before))
+  AND (sqlc.narg(cursor_started_at)::timestamp IS NULL OR (started_at, id) < (sqlc.narg(cursor_started_at), @cursor_id::uuid))
+ORDER BY started_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: CountWorkflowExecutions :one
+SELECT count(*) FROM kainos_workflow_execution
+WHERE user_workflow_id = @user_workflow_id
+  AND (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(started_after)::timestamp IS NULL OR started_at >= sqlc.narg(started_after))
+  AND (sqlc.narg(started_before)::timestamp IS NULL OR started_at < sqlc.narg(started_before));
+
+-- name: GetUserWorkflowExecution :one
+SELECT * FROM kainos_workflow_execution
+WHERE user_workflow_id = @user_workflow_id AND temporal_run_id = @temporal_run_id;
+
+-- name: GetLatestWorkflowExecution :one
+SELECT * FROM kainos_workflow_execution
+WHERE user_workflow_id = @user_workflow_id AND finished_at IS NOT NULL
+ORDER BY started_at DESC, id DESC
+LIMIT 1;
+
+-- name: LockUserRuns :exec
+-- Serializes run-now requests of one user until the end of the transaction
+SELECT pg_advisory_xact_lock(hashtext(@clerk_id::text));
+
+-- name: CountRunningExecutionsByClerkID :one
+SELECT count(*) FROM kainos_workflow_execution e
+JOIN kainos_user_workflow uw ON e.user_workflow_id = uw.id
+JOIN kainos_user u ON uw.customer_id = u.id
+WHERE u.clerk_id = @clerk_id
+  AND e.status = 'RUNNING'
+  AND e.started_at >= @started_after;
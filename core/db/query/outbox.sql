@@ -0,0 +1,34 @@
+-- name: CreateOutboxEvent :exec
+-- An event id seen before is ignored, so retried writes of a deterministic id are safe
+INSERT INTO kainos_event_outbox (id, subject, payload) VALUES (@id, @subject, @payload)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: ClaimOutboxEvents :many
+-- Leases a batch of due events so concurrent relays never publish the same row at once
+UPDATE kainos_event_outbox
+SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg(lease_seconds)::int)
+WHERE id IN (
+    SELECT id FROM kainos_event_outbox
+    WHERE sent_at IS NULL AND next_attempt_at <= NOW()
+    ORDER BY created_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventSent :exec
+UPDATE kainos_event_outbox SET sent_at = NOW(), last_error = NULL WHERE id = @id;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE kainos_event_outbox
+SET attempts = attempts + 1, last_error = @last_error, next_attempt_at = @next_attempt_at
+WHERE id = @id;
+
+-- name: GetOutboxLag :one
+SELECT COUNT(*) AS pending,
+       COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)::float8 AS oldest_pending_seconds
+FROM kainos_event_outbox
+WHERE sent_at IS NULL;
+
+-- name: DeleteSentOutboxEvents :execrows
+DELETE FROM kainos_event_outbox WHERE sent_at IS NOT NULL AND sent_at < @sent_before;
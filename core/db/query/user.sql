@@ -20,3 +20,16 @@ returning *;
 -- name: GetUserByID :one
 SELECT * FROM kainos_user
 WHERE id = @id AND deleted_at is NULL;
+
+-- name: UpsertUserByClerkID :one
+INSERT INTO kainos_user (id, clerk_id, first_name, email) VALUES (@id, @clerk_id, @first_name, @email)
+ON CONFLICT (clerk_id) DO UPDATE
+SET first_name = EXCLUDED.first_name, email = EXCLUDED.email, updated_at = NOW()
+WHERE kainos_user.deleted_at IS NULL
+returning *, (xmax = 0)::bool AS inserted;
+
+-- name: UpdateUserPlanByClerkID :one
+UPDATE kainos_user
+SET plan = @plan, updated_at = NOW()
+WHERE clerk_id = @clerk_id AND deleted_at IS NULL
+returning *;
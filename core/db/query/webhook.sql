@@ -0,0 +1,7 @@
+-- name: RecordWebhookMessage :execrows
+INSERT INTO kainos_webhook_message (svix_id) VALUES (@svix_id)
+ON CONFLICT (svix_id) DO NOTHING;
+
+-- name: DeleteWebhookMessagesBefore :execrows
+-- Svix stops retrying a message long before the retention, so older ids can no longer be replayed.
+DELETE FROM kainos_webhook_message WHERE received_at < @received_before;
@@ -0,0 +1,74 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO kainos_webhook_endpoint (id, customer_id, url, secret, events)
+VALUES (@id, @customer_id, @url, @secret, @events)
+RETURNING *;
+
+-- name: ListWebhookEndpointsByClerkID :many
+SELECT e.* FROM kainos_webhook_endpoint e
+JOIN kainos_user u ON e.customer_id = u.id
+WHERE u.clerk_id = @clerk_id
+ORDER BY e.created_at;
+
+-- name: GetWebhookEndpointByIDAndClerkID :one
+SELECT e.* FROM kainos_webhook_endpoint e
+JOIN kainos_user u ON e.customer_id = u.id
+WHERE e.id = @id AND u.clerk_id = @clerk_id;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE kainos_webhook_endpoint
+SET url = @url, events = @events, active = @active, updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM kainos_webhook_endpoint
+WHERE id = @id;
+
+-- name: CreateWebhookDeliveriesForExecution :execrows
+-- Queues the event for every active endpoint of the user workflow's owner subscribed to it
+INSERT INTO kainos_webhook_delivery (id, endpoint_id, execution_id, event_type, payload)
+SELECT gen_random_uuid(), e.id, @execution_id, @event_type::varchar, @payload
+FROM kainos_webhook_endpoint e
+JOIN kainos_user_workflow uw ON uw.customer_id = e.customer_id
+WHERE uw.id = @user_workflow_id AND e.active AND @event_type::varchar = ANY(e.events)
+ON CONFLICT (endpoint_id, execution_id, event_type) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+-- Leases a batch of due deliveries so concurrent dispatchers never send the same row at once
+UPDATE kainos_webhook_delivery d
+SET next_attempt_at = NOW() + make_interval(secs => @lease_seconds::int)
+FROM kainos_webhook_endpoint e
+WHERE d.endpoint_id = e.id AND d.id IN (
+    SELECT id FROM kainos_webhook_delivery
+    WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.event_type, d.payload, d.attempts, e.url, e.secret;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE kainos_webhook_delivery
+SET status = 'DELIVERED', attempts = attempts + 1, last_status_code = @last_status_code,
+    last_error = NULL, delivered_at = NOW(), updated_at = NOW()
+WHERE id = @id;
+
+-- name: MarkWebhookDeliveryFailed :exec
+-- Records a failed attempt; status stays PENDING until the attempts run out
+UPDATE kainos_webhook_delivery
+SET status = @status, attempts = attempts + 1, last_status_code = @last_status_code,
+    last_error = @last_error, next_attempt_at = @next_attempt_at, updated_at = NOW()
+WHERE id = @id;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM kainos_webhook_delivery
+WHERE endpoint_id = @endpoint_id
+ORDER BY created_at DESC, id DESC
+LIMIT @page_size;
+
+-- name: RedeliverWebhookDelivery :one
+-- Sends the delivery again right away with a fresh set of attempts
+UPDATE kainos_webhook_delivery
+SET status = 'PENDING', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = @id AND endpoint_id = @endpoint_id
+RETURNING *;
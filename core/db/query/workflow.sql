@@ -1,5 +1,11 @@
 -- name: CreateWorkflow :one
-INSERT INTO kainos_workflow (id, workflow_name, workflow_description, price) VALUES (@id, @workflow_name, @workflow_description, @price) returning *;
+INSERT INTO kainos_workflow (
+    id, workflow_name, workflow_description, price, mastra_workflow_id,
+    default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue
+) VALUES (
+    @id, @workflow_name, @workflow_description, @price, @mastra_workflow_id,
+    @default_cron_time, @default_schedule_preset, @default_time_zone, @input_schema, @task_queue
+) returning *;
 
 -- name: CreateUserWorkflow :one
 INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, status) VALUES (@id, @workflow_id, @customer_id, @meta_data, @status) returning *;
@@ -8,6 +14,37 @@ INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, statu
 SELECT * from kainos_workflow
 WHERE deleted_at is NULL;
 
+-- name: GetWorkflowByID :one
+SELECT * FROM kainos_workflow
+WHERE id = @id;
+
+-- name: ListWorkflows :many
+SELECT * FROM kainos_workflow
+WHERE sqlc.arg(include_deleted)::bool OR deleted_at IS NULL
+ORDER BY created_at, id;
+
+-- name: UpdateWorkflow :one
+UPDATE kainos_workflow
+SET
+    workflow_name = @workflow_name,
+    workflow_description = @workflow_description,
+    price = @price,
+    mastra_workflow_id = @mastra_workflow_id,
+    default_cron_time = @default_cron_time,
+    default_schedule_preset = @default_schedule_preset,
+    default_time_zone = @default_time_zone,
+    input_schema = @input_schema,
+    task_queue = @task_queue,
+    updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteWorkflow :one
+UPDATE kainos_workflow
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
 -- -- name: GetUserWorkflow :many
 -- SELECT workflow_id, workflow_name, meta_data, cron_time, status, kainos_user_workflow.created_at, kainos_user_workflow.updated_at
 -- from kainos_user_workflow
@@ -15,26 +52,28 @@ WHERE deleted_at is NULL;
 -- join kainos_user on kainos_user_workflow.customer_id = kainos_user.id;
 
 -- name: GetUserWorkflowsByClerkID :many
-SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at,
-       uw.updated_at, w.workflow_name, w.workflow_description, w.price
+SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at, uw.updated_at, uw.schedule_preset, uw.time_zone, uw.name, uw.notify_on,
+       w.workflow_name, w.workflow_description, w.price, w.mastra_workflow_id
 FROM kainos_user_workflow uw
 JOIN kainos_workflow w ON uw.workflow_id = w.id
 JOIN kainos_user u ON uw.customer_id = u.id
-WHERE u.clerk_id = @clerk_id AND u.deleted_at is NULL;
+WHERE u.clerk_id = @clerk_id AND uw.deleted_at IS NULL AND u.deleted_at is NULL AND w.deleted_at IS NULL;
 
 -- name: UpdateUserWorkflowStatus :one
 UPDATE kainos_user_workflow
 SET status = @status, updated_at = NOW()
-WHERE id = @id
+WHERE id = @id AND deleted_at IS NULL
 returning *;
 
 -- name: UpdateUserWorkflowSchedule :one
 UPDATE kainos_user_workflow
 SET
     cron_time = @cron_time,
+    schedule_preset = @schedule_preset,
+    time_zone = @time_zone,
     status = @status,
     updated_at = NOW()
-WHERE id = @id
+WHERE id = @id AND deleted_at IS NULL
 RETURNING *;
 
 -- name: GetUserWorkflowByID :one
@@ -47,9 +86,119 @@ SELECT
     uw.status,
     uw.created_at,
     uw.updated_at,
+    uw.schedule_preset,
+    uw.time_zone,
+    uw.name,
+    uw.notify_on,
     w.workflow_name,
     w.workflow_description,
-    w.price
+    w.price,
+    w.mastra_workflow_id
 FROM kainos_user_workflow uw
          JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.id = @id AND uw.deleted_at IS NULL;
+
+-- name: GetUserWorkflowByIDAndClerkID :one
+SELECT
+    uw.id,
+    uw.workflow_id,
+    uw.customer_id,
+    uw.meta_data,
+    uw.cron_time,
+    uw.status,
+    uw.created_at,
+    uw.updated_at,
+    uw.schedule_preset,
+    uw.time_zone,
+    uw.name,
+    uw.notify_on,
+    w.workflow_name,
+    w.workflow_description,
+    w.price,
+    w.mastra_workflow_id
+FROM kainos_user_workflow uw
+         JOIN kainos_workflow w ON uw.workflow_id = w.id
+         JOIN kainos_user u ON uw.customer_id = u.id
+WHERE uw.id = @id AND u.clerk_id = @clerk_id AND uw.deleted_at IS NULL AND u.deleted_at IS NULL AND w.deleted_at IS NULL;
+
+-- name: CreateDefaultUserWorkflows :execrows
+-- Subscribes the user to the catalog workflows it has none of yet, oldest first, at most max_count of them
+INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, status, cron_time, schedule_preset, time_zone)
+SELECT gen_random_uuid(), w.id, sqlc.arg(customer_id)::uuid, '{}', 'OFF', w.default_cron_time, w.default_schedule_preset, w.default_time_zone
+FROM kainos_workflow w
+WHERE w.deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM kainos_user_workflow uw
+    WHERE uw.customer_id = sqlc.arg(customer_id)::uuid AND uw.workflow_id = w.id
+  )
+ORDER BY w.created_at, w.id
+LIMIT sqlc.arg(max_count);
+
+-- name: ListUserWorkflowSchedules :many
+-- Every user workflow with whether its owner or catalog entry was deleted and the catalog task queue,
+-- for reconciling Temporal schedules. Deleted user workflows are left out, so their schedules are orphans.
+SELECT sqlc.embed(uw), (u.deleted_at IS NOT NULL)::bool AS user_deleted, (w.deleted_at IS NOT NULL)::bool AS workflow_deleted, w.task_queue
+FROM kainos_user_workflow uw
+JOIN kainos_user u ON uw.customer_id = u.id
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.deleted_at IS NULL
+ORDER BY uw.id;
+
+-- name: GetUserWorkflowSchedule :one
+-- One row of ListUserWorkflowSchedules, re-read by the reconciler right before it changes a schedule.
+SELECT sqlc.embed(uw), (u.deleted_at IS NOT NULL)::bool AS user_deleted, (w.deleted_at IS NOT NULL)::bool AS workflow_deleted, w.task_queue
+FROM kainos_user_workflow uw
+JOIN kainos_user u ON uw.customer_id = u.id
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.id = @id AND uw.deleted_at IS NULL;
+
+-- name: ListUserWorkflowsByWorkflowID :many
+SELECT * FROM kainos_user_workflow
+WHERE workflow_id = @workflow_id AND deleted_at IS NULL
+ORDER BY id;
+
+-- name: UpdateUserWorkflowMetaData :one
+UPDATE kainos_user_workflow
+SET meta_data = @meta_data, updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: LockUserWorkflows :exec
+-- Serializes instance creation of one user until the end of the transaction
+SELECT pg_advisory_xact_lock(hashtext('user_workflows:' || @clerk_id::text));
+
+-- name: CountUserWorkflowsByCustomerID :one
+SELECT count(*) FROM kainos_user_workflow uw
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.customer_id = @customer_id AND uw.deleted_at IS NULL AND w.deleted_at IS NULL;
+
+-- name: CreateUserWorkflowInstance :one
+INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, name, meta_data, cron_time, schedule_preset, time_zone, notify_on, status)
+VALUES (@id, @workflow_id, @customer_id, @name, @meta_data, @cron_time, @schedule_preset, @time_zone, @notify_on, 'OFF')
+RETURNING *;
+
+-- name: RenameUserWorkflow :one
+UPDATE kainos_user_workflow
+SET name = @name, updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteUserWorkflow :one
+UPDATE kainos_user_workflow
+SET deleted_at = NOW(), status = 'OFF', updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UpdateUserWorkflowNotifyOn :one
+UPDATE kainos_user_workflow
+SET notify_on = @notify_on, updated_at = NOW()
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetWorkflowNotificationTarget :one
+-- Who a finished run of the user workflow is reported to, and when
+SELECT uw.id, uw.name, uw.notify_on, w.workflow_name, u.clerk_id, u.email, u.first_name
+FROM kainos_user_workflow uw
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+JOIN kainos_user u ON uw.customer_id = u.id
 WHERE uw.id = @id;
@@ -41,3 +41,122 @@ CREATE  TABLE  IF NOT EXISTS  kainos_user_analysis (
     customer_id uuid not null references kainos_user(id),
     created_at timestamp default  now()
 );
+
+CREATE TABLE IF NOT EXISTS kainos_workflow_execution (
+    id uuid primary key,
+    user_workflow_id uuid not null references kainos_user_workflow(id),
+    temporal_workflow_id varchar not null,
+    temporal_run_id varchar not null,
+    status varchar not null default 'RUNNING',
+    started_at timestamp not null default now(),
+    finished_at timestamp,
+    output jsonb,
+    error text,
+    attempt int not null default 0,
+    created_at timestamp not null default now(),
+    updated_at timestamp,
+    unique (temporal_workflow_id, temporal_run_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_execution_user_workflow_started
+    ON kainos_workflow_execution (user_workflow_id, started_at desc);
+
+CREATE TABLE IF NOT EXISTS kainos_webhook_message (
+    svix_id varchar primary key,
+    received_at timestamp not null default now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kainos_user_clerk_id ON kainos_user (clerk_id);
+
+CREATE TABLE IF NOT EXISTS kainos_event_outbox (
+    id uuid primary key,
+    subject varchar not null,
+    payload jsonb not null,
+    attempts int not null default 0,
+    last_error text,
+    next_attempt_at timestamp not null default now(),
+    sent_at timestamp,
+    created_at timestamp not null default now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending
+    ON kainos_event_outbox (next_attempt_at) WHERE sent_at IS NULL;
+
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS schedule_preset varchar,
+    ADD COLUMN IF NOT EXISTS time_zone varchar;
+
+ALTER TABLE kainos_workflow
+    ADD COLUMN IF NOT EXISTS mastra_workflow_id varchar,
+    ADD COLUMN IF NOT EXISTS default_cron_time varchar,
+    ADD COLUMN IF NOT EXISTS default_schedule_preset varchar,
+    ADD COLUMN IF NOT EXISTS default_time_zone varchar,
+    ADD COLUMN IF NOT EXISTS input_schema jsonb;
+
+ALTER TABLE kainos_user
+    ADD COLUMN IF NOT EXISTS plan varchar not null default 'free';
+
+-- Instances of the same catalog workflow are told apart by name; NULL shows the catalog name
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS name varchar;
+
+CREATE INDEX IF NOT EXISTS idx_user_workflow_customer
+    ON kainos_user_workflow (customer_id);
+
+-- When a finished run is emailed to the user: always, failure (only FAILED runs) or never
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS notify_on varchar not null default 'always'
+        CHECK (notify_on IN ('always', 'failure', 'never'));
+
+-- Deleted user workflows are only marked, so their executions and webhook deliveries are kept
+ALTER TABLE kainos_user_workflow
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+CREATE TABLE IF NOT EXISTS kainos_webhook_endpoint (
+    id uuid primary key,
+    customer_id uuid not null references kainos_user(id),
+    url varchar not null,
+    secret varchar not null,
+    events text[] not null,
+    active boolean not null default true,
+    created_at timestamp not null default now(),
+    updated_at timestamp
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoint_customer
+    ON kainos_webhook_endpoint (customer_id);
+
+-- One row per endpoint and finished execution; the row is the delivery log of its attempts
+CREATE TABLE IF NOT EXISTS kainos_webhook_delivery (
+    id uuid primary key,
+    endpoint_id uuid not null references kainos_webhook_endpoint(id) on delete cascade,
+    execution_id uuid not null references kainos_workflow_execution(id) on delete cascade,
+    event_type varchar not null,
+    payload jsonb not null,
+    status varchar not null default 'PENDING',
+    attempts int not null default 0,
+    next_attempt_at timestamp not null default now(),
+    last_status_code int,
+    last_error text,
+    delivered_at timestamp,
+    created_at timestamp not null default now(),
+    updated_at timestamp,
+    unique (endpoint_id, execution_id, event_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_due
+    ON kainos_webhook_delivery (next_attempt_at) WHERE status = 'PENDING';
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_endpoint_created
+    ON kainos_webhook_delivery (endpoint_id, created_at desc);
+
+-- Task queue the catalog workflow's runs go to; NULL runs on APP_TEMPORAL_TASK_QUEUE
+ALTER TABLE kainos_workflow
+    ADD COLUMN IF NOT EXISTS task_queue varchar;
+
+-- Only users that are not soft-deleted keep their email unique, so a deleted user can sign up again
+ALTER TABLE kainos_user
+    DROP CONSTRAINT IF EXISTS kainos_user_email_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_kainos_user_email_active
+    ON kainos_user (email) WHERE deleted_at IS NULL;
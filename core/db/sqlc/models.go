@@ -9,6 +9,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type KainosEventOutbox struct {
+	ID            uuid.UUID        `json:"id"`
+	Subject       string           `json:"subject"`
+	Payload       []byte           `json:"payload"`
+	Attempts      int32            `json:"attempts"`
+	LastError     *string          `json:"last_error"`
+	NextAttemptAt pgtype.Timestamp `json:"next_attempt_at"`
+	SentAt        pgtype.Timestamp `json:"sent_at"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+}
+
 type KainosUser struct {
 	ID        uuid.UUID        `json:"id"`
 	ClerkID   string           `json:"clerk_id"`
@@ -17,6 +28,7 @@ type KainosUser struct {
 	CreatedAt pgtype.Timestamp `json:"created_at"`
 	DeletedAt pgtype.Timestamp `json:"deleted_at"`
 	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	Plan      string           `json:"plan"`
 }
 
 type KainosUserAnalysis struct {
@@ -28,24 +40,82 @@ type KainosUserAnalysis struct {
 }
 
 type KainosUserWorkflow struct {
+	ID             uuid.UUID        `json:"id"`
+	WorkflowID     uuid.UUID        `json:"workflow_id"`
+	CustomerID     interface{}      `json:"customer_id"`
+	MetaData       []byte           `json:"meta_data"`
+	CronTime       *string          `json:"cron_time"`
+	Status         *string          `json:"status"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+	SchedulePreset *string          `json:"schedule_preset"`
+	TimeZone       *string          `json:"time_zone"`
+	Name           *string          `json:"name"`
+	NotifyOn       string           `json:"notify_on"`
+	DeletedAt      pgtype.Timestamp `json:"deleted_at"`
+}
+
+type KainosWebhookDelivery struct {
+	ID             uuid.UUID        `json:"id"`
+	EndpointID     uuid.UUID        `json:"endpoint_id"`
+	ExecutionID    uuid.UUID        `json:"execution_id"`
+	EventType      string           `json:"event_type"`
+	Payload        []byte           `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int32            `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamp `json:"next_attempt_at"`
+	LastStatusCode *int32           `json:"last_status_code"`
+	LastError      *string          `json:"last_error"`
+	DeliveredAt    pgtype.Timestamp `json:"delivered_at"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+	UpdatedAt      pgtype.Timestamp `json:"updated_at"`
+}
+
+type KainosWebhookEndpoint struct {
 	ID         uuid.UUID        `json:"id"`
-	WorkflowID uuid.UUID        `json:"workflow_id"`
-	CustomerID interface{}      `json:"customer_id"`
-	MetaData   []byte           `json:"meta_data"`
-	CronTime   *string          `json:"cron_time"`
-	Status     *string          `json:"status"`
+	CustomerID uuid.UUID        `json:"customer_id"`
+	Url        string           `json:"url"`
+	Secret     string           `json:"secret"`
+	Events     []string         `json:"events"`
+	Active     bool             `json:"active"`
 	CreatedAt  pgtype.Timestamp `json:"created_at"`
 	UpdatedAt  pgtype.Timestamp `json:"updated_at"`
 }
 
+type KainosWebhookMessage struct {
+	SvixID     string           `json:"svix_id"`
+	ReceivedAt pgtype.Timestamp `json:"received_at"`
+}
+
 type KainosWorkflow struct {
-	ID                  uuid.UUID        `json:"id"`
-	WorkflowName        string           `json:"workflow_name"`
-	WorkflowDescription string           `json:"workflow_description"`
-	CreatedAt           pgtype.Timestamp `json:"created_at"`
-	DeletedAt           pgtype.Timestamp `json:"deleted_at"`
-	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
-	Price               *float64         `json:"price"`
+	ID                    uuid.UUID        `json:"id"`
+	WorkflowName          string           `json:"workflow_name"`
+	WorkflowDescription   string           `json:"workflow_description"`
+	CreatedAt             pgtype.Timestamp `json:"created_at"`
+	DeletedAt             pgtype.Timestamp `json:"deleted_at"`
+	UpdatedAt             pgtype.Timestamp `json:"updated_at"`
+	Price                 *float64         `json:"price"`
+	MastraWorkflowID      *string          `json:"mastra_workflow_id"`
+	DefaultCronTime       *string          `json:"default_cron_time"`
+	DefaultSchedulePreset *string          `json:"default_schedule_preset"`
+	DefaultTimeZone       *string          `json:"default_time_zone"`
+	InputSchema           []byte           `json:"input_schema"`
+	TaskQueue             *string          `json:"task_queue"`
+}
+
+type KainosWorkflowExecution struct {
+	ID                 uuid.UUID        `json:"id"`
+	UserWorkflowID     uuid.UUID        `json:"user_workflow_id"`
+	TemporalWorkflowID string           `json:"temporal_workflow_id"`
+	TemporalRunID      string           `json:"temporal_run_id"`
+	Status             string           `json:"status"`
+	StartedAt          pgtype.Timestamp `json:"started_at"`
+	FinishedAt         pgtype.Timestamp `json:"finished_at"`
+	Output             []byte           `json:"output"`
+	Error              *string          `json:"error"`
+	Attempt            int32            `json:"attempt"`
+	CreatedAt          pgtype.Timestamp `json:"created_at"`
+	UpdatedAt          pgtype.Timestamp `json:"updated_at"`
 }
 
 type SystemDefinedAnalysis struct {
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func claimedIDs(t *testing.T, store *SQLStore) map[uuid.UUID]bool {
+	t.Helper()
+
+	events, err := store.ClaimOutboxEvents(context.Background(), ClaimOutboxEventsParams{LeaseSeconds: 30, BatchSize: 1000})
+	if err != nil {
+		t.Fatalf("ClaimOutboxEvents: %v", err)
+	}
+	ids := make(map[uuid.UUID]bool, len(events))
+	for _, event := range events {
+		ids[event.ID] = true
+	}
+	return ids
+}
+
+func TestClaimOutboxEvents_LeasesUntilSentOrFailed(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+
+	sent, failed := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{sent, failed} {
+		if err := store.CreateOutboxEvent(ctx, CreateOutboxEventParams{ID: id, Subject: "user.created", Payload: []byte(`{}`)}); err != nil {
+			t.Fatalf("CreateOutboxEvent: %v", err)
+		}
+	}
+
+	if claimed := claimedIDs(t, store); !claimed[sent] || !claimed[failed] {
+		t.Fatalf("first claim = %v, want both events", claimed)
+	}
+	// Leased to the first relay, so another one does not publish them too
+	if claimed := claimedIDs(t, store); claimed[sent] || claimed[failed] {
+		t.Fatalf("second claim = %v, want neither event while leased", claimed)
+	}
+
+	if err := store.MarkOutboxEventSent(ctx, sent); err != nil {
+		t.Fatalf("MarkOutboxEventSent: %v", err)
+	}
+	message := "no responders"
+	if err := store.MarkOutboxEventFailed(ctx, MarkOutboxEventFailedParams{
+		LastError:     &message,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now().UTC().Add(-24 * time.Hour), Valid: true},
+		ID:            failed,
+	}); err != nil {
+		t.Fatalf("MarkOutboxEventFailed: %v", err)
+	}
+
+	claimed := claimedIDs(t, store)
+	if claimed[sent] {
+		t.Error("sent event was claimed again")
+	}
+	if !claimed[failed] {
+		t.Error("failed event was not retried once due")
+	}
+}
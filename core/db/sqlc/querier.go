@@ -8,30 +8,95 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	// Gives a row reserved by run-now, which has no run id yet, the run id Temporal assigned.
+	// A row failed because the start looked failed is RUNNING again once the run shows up.
+	AttachWorkflowExecutionRunID(ctx context.Context, arg AttachWorkflowExecutionRunIDParams) (int64, error)
+	// Leases a batch of due events so concurrent relays never publish the same row at once
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]KainosEventOutbox, error)
+	// Leases a batch of due deliveries so concurrent dispatchers never send the same row at once
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]ClaimWebhookDeliveriesRow, error)
+	CountRunningExecutionsByClerkID(ctx context.Context, arg CountRunningExecutionsByClerkIDParams) (int64, error)
+	CountUserWorkflowsByCustomerID(ctx context.Context, customerID interface{}) (int64, error)
+	CountWorkflowExecutions(ctx context.Context, arg CountWorkflowExecutionsParams) (int64, error)
+	// Subscribes the user to the catalog workflows it has none of yet, oldest first, at most max_count of them
+	CreateDefaultUserWorkflows(ctx context.Context, arg CreateDefaultUserWorkflowsParams) (int64, error)
+	// An event id seen before is ignored, so retried writes of a deterministic id are safe
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
 	CreateSystemAnalysis(ctx context.Context, arg CreateSystemAnalysisParams) (SystemDefinedAnalysis, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (KainosUser, error)
 	CreateUserAnalysis(ctx context.Context, arg CreateUserAnalysisParams) (KainosUserAnalysis, error)
 	CreateUserWorkflow(ctx context.Context, arg CreateUserWorkflowParams) (KainosUserWorkflow, error)
+	CreateUserWorkflowInstance(ctx context.Context, arg CreateUserWorkflowInstanceParams) (KainosUserWorkflow, error)
+	// Queues the event for every active endpoint of the user workflow's owner subscribed to it
+	CreateWebhookDeliveriesForExecution(ctx context.Context, arg CreateWebhookDeliveriesForExecutionParams) (int64, error)
+	CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEndpointParams) (KainosWebhookEndpoint, error)
 	CreateWorkflow(ctx context.Context, arg CreateWorkflowParams) (KainosWorkflow, error)
+	CreateWorkflowExecution(ctx context.Context, arg CreateWorkflowExecutionParams) (KainosWorkflowExecution, error)
+	DeleteSentOutboxEvents(ctx context.Context, sentBefore pgtype.Timestamp) (int64, error)
+	DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error
+	// Svix stops retrying a message long before the retention, so older ids can no longer be replayed.
+	DeleteWebhookMessagesBefore(ctx context.Context, receivedBefore pgtype.Timestamp) (int64, error)
+	FinishWorkflowExecution(ctx context.Context, arg FinishWorkflowExecutionParams) (KainosWorkflowExecution, error)
+	GetLatestWorkflowExecution(ctx context.Context, userWorkflowID uuid.UUID) (KainosWorkflowExecution, error)
+	GetOutboxLag(ctx context.Context) (GetOutboxLagRow, error)
 	GetSystemAnalysis(ctx context.Context) ([]SystemDefinedAnalysis, error)
 	GetUserAnalysis(ctx context.Context) ([]GetUserAnalysisRow, error)
 	GetUserByClerkID(ctx context.Context, clerkID string) (KainosUser, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (KainosUser, error)
 	GetUserWorkflowByID(ctx context.Context, id uuid.UUID) (GetUserWorkflowByIDRow, error)
+	GetUserWorkflowByIDAndClerkID(ctx context.Context, arg GetUserWorkflowByIDAndClerkIDParams) (GetUserWorkflowByIDAndClerkIDRow, error)
+	GetUserWorkflowExecution(ctx context.Context, arg GetUserWorkflowExecutionParams) (KainosWorkflowExecution, error)
+	// One row of ListUserWorkflowSchedules, re-read by the reconciler right before it changes a schedule.
+	GetUserWorkflowSchedule(ctx context.Context, id uuid.UUID) (GetUserWorkflowScheduleRow, error)
 	// -- name: GetUserWorkflow :many
 	// SELECT workflow_id, workflow_name, meta_data, cron_time, status, kainos_user_workflow.created_at, kainos_user_workflow.updated_at
 	// from kainos_user_workflow
 	// join kainos_workflow on kainos_user_workflow.workflow_id = kainos_workflow.id
 	// join kainos_user on kainos_user_workflow.customer_id = kainos_user.id;
 	GetUserWorkflowsByClerkID(ctx context.Context, clerkID string) ([]GetUserWorkflowsByClerkIDRow, error)
+	GetWebhookEndpointByIDAndClerkID(ctx context.Context, arg GetWebhookEndpointByIDAndClerkIDParams) (KainosWebhookEndpoint, error)
 	GetWorkflow(ctx context.Context) ([]KainosWorkflow, error)
+	GetWorkflowByID(ctx context.Context, id uuid.UUID) (KainosWorkflow, error)
+	GetWorkflowExecutionByRunID(ctx context.Context, arg GetWorkflowExecutionByRunIDParams) (KainosWorkflowExecution, error)
+	// Who a finished run of the user workflow is reported to, and when
+	GetWorkflowNotificationTarget(ctx context.Context, id uuid.UUID) (GetWorkflowNotificationTargetRow, error)
+	// Every user workflow with whether its owner or catalog entry was deleted, for reconciling Temporal schedules
+	ListUserWorkflowSchedules(ctx context.Context) ([]ListUserWorkflowSchedulesRow, error)
+	ListUserWorkflowsByWorkflowID(ctx context.Context, workflowID uuid.UUID) ([]KainosUserWorkflow, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]KainosWebhookDelivery, error)
+	ListWebhookEndpointsByClerkID(ctx context.Context, clerkID string) ([]KainosWebhookEndpoint, error)
+	ListWorkflowExecutions(ctx context.Context, arg ListWorkflowExecutionsParams) ([]KainosWorkflowExecution, error)
+	ListWorkflows(ctx context.Context, includeDeleted bool) ([]KainosWorkflow, error)
+	// Serializes run-now requests of one user until the end of the transaction
+	LockUserRuns(ctx context.Context, clerkID string) error
+	// Serializes instance creation of one user until the end of the transaction
+	LockUserWorkflows(ctx context.Context, clerkID string) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkOutboxEventSent(ctx context.Context, id uuid.UUID) error
+	MarkWebhookDeliveryDelivered(ctx context.Context, arg MarkWebhookDeliveryDeliveredParams) error
+	// Records a failed attempt; status stays PENDING until the attempts run out
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	RecordWebhookMessage(ctx context.Context, svixID string) (int64, error)
+	// Sends the delivery again right away with a fresh set of attempts
+	RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (KainosWebhookDelivery, error)
+	RenameUserWorkflow(ctx context.Context, arg RenameUserWorkflowParams) (KainosUserWorkflow, error)
 	SoftDeleteUserByClerkID(ctx context.Context, clerkID string) (KainosUser, error)
+	SoftDeleteUserWorkflow(ctx context.Context, id uuid.UUID) (KainosUserWorkflow, error)
+	SoftDeleteWorkflow(ctx context.Context, id uuid.UUID) (KainosWorkflow, error)
 	UpdateUserByClerkID(ctx context.Context, arg UpdateUserByClerkIDParams) (KainosUser, error)
+	UpdateUserPlanByClerkID(ctx context.Context, arg UpdateUserPlanByClerkIDParams) (KainosUser, error)
+	UpdateUserWorkflowMetaData(ctx context.Context, arg UpdateUserWorkflowMetaDataParams) (KainosUserWorkflow, error)
+	UpdateUserWorkflowNotifyOn(ctx context.Context, arg UpdateUserWorkflowNotifyOnParams) (KainosUserWorkflow, error)
 	UpdateUserWorkflowSchedule(ctx context.Context, arg UpdateUserWorkflowScheduleParams) (KainosUserWorkflow, error)
 	UpdateUserWorkflowStatus(ctx context.Context, arg UpdateUserWorkflowStatusParams) (KainosUserWorkflow, error)
+	UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (KainosWebhookEndpoint, error)
+	UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams) (KainosWorkflow, error)
+	UpdateWorkflowExecutionAttempt(ctx context.Context, arg UpdateWorkflowExecutionAttemptParams) (KainosWorkflowExecution, error)
+	UpsertUserByClerkID(ctx context.Context, arg UpsertUserByClerkIDParams) (UpsertUserByClerkIDRow, error)
 }
 
 var _ Querier = (*Queries)(nil)
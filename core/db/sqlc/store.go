@@ -10,7 +10,13 @@ import (
 // Store provides all functions to execute database queries and transactions
 type Store interface {
 	Querier
-	// Add transaction methods here
+	ProvisionUserTx(ctx context.Context, arg ProvisionUserTxParams) (ProvisionUserTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (KainosUser, error)
+	SoftDeleteUserTx(ctx context.Context, arg SoftDeleteUserTxParams) (KainosUser, error)
+	ReserveWorkflowRunTx(ctx context.Context, arg ReserveWorkflowRunTxParams) (KainosWorkflowExecution, error)
+	CreateUserWorkflowInstanceTx(ctx context.Context, arg CreateUserWorkflowInstanceTxParams) (KainosUserWorkflow, error)
+	DeleteUserWorkflowTx(ctx context.Context, arg DeleteUserWorkflowTxParams) (KainosUserWorkflow, error)
+	FinishWorkflowExecutionTx(ctx context.Context, arg FinishWorkflowExecutionTxParams) (KainosWorkflowExecution, error)
 }
 
 // SQLStore implements Store interface
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// FinishWorkflowExecutionTxParams contains the input parameters of the finish execution transaction
+type FinishWorkflowExecutionTxParams struct {
+	FinishWorkflowExecutionParams
+	// WebhookEvent builds the event sent to the owner's webhook endpoints from the finished row
+	WebhookEvent func(execution KainosWorkflowExecution) (eventType string, payload []byte, err error)
+	// Event is written to the outbox when set, for runs that do not publish their own completion
+	Event *CreateOutboxEventParams
+}
+
+// FinishWorkflowExecutionTx finishes an execution and queues its webhook deliveries and outbox event
+// in the same transaction, so every finished execution is delivered to the endpoints subscribed at that time.
+func (store *SQLStore) FinishWorkflowExecutionTx(ctx context.Context, arg FinishWorkflowExecutionTxParams) (KainosWorkflowExecution, error) {
+	var execution KainosWorkflowExecution
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		execution, err = q.FinishWorkflowExecution(ctx, arg.FinishWorkflowExecutionParams)
+		if err != nil {
+			return fmt.Errorf("failed to finish workflow execution: %w", err)
+		}
+
+		if arg.Event != nil {
+			if err := q.CreateOutboxEvent(ctx, *arg.Event); err != nil {
+				return fmt.Errorf("failed to write outbox event: %w", err)
+			}
+		}
+
+		if arg.WebhookEvent == nil {
+			return nil
+		}
+
+		eventType, payload, err := arg.WebhookEvent(execution)
+		if err != nil {
+			return fmt.Errorf("failed to build webhook event: %w", err)
+		}
+
+		_, err = q.CreateWebhookDeliveriesForExecution(ctx, CreateWebhookDeliveriesForExecutionParams{
+			ExecutionID:    execution.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			UserWorkflowID: execution.UserWorkflowID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create webhook deliveries: %w", err)
+		}
+		return nil
+	})
+
+	return execution, err
+}
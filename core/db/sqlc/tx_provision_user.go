@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// ProvisionUserTxParams contains the input parameters of the provision user transaction
+type ProvisionUserTxParams struct {
+	ClerkID   string
+	FirstName *string
+	Email     string
+	// Plan is stored when set; otherwise a new user keeps the default plan
+	Plan *string
+	// Event is written to the outbox only when the user is actually created
+	Event CreateOutboxEventParams
+	// InstanceLimit returns how many user workflows a plan allows; default subscriptions stop there
+	InstanceLimit func(plan string) int
+	// WebhookMessageID is the Svix message id of the delivery, empty outside of webhooks
+	WebhookMessageID string
+}
+
+// ProvisionUserTxResult is the result of the provision user transaction
+type ProvisionUserTxResult struct {
+	User UpsertUserByClerkIDRow
+	// Created is false when the user already existed, e.g. when Clerk replays user.created
+	Created bool
+	// SubscriptionsCreated counts the default subscriptions added by this call
+	SubscriptionsCreated int64
+}
+
+// ProvisionUserTx upserts the user by clerk_id and subscribes it to every catalog
+// workflow it is not subscribed to yet, up to its plan's limit, all in one transaction.
+// Running it again for the same user only fills in missing subscriptions.
+func (store *SQLStore) ProvisionUserTx(ctx context.Context, arg ProvisionUserTxParams) (ProvisionUserTxResult, error) {
+	var result ProvisionUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := recordDelivery(ctx, q, arg.WebhookMessageID); err != nil {
+			return err
+		}
+
+		var err error
+		result.User, err = q.UpsertUserByClerkID(ctx, UpsertUserByClerkIDParams{
+			ID:        uuid.New(),
+			ClerkID:   arg.ClerkID,
+			FirstName: arg.FirstName,
+			Email:     arg.Email,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upsert user: %w", err)
+		}
+		result.Created = result.User.Inserted
+
+		if arg.Plan != nil {
+			user, err := q.UpdateUserPlanByClerkID(ctx, UpdateUserPlanByClerkIDParams{Plan: *arg.Plan, ClerkID: arg.ClerkID})
+			if err != nil {
+				return fmt.Errorf("failed to update user plan: %w", err)
+			}
+			result.User.Plan = user.Plan
+		}
+
+		if result.Created {
+			if err := q.CreateOutboxEvent(ctx, arg.Event); err != nil {
+				return fmt.Errorf("failed to write outbox event: %w", err)
+			}
+		}
+
+		// Same lock and count as CreateUserWorkflowInstanceTx, so both respect the plan limit
+		if err := q.LockUserWorkflows(ctx, arg.ClerkID); err != nil {
+			return fmt.Errorf("failed to lock user workflows: %w", err)
+		}
+		count, err := q.CountUserWorkflowsByCustomerID(ctx, result.User.ID)
+		if err != nil {
+			return fmt.Errorf("failed to count user workflows: %w", err)
+		}
+		remaining := int64(arg.InstanceLimit(result.User.Plan)) - count
+		if remaining <= 0 {
+			return nil
+		}
+
+		result.SubscriptionsCreated, err = q.CreateDefaultUserWorkflows(ctx, CreateDefaultUserWorkflowsParams{
+			CustomerID: result.User.ID,
+			MaxCount:   int32(min(remaining, math.MaxInt32)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create default user workflows: %w", err)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
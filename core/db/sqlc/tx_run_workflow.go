@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrTooManyRunningExecutions is returned when the user already has the allowed number of runs in flight
+var ErrTooManyRunningExecutions = errors.New("too many running workflow executions")
+
+// ReserveWorkflowRunTxParams contains the input parameters of the reserve workflow run transaction
+type ReserveWorkflowRunTxParams struct {
+	ClerkID        string
+	UserWorkflowID uuid.UUID
+	// TemporalWorkflowID is the id the run will be started under
+	TemporalWorkflowID string
+	// MaxRunning counts RUNNING executions started after RunningSince, so rows left behind by a crashed worker expire
+	MaxRunning   int64
+	RunningSince time.Time
+}
+
+// ReserveWorkflowRunTx records a RUNNING execution without a run id under a per-user lock once
+// the user is below MaxRunning, so the next request already counts it. The caller starts the
+// Temporal workflow under TemporalWorkflowID after the commit, then attaches the run id with
+// AttachWorkflowExecutionRunID, or finishes the row as FAILED when the start failed.
+func (store *SQLStore) ReserveWorkflowRunTx(ctx context.Context, arg ReserveWorkflowRunTxParams) (KainosWorkflowExecution, error) {
+	var execution KainosWorkflowExecution
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockUserRuns(ctx, arg.ClerkID); err != nil {
+			return fmt.Errorf("failed to lock user runs: %w", err)
+		}
+
+		running, err := q.CountRunningExecutionsByClerkID(ctx, CountRunningExecutionsByClerkIDParams{
+			ClerkID:      arg.ClerkID,
+			StartedAfter: pgtype.Timestamp{Time: arg.RunningSince.UTC(), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count running executions: %w", err)
+		}
+		if running >= arg.MaxRunning {
+			return ErrTooManyRunningExecutions
+		}
+
+		execution, err = q.CreateWorkflowExecution(ctx, CreateWorkflowExecutionParams{
+			ID:                 uuid.New(),
+			UserWorkflowID:     arg.UserWorkflowID,
+			TemporalWorkflowID: arg.TemporalWorkflowID,
+			TemporalRunID:      "",
+			Status:             "RUNNING",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reserve workflow execution: %w", err)
+		}
+		return nil
+	})
+
+	return execution, err
+}
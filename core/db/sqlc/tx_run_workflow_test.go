@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReserveWorkflowRunTx_ConcurrentRunsStayWithinLimit(t *testing.T) {
+	store := requireStore(t)
+	user := createTestUser(t, store)
+	workflow := createTestCatalogWorkflow(t, store)
+	instance, err := createTestInstance(t, store, user, workflow, 1)
+	if err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+
+	const limit = 2
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.ReserveWorkflowRunTx(context.Background(), ReserveWorkflowRunTxParams{
+				ClerkID:            user.ClerkID,
+				UserWorkflowID:     instance.ID,
+				TemporalWorkflowID: instance.ID.String() + "-run-" + uuid.NewString(),
+				MaxRunning:         limit,
+				RunningSince:       time.Now().Add(-time.Hour),
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	reserved, refused := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			reserved++
+		case errors.Is(err, ErrTooManyRunningExecutions):
+			refused++
+		default:
+			t.Fatalf("ReserveWorkflowRunTx: %v", err)
+		}
+	}
+	if reserved != limit || refused != 6-limit {
+		t.Errorf("reserved %d and refused %d, want %d and %d", reserved, refused, limit, 6-limit)
+	}
+}
+
+func TestAttachWorkflowExecutionRunID(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	user := createTestUser(t, store)
+	workflow := createTestCatalogWorkflow(t, store)
+	instance, err := createTestInstance(t, store, user, workflow, 1)
+	if err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+
+	reserved, err := store.ReserveWorkflowRunTx(ctx, ReserveWorkflowRunTxParams{
+		ClerkID:            user.ClerkID,
+		UserWorkflowID:     instance.ID,
+		TemporalWorkflowID: instance.ID.String() + "-run-" + uuid.NewString(),
+		MaxRunning:         1,
+		RunningSince:       time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("ReserveWorkflowRunTx: %v", err)
+	}
+	if reserved.Status != "RUNNING" || reserved.TemporalRunID != "" {
+		t.Fatalf("reserved row = %s %q, want RUNNING without a run id", reserved.Status, reserved.TemporalRunID)
+	}
+
+	runID := uuid.NewString()
+	attach := AttachWorkflowExecutionRunIDParams{TemporalRunID: runID, TemporalWorkflowID: reserved.TemporalWorkflowID}
+	attached, err := store.AttachWorkflowExecutionRunID(ctx, attach)
+	if err != nil {
+		t.Fatalf("AttachWorkflowExecutionRunID: %v", err)
+	}
+	if attached != 1 {
+		t.Fatalf("attached %d rows, want 1", attached)
+	}
+
+	execution, err := store.GetWorkflowExecutionByRunID(ctx, GetWorkflowExecutionByRunIDParams{
+		TemporalWorkflowID: reserved.TemporalWorkflowID,
+		TemporalRunID:      runID,
+	})
+	if err != nil {
+		t.Fatalf("GetWorkflowExecutionByRunID: %v", err)
+	}
+	if execution.ID != reserved.ID || execution.Status != "RUNNING" {
+		t.Errorf("execution = %s %s, want the reserved row still RUNNING", execution.ID, execution.Status)
+	}
+
+	// Once the run id is set the row is no longer up for grabs
+	attached, err = store.AttachWorkflowExecutionRunID(ctx, attach)
+	if err != nil {
+		t.Fatalf("AttachWorkflowExecutionRunID again: %v", err)
+	}
+	if attached != 0 {
+		t.Errorf("attached %d rows the second time, want 0", attached)
+	}
+}
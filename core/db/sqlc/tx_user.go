@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrWebhookMessageProcessed is returned when an earlier delivery of the same Svix message was processed
+var ErrWebhookMessageProcessed = errors.New("webhook message already processed")
+
+// recordDelivery records the Svix message a transaction processes. The record commits or
+// rolls back with the transaction, so a failed delivery can be retried and a processed one
+// cannot be replayed. A concurrent delivery of the same message waits for this one to finish.
+func recordDelivery(ctx context.Context, q *Queries, svixID string) error {
+	if svixID == "" {
+		return nil
+	}
+	recorded, err := q.RecordWebhookMessage(ctx, svixID)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook message: %w", err)
+	}
+	if recorded == 0 {
+		return ErrWebhookMessageProcessed
+	}
+	return nil
+}
+
+// UpdateUserTxParams contains the input parameters of the update user transaction
+type UpdateUserTxParams struct {
+	UpdateUserByClerkIDParams
+	// Plan is stored when set, e.g. when the Clerk metadata carries one
+	Plan  *string
+	Event CreateOutboxEventParams
+	// WebhookMessageID is the Svix message id of the delivery, empty outside of webhooks
+	WebhookMessageID string
+}
+
+// UpdateUserTx updates the user and writes its user.updated event to the outbox
+// in the same transaction, so the event is never lost nor sent for a failed update.
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (KainosUser, error) {
+	var user KainosUser
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := recordDelivery(ctx, q, arg.WebhookMessageID); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = q.UpdateUserByClerkID(ctx, arg.UpdateUserByClerkIDParams)
+		if err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		if arg.Plan != nil {
+			user, err = q.UpdateUserPlanByClerkID(ctx, UpdateUserPlanByClerkIDParams{Plan: *arg.Plan, ClerkID: arg.ClerkID})
+			if err != nil {
+				return fmt.Errorf("failed to update user plan: %w", err)
+			}
+		}
+
+		if err := q.CreateOutboxEvent(ctx, arg.Event); err != nil {
+			return fmt.Errorf("failed to write outbox event: %w", err)
+		}
+
+		return nil
+	})
+
+	return user, err
+}
+
+// SoftDeleteUserTxParams contains the input parameters of the soft delete user transaction
+type SoftDeleteUserTxParams struct {
+	ClerkID string
+	Event   CreateOutboxEventParams
+	// WebhookMessageID is the Svix message id of the delivery, empty outside of webhooks
+	WebhookMessageID string
+}
+
+// SoftDeleteUserTx soft deletes the user and writes its user.deleted event to the outbox
+// in the same transaction.
+func (store *SQLStore) SoftDeleteUserTx(ctx context.Context, arg SoftDeleteUserTxParams) (KainosUser, error) {
+	var user KainosUser
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := recordDelivery(ctx, q, arg.WebhookMessageID); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = q.SoftDeleteUserByClerkID(ctx, arg.ClerkID)
+		if err != nil {
+			return fmt.Errorf("failed to soft delete user: %w", err)
+		}
+
+		if err := q.CreateOutboxEvent(ctx, arg.Event); err != nil {
+			return fmt.Errorf("failed to write outbox event: %w", err)
+		}
+
+		return nil
+	})
+
+	return user, err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestProvisionUserTx_SkipsReplayedWebhookMessage(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+
+	clerkID := "user_" + uuid.NewString()
+	params := ProvisionUserTxParams{
+		ClerkID:          clerkID,
+		Email:            clerkID + "@example.com",
+		Event:            CreateOutboxEventParams{ID: uuid.New(), Subject: "user.created", Payload: []byte(`{}`)},
+		InstanceLimit:    func(plan string) int { return 0 },
+		WebhookMessageID: "msg_" + uuid.NewString(),
+	}
+
+	if _, err := store.ProvisionUserTx(ctx, params); err != nil {
+		t.Fatalf("ProvisionUserTx: %v", err)
+	}
+
+	params.Event.ID = uuid.New()
+	if _, err := store.ProvisionUserTx(ctx, params); !errors.Is(err, ErrWebhookMessageProcessed) {
+		t.Errorf("replayed ProvisionUserTx = %v, want ErrWebhookMessageProcessed", err)
+	}
+}
+
+func TestUpdateUserTx_FailedDeliveryCanBeRetried(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	user := createTestUser(t, store)
+
+	params := UpdateUserTxParams{
+		UpdateUserByClerkIDParams: UpdateUserByClerkIDParams{ClerkID: user.ClerkID, Email: user.Email},
+		// A duplicate outbox id fails the transaction after the message id was recorded
+		Event:            CreateOutboxEventParams{ID: uuid.New(), Subject: "user.updated", Payload: []byte(`{}`)},
+		WebhookMessageID: "msg_" + uuid.NewString(),
+	}
+	if err := store.CreateOutboxEvent(ctx, params.Event); err != nil {
+		t.Fatalf("CreateOutboxEvent: %v", err)
+	}
+
+	if _, err := store.UpdateUserTx(ctx, params); err == nil || errors.Is(err, ErrWebhookMessageProcessed) {
+		t.Fatalf("UpdateUserTx = %v, want the outbox error", err)
+	}
+
+	// The message id was rolled back with it, so the retry goes through
+	params.Event.ID = uuid.New()
+	if _, err := store.UpdateUserTx(ctx, params); err != nil {
+		t.Errorf("retried UpdateUserTx: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// ErrTooManyWorkflowInstances is returned when the user already has as many workflows as their plan allows
+	ErrTooManyWorkflowInstances = errors.New("too many workflow instances")
+	// ErrUserWorkflowRunning is returned when a user workflow with a run in flight is deleted
+	ErrUserWorkflowRunning = errors.New("user workflow has a running execution")
+)
+
+// CreateUserWorkflowInstanceTxParams contains the input parameters of the create instance transaction
+type CreateUserWorkflowInstanceTxParams struct {
+	CreateUserWorkflowInstanceParams
+	ClerkID      string
+	MaxInstances int64
+}
+
+// CreateUserWorkflowInstanceTx creates a user workflow under a per-user lock once the user is
+// below MaxInstances, so concurrent requests cannot go over the plan limit.
+func (store *SQLStore) CreateUserWorkflowInstanceTx(ctx context.Context, arg CreateUserWorkflowInstanceTxParams) (KainosUserWorkflow, error) {
+	var userWorkflow KainosUserWorkflow
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockUserWorkflows(ctx, arg.ClerkID); err != nil {
+			return fmt.Errorf("failed to lock user workflows: %w", err)
+		}
+
+		count, err := q.CountUserWorkflowsByCustomerID(ctx, arg.CustomerID)
+		if err != nil {
+			return fmt.Errorf("failed to count user workflows: %w", err)
+		}
+		if count >= arg.MaxInstances {
+			return ErrTooManyWorkflowInstances
+		}
+
+		userWorkflow, err = q.CreateUserWorkflowInstance(ctx, arg.CreateUserWorkflowInstanceParams)
+		if err != nil {
+			return fmt.Errorf("failed to create user workflow: %w", err)
+		}
+		return nil
+	})
+
+	return userWorkflow, err
+}
+
+// DeleteUserWorkflowTxParams contains the input parameters of the delete user workflow transaction
+type DeleteUserWorkflowTxParams struct {
+	ClerkID        string
+	UserWorkflowID uuid.UUID
+	// RunningSince bounds the RUNNING check, like ReserveWorkflowRunTx, so stale rows do not block the delete
+	RunningSince time.Time
+}
+
+// DeleteUserWorkflowTx soft-deletes a user workflow, keeping its execution history and webhook
+// deliveries. It holds the user's run lock, so no run-now request starts a run meanwhile.
+// The caller deletes the Temporal schedule once this returns.
+func (store *SQLStore) DeleteUserWorkflowTx(ctx context.Context, arg DeleteUserWorkflowTxParams) (KainosUserWorkflow, error) {
+	var userWorkflow KainosUserWorkflow
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockUserRuns(ctx, arg.ClerkID); err != nil {
+			return fmt.Errorf("failed to lock user runs: %w", err)
+		}
+
+		running := "RUNNING"
+		count, err := q.CountWorkflowExecutions(ctx, CountWorkflowExecutionsParams{
+			UserWorkflowID: arg.UserWorkflowID,
+			Status:         &running,
+			StartedAfter:   pgtype.Timestamp{Time: arg.RunningSince.UTC(), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count running executions: %w", err)
+		}
+		if count > 0 {
+			return ErrUserWorkflowRunning
+		}
+
+		userWorkflow, err = q.SoftDeleteUserWorkflow(ctx, arg.UserWorkflowID)
+		if err != nil {
+			return fmt.Errorf("failed to delete user workflow: %w", err)
+		}
+		return nil
+	})
+
+	return userWorkflow, err
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func createTestInstance(t *testing.T, store *SQLStore, user KainosUser, workflow KainosWorkflow, maxInstances int64) (KainosUserWorkflow, error) {
+	t.Helper()
+
+	return store.CreateUserWorkflowInstanceTx(context.Background(), CreateUserWorkflowInstanceTxParams{
+		CreateUserWorkflowInstanceParams: CreateUserWorkflowInstanceParams{
+			ID:         uuid.New(),
+			WorkflowID: workflow.ID,
+			CustomerID: user.ID,
+			MetaData:   []byte(`{}`),
+			NotifyOn:   "never",
+		},
+		ClerkID:      user.ClerkID,
+		MaxInstances: maxInstances,
+	})
+}
+
+func TestCreateUserWorkflowInstanceTx_ConcurrentRequestsStayWithinLimit(t *testing.T) {
+	store := requireStore(t)
+	user := createTestUser(t, store)
+	workflow := createTestCatalogWorkflow(t, store)
+
+	const limit = 3
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := createTestInstance(t, store, user, workflow, limit)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created, refused := 0, 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, ErrTooManyWorkflowInstances):
+			refused++
+		default:
+			t.Fatalf("CreateUserWorkflowInstanceTx: %v", err)
+		}
+	}
+	if created != limit || refused != 8-limit {
+		t.Errorf("created %d and refused %d, want %d and %d", created, refused, limit, 8-limit)
+	}
+}
+
+func TestDeleteUserWorkflowTx_KeepsExecutionHistory(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	user := createTestUser(t, store)
+	workflow := createTestCatalogWorkflow(t, store)
+
+	instance, err := createTestInstance(t, store, user, workflow, 1)
+	if err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	execution, err := store.CreateWorkflowExecution(ctx, CreateWorkflowExecutionParams{
+		ID:                 uuid.New(),
+		UserWorkflowID:     instance.ID,
+		TemporalWorkflowID: "wf-" + instance.ID.String(),
+		TemporalRunID:      uuid.NewString(),
+		Status:             "SUCCEEDED",
+	})
+	if err != nil {
+		t.Fatalf("CreateWorkflowExecution: %v", err)
+	}
+
+	deleted, err := store.DeleteUserWorkflowTx(ctx, DeleteUserWorkflowTxParams{
+		ClerkID:        user.ClerkID,
+		UserWorkflowID: instance.ID,
+		RunningSince:   time.Now().Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("DeleteUserWorkflowTx: %v", err)
+	}
+	if !deleted.DeletedAt.Valid {
+		t.Error("expected deleted_at to be set")
+	}
+
+	if _, err := store.GetWorkflowExecutionByRunID(ctx, GetWorkflowExecutionByRunIDParams{
+		TemporalWorkflowID: execution.TemporalWorkflowID,
+		TemporalRunID:      execution.TemporalRunID,
+	}); err != nil {
+		t.Errorf("execution was not kept: %v", err)
+	}
+
+	workflows, err := store.GetUserWorkflowsByClerkID(ctx, user.ClerkID)
+	if err != nil {
+		t.Fatalf("GetUserWorkflowsByClerkID: %v", err)
+	}
+	if len(workflows) != 0 {
+		t.Errorf("deleted workflow is still listed: %+v", workflows)
+	}
+
+	// The deleted instance no longer counts against the limit
+	if _, err := createTestInstance(t, store, user, workflow, 1); err != nil {
+		t.Errorf("create after delete: %v", err)
+	}
+
+	_, err = store.DeleteUserWorkflowTx(ctx, DeleteUserWorkflowTxParams{
+		ClerkID:        user.ClerkID,
+		UserWorkflowID: instance.ID,
+		RunningSince:   time.Now().Add(-time.Hour),
+	})
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("deleting twice = %v, want no rows", err)
+	}
+}
+
+func TestDeleteUserWorkflowTx_RefusesRunningWorkflow(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	user := createTestUser(t, store)
+	workflow := createTestCatalogWorkflow(t, store)
+
+	instance, err := createTestInstance(t, store, user, workflow, 1)
+	if err != nil {
+		t.Fatalf("create instance: %v", err)
+	}
+	if _, err := store.CreateWorkflowExecution(ctx, CreateWorkflowExecutionParams{
+		ID:                 uuid.New(),
+		UserWorkflowID:     instance.ID,
+		TemporalWorkflowID: "wf-" + instance.ID.String(),
+		TemporalRunID:      uuid.NewString(),
+		Status:             "RUNNING",
+	}); err != nil {
+		t.Fatalf("CreateWorkflowExecution: %v", err)
+	}
+
+	_, err = store.DeleteUserWorkflowTx(ctx, DeleteUserWorkflowTxParams{
+		ClerkID:        user.ClerkID,
+		UserWorkflowID: instance.ID,
+		RunningSince:   time.Now().Add(-time.Hour),
+	})
+	if !errors.Is(err, ErrUserWorkflowRunning) {
+		t.Fatalf("DeleteUserWorkflowTx = %v, want ErrUserWorkflowRunning", err)
+	}
+}
+
+func TestProvisionUserTx_StopsAtPlanLimit(t *testing.T) {
+	store := requireStore(t)
+	ctx := context.Background()
+	for range 3 {
+		createTestCatalogWorkflow(t, store)
+	}
+
+	clerkID := "user_" + uuid.NewString()
+	params := ProvisionUserTxParams{
+		ClerkID:       clerkID,
+		Email:         clerkID + "@example.com",
+		Event:         CreateOutboxEventParams{ID: uuid.New(), Subject: "user.created", Payload: []byte(`{}`)},
+		InstanceLimit: func(plan string) int { return 2 },
+	}
+
+	result, err := store.ProvisionUserTx(ctx, params)
+	if err != nil {
+		t.Fatalf("ProvisionUserTx: %v", err)
+	}
+	if result.SubscriptionsCreated != 2 {
+		t.Errorf("created %d subscriptions, want the plan limit of 2", result.SubscriptionsCreated)
+	}
+
+	// A replayed user.created does not go past the limit either
+	params.Event.ID = uuid.New()
+	result, err = store.ProvisionUserTx(ctx, params)
+	if err != nil {
+		t.Fatalf("ProvisionUserTx again: %v", err)
+	}
+	if result.Created || result.SubscriptionsCreated != 0 {
+		t.Errorf("replay created=%v with %d subscriptions, want neither", result.Created, result.SubscriptionsCreated)
+	}
+}
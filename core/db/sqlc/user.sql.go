@@ -9,10 +9,11 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO kainos_user (id, clerk_id, first_name, email) VALUES ($1, $2, $3, $4) returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at
+INSERT INTO kainos_user (id, clerk_id, first_name, email) VALUES ($1, $2, $3, $4) returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan
 `
 
 type CreateUserParams struct {
@@ -38,12 +39,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (KainosU
 		&i.CreatedAt,
 		&i.DeletedAt,
 		&i.UpdatedAt,
+		&i.Plan,
 	)
 	return i, err
 }
 
 const getUserByClerkID = `-- name: GetUserByClerkID :one
-SELECT id, clerk_id, first_name, email, created_at, deleted_at, updated_at FROM kainos_user
+SELECT id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan FROM kainos_user
 WHERE clerk_id = $1 AND deleted_at IS NULL
 `
 
@@ -58,12 +60,13 @@ func (q *Queries) GetUserByClerkID(ctx context.Context, clerkID string) (KainosU
 		&i.CreatedAt,
 		&i.DeletedAt,
 		&i.UpdatedAt,
+		&i.Plan,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, clerk_id, first_name, email, created_at, deleted_at, updated_at FROM kainos_user
+SELECT id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan FROM kainos_user
 WHERE id = $1 AND deleted_at is NULL
 `
 
@@ -78,6 +81,7 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (KainosUser, er
 		&i.CreatedAt,
 		&i.DeletedAt,
 		&i.UpdatedAt,
+		&i.Plan,
 	)
 	return i, err
 }
@@ -86,7 +90,7 @@ const softDeleteUserByClerkID = `-- name: SoftDeleteUserByClerkID :one
 UPDATE kainos_user
 SET deleted_at = NOW()
 WHERE clerk_id = $1 AND deleted_at is NULL
-returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at
+returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan
 `
 
 func (q *Queries) SoftDeleteUserByClerkID(ctx context.Context, clerkID string) (KainosUser, error) {
@@ -100,6 +104,7 @@ func (q *Queries) SoftDeleteUserByClerkID(ctx context.Context, clerkID string) (
 		&i.CreatedAt,
 		&i.DeletedAt,
 		&i.UpdatedAt,
+		&i.Plan,
 	)
 	return i, err
 }
@@ -108,7 +113,7 @@ const updateUserByClerkID = `-- name: UpdateUserByClerkID :one
 UPDATE kainos_user
 SET first_name = $1, email = $2, updated_at = NOW()
 WHERE clerk_id = $3 AND deleted_at is NULL
-returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at
+returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan
 `
 
 type UpdateUserByClerkIDParams struct {
@@ -128,6 +133,84 @@ func (q *Queries) UpdateUserByClerkID(ctx context.Context, arg UpdateUserByClerk
 		&i.CreatedAt,
 		&i.DeletedAt,
 		&i.UpdatedAt,
+		&i.Plan,
+	)
+	return i, err
+}
+
+const updateUserPlanByClerkID = `-- name: UpdateUserPlanByClerkID :one
+UPDATE kainos_user
+SET plan = $1, updated_at = NOW()
+WHERE clerk_id = $2 AND deleted_at IS NULL
+returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan
+`
+
+type UpdateUserPlanByClerkIDParams struct {
+	Plan    string `json:"plan"`
+	ClerkID string `json:"clerk_id"`
+}
+
+func (q *Queries) UpdateUserPlanByClerkID(ctx context.Context, arg UpdateUserPlanByClerkIDParams) (KainosUser, error) {
+	row := q.db.QueryRow(ctx, updateUserPlanByClerkID, arg.Plan, arg.ClerkID)
+	var i KainosUser
+	err := row.Scan(
+		&i.ID,
+		&i.ClerkID,
+		&i.FirstName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.UpdatedAt,
+		&i.Plan,
+	)
+	return i, err
+}
+
+const upsertUserByClerkID = `-- name: UpsertUserByClerkID :one
+INSERT INTO kainos_user (id, clerk_id, first_name, email) VALUES ($1, $2, $3, $4)
+ON CONFLICT (clerk_id) DO UPDATE
+SET first_name = EXCLUDED.first_name, email = EXCLUDED.email, updated_at = NOW()
+WHERE kainos_user.deleted_at IS NULL
+returning id, clerk_id, first_name, email, created_at, deleted_at, updated_at, plan, (xmax = 0)::bool AS inserted
+`
+
+type UpsertUserByClerkIDParams struct {
+	ID        uuid.UUID `json:"id"`
+	ClerkID   string    `json:"clerk_id"`
+	FirstName *string   `json:"first_name"`
+	Email     string    `json:"email"`
+}
+
+type UpsertUserByClerkIDRow struct {
+	ID        uuid.UUID        `json:"id"`
+	ClerkID   string           `json:"clerk_id"`
+	FirstName *string          `json:"first_name"`
+	Email     string           `json:"email"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+	DeletedAt pgtype.Timestamp `json:"deleted_at"`
+	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+	Plan      string           `json:"plan"`
+	Inserted  bool             `json:"inserted"`
+}
+
+func (q *Queries) UpsertUserByClerkID(ctx context.Context, arg UpsertUserByClerkIDParams) (UpsertUserByClerkIDRow, error) {
+	row := q.db.QueryRow(ctx, upsertUserByClerkID,
+		arg.ID,
+		arg.ClerkID,
+		arg.FirstName,
+		arg.Email,
+	)
+	var i UpsertUserByClerkIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.ClerkID,
+		&i.FirstName,
+		&i.Email,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.UpdatedAt,
+		&i.Plan,
+		&i.Inserted,
 	)
 	return i, err
 }
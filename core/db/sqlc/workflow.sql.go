@@ -12,8 +12,48 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countUserWorkflowsByCustomerID = `-- name: CountUserWorkflowsByCustomerID :one
+SELECT count(*) FROM kainos_user_workflow uw
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.customer_id = $1 AND uw.deleted_at IS NULL AND w.deleted_at IS NULL
+`
+
+func (q *Queries) CountUserWorkflowsByCustomerID(ctx context.Context, customerID interface{}) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserWorkflowsByCustomerID, customerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createDefaultUserWorkflows = `-- name: CreateDefaultUserWorkflows :execrows
+INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, status, cron_time, schedule_preset, time_zone)
+SELECT gen_random_uuid(), w.id, $1::uuid, '{}', 'OFF', w.default_cron_time, w.default_schedule_preset, w.default_time_zone
+FROM kainos_workflow w
+WHERE w.deleted_at IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM kainos_user_workflow uw
+    WHERE uw.customer_id = $1::uuid AND uw.workflow_id = w.id
+  )
+ORDER BY w.created_at, w.id
+LIMIT $2
+`
+
+type CreateDefaultUserWorkflowsParams struct {
+	CustomerID uuid.UUID `json:"customer_id"`
+	MaxCount   int32     `json:"max_count"`
+}
+
+// Subscribes the user to the catalog workflows it has none of yet, oldest first, at most max_count of them
+func (q *Queries) CreateDefaultUserWorkflows(ctx context.Context, arg CreateDefaultUserWorkflowsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createDefaultUserWorkflows, arg.CustomerID, arg.MaxCount)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createUserWorkflow = `-- name: CreateUserWorkflow :one
-INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, status) VALUES ($1, $2, $3, $4, $5) returning id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at
+INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, meta_data, status) VALUES ($1, $2, $3, $4, $5) returning id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
 `
 
 type CreateUserWorkflowParams struct {
@@ -42,19 +82,85 @@ func (q *Queries) CreateUserWorkflow(ctx context.Context, arg CreateUserWorkflow
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const createUserWorkflowInstance = `-- name: CreateUserWorkflowInstance :one
+INSERT INTO kainos_user_workflow (id, workflow_id, customer_id, name, meta_data, cron_time, schedule_preset, time_zone, notify_on, status)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'OFF')
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
+`
+
+type CreateUserWorkflowInstanceParams struct {
+	ID             uuid.UUID   `json:"id"`
+	WorkflowID     uuid.UUID   `json:"workflow_id"`
+	CustomerID     interface{} `json:"customer_id"`
+	Name           *string     `json:"name"`
+	MetaData       []byte      `json:"meta_data"`
+	CronTime       *string     `json:"cron_time"`
+	SchedulePreset *string     `json:"schedule_preset"`
+	TimeZone       *string     `json:"time_zone"`
+	NotifyOn       string      `json:"notify_on"`
+}
+
+func (q *Queries) CreateUserWorkflowInstance(ctx context.Context, arg CreateUserWorkflowInstanceParams) (KainosUserWorkflow, error) {
+	row := q.db.QueryRow(ctx, createUserWorkflowInstance,
+		arg.ID,
+		arg.WorkflowID,
+		arg.CustomerID,
+		arg.Name,
+		arg.MetaData,
+		arg.CronTime,
+		arg.SchedulePreset,
+		arg.TimeZone,
+		arg.NotifyOn,
+	)
+	var i KainosUserWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
 const createWorkflow = `-- name: CreateWorkflow :one
-INSERT INTO kainos_workflow (id, workflow_name, workflow_description, price) VALUES ($1, $2, $3, $4) returning id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price
+INSERT INTO kainos_workflow (
+    id, workflow_name, workflow_description, price, mastra_workflow_id,
+    default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue
+) VALUES (
+    $1, $2, $3, $4, $5,
+    $6, $7, $8, $9, $10
+) returning id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue
 `
 
 type CreateWorkflowParams struct {
-	ID                  uuid.UUID `json:"id"`
-	WorkflowName        string    `json:"workflow_name"`
-	WorkflowDescription string    `json:"workflow_description"`
-	Price               *float64  `json:"price"`
+	ID                    uuid.UUID `json:"id"`
+	WorkflowName          string    `json:"workflow_name"`
+	WorkflowDescription   string    `json:"workflow_description"`
+	Price                 *float64  `json:"price"`
+	MastraWorkflowID      *string   `json:"mastra_workflow_id"`
+	DefaultCronTime       *string   `json:"default_cron_time"`
+	DefaultSchedulePreset *string   `json:"default_schedule_preset"`
+	DefaultTimeZone       *string   `json:"default_time_zone"`
+	InputSchema           []byte    `json:"input_schema"`
+	TaskQueue             *string   `json:"task_queue"`
 }
 
 func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams) (KainosWorkflow, error) {
@@ -63,6 +169,12 @@ func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams)
 		arg.WorkflowName,
 		arg.WorkflowDescription,
 		arg.Price,
+		arg.MastraWorkflowID,
+		arg.DefaultCronTime,
+		arg.DefaultSchedulePreset,
+		arg.DefaultTimeZone,
+		arg.InputSchema,
+		arg.TaskQueue,
 	)
 	var i KainosWorkflow
 	err := row.Scan(
@@ -73,6 +185,12 @@ func (q *Queries) CreateWorkflow(ctx context.Context, arg CreateWorkflowParams)
 		&i.DeletedAt,
 		&i.UpdatedAt,
 		&i.Price,
+		&i.MastraWorkflowID,
+		&i.DefaultCronTime,
+		&i.DefaultSchedulePreset,
+		&i.DefaultTimeZone,
+		&i.InputSchema,
+		&i.TaskQueue,
 	)
 	return i, err
 }
@@ -87,12 +205,17 @@ SELECT
     uw.status,
     uw.created_at,
     uw.updated_at,
+    uw.schedule_preset,
+    uw.time_zone,
+    uw.name,
+    uw.notify_on,
     w.workflow_name,
     w.workflow_description,
-    w.price
+    w.price,
+    w.mastra_workflow_id
 FROM kainos_user_workflow uw
          JOIN kainos_workflow w ON uw.workflow_id = w.id
-WHERE uw.id = $1
+WHERE uw.id = $1 AND uw.deleted_at IS NULL
 `
 
 type GetUserWorkflowByIDRow struct {
@@ -104,9 +227,14 @@ type GetUserWorkflowByIDRow struct {
 	Status              *string          `json:"status"`
 	CreatedAt           pgtype.Timestamp `json:"created_at"`
 	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	SchedulePreset      *string          `json:"schedule_preset"`
+	TimeZone            *string          `json:"time_zone"`
+	Name                *string          `json:"name"`
+	NotifyOn            string           `json:"notify_on"`
 	WorkflowName        string           `json:"workflow_name"`
 	WorkflowDescription string           `json:"workflow_description"`
 	Price               *float64         `json:"price"`
+	MastraWorkflowID    *string          `json:"mastra_workflow_id"`
 }
 
 func (q *Queries) GetUserWorkflowByID(ctx context.Context, id uuid.UUID) (GetUserWorkflowByIDRow, error) {
@@ -121,21 +249,138 @@ func (q *Queries) GetUserWorkflowByID(ctx context.Context, id uuid.UUID) (GetUse
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.WorkflowName,
+		&i.WorkflowDescription,
+		&i.Price,
+		&i.MastraWorkflowID,
+	)
+	return i, err
+}
+
+const getUserWorkflowByIDAndClerkID = `-- name: GetUserWorkflowByIDAndClerkID :one
+SELECT
+    uw.id,
+    uw.workflow_id,
+    uw.customer_id,
+    uw.meta_data,
+    uw.cron_time,
+    uw.status,
+    uw.created_at,
+    uw.updated_at,
+    uw.schedule_preset,
+    uw.time_zone,
+    uw.name,
+    uw.notify_on,
+    w.workflow_name,
+    w.workflow_description,
+    w.price,
+    w.mastra_workflow_id
+FROM kainos_user_workflow uw
+         JOIN kainos_workflow w ON uw.workflow_id = w.id
+         JOIN kainos_user u ON uw.customer_id = u.id
+WHERE uw.id = $1 AND u.clerk_id = $2 AND uw.deleted_at IS NULL AND u.deleted_at IS NULL AND w.deleted_at IS NULL
+`
+
+type GetUserWorkflowByIDAndClerkIDParams struct {
+	ID      uuid.UUID `json:"id"`
+	ClerkID string    `json:"clerk_id"`
+}
+
+type GetUserWorkflowByIDAndClerkIDRow struct {
+	ID                  uuid.UUID        `json:"id"`
+	WorkflowID          uuid.UUID        `json:"workflow_id"`
+	CustomerID          interface{}      `json:"customer_id"`
+	MetaData            []byte           `json:"meta_data"`
+	CronTime            *string          `json:"cron_time"`
+	Status              *string          `json:"status"`
+	CreatedAt           pgtype.Timestamp `json:"created_at"`
+	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	SchedulePreset      *string          `json:"schedule_preset"`
+	TimeZone            *string          `json:"time_zone"`
+	Name                *string          `json:"name"`
+	NotifyOn            string           `json:"notify_on"`
+	WorkflowName        string           `json:"workflow_name"`
+	WorkflowDescription string           `json:"workflow_description"`
+	Price               *float64         `json:"price"`
+	MastraWorkflowID    *string          `json:"mastra_workflow_id"`
+}
+
+func (q *Queries) GetUserWorkflowByIDAndClerkID(ctx context.Context, arg GetUserWorkflowByIDAndClerkIDParams) (GetUserWorkflowByIDAndClerkIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserWorkflowByIDAndClerkID, arg.ID, arg.ClerkID)
+	var i GetUserWorkflowByIDAndClerkIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
 		&i.WorkflowName,
 		&i.WorkflowDescription,
 		&i.Price,
+		&i.MastraWorkflowID,
+	)
+	return i, err
+}
+
+const getUserWorkflowSchedule = `-- name: GetUserWorkflowSchedule :one
+SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at, uw.updated_at, uw.schedule_preset, uw.time_zone, uw.name, uw.notify_on, uw.deleted_at, (u.deleted_at IS NOT NULL)::bool AS user_deleted, (w.deleted_at IS NOT NULL)::bool AS workflow_deleted, w.task_queue
+FROM kainos_user_workflow uw
+JOIN kainos_user u ON uw.customer_id = u.id
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.id = $1 AND uw.deleted_at IS NULL
+`
+
+type GetUserWorkflowScheduleRow struct {
+	KainosUserWorkflow KainosUserWorkflow `json:"kainos_user_workflow"`
+	UserDeleted        bool               `json:"user_deleted"`
+	WorkflowDeleted    bool               `json:"workflow_deleted"`
+	TaskQueue          *string            `json:"task_queue"`
+}
+
+// One row of ListUserWorkflowSchedules, re-read by the reconciler right before it changes a schedule.
+func (q *Queries) GetUserWorkflowSchedule(ctx context.Context, id uuid.UUID) (GetUserWorkflowScheduleRow, error) {
+	row := q.db.QueryRow(ctx, getUserWorkflowSchedule, id)
+	var i GetUserWorkflowScheduleRow
+	err := row.Scan(
+		&i.KainosUserWorkflow.ID,
+		&i.KainosUserWorkflow.WorkflowID,
+		&i.KainosUserWorkflow.CustomerID,
+		&i.KainosUserWorkflow.MetaData,
+		&i.KainosUserWorkflow.CronTime,
+		&i.KainosUserWorkflow.Status,
+		&i.KainosUserWorkflow.CreatedAt,
+		&i.KainosUserWorkflow.UpdatedAt,
+		&i.KainosUserWorkflow.SchedulePreset,
+		&i.KainosUserWorkflow.TimeZone,
+		&i.KainosUserWorkflow.Name,
+		&i.KainosUserWorkflow.NotifyOn,
+		&i.KainosUserWorkflow.DeletedAt,
+		&i.UserDeleted,
+		&i.WorkflowDeleted,
+		&i.TaskQueue,
 	)
 	return i, err
 }
 
 const getUserWorkflowsByClerkID = `-- name: GetUserWorkflowsByClerkID :many
 
-SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at,
-       uw.updated_at, w.workflow_name, w.workflow_description, w.price
+SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at, uw.updated_at, uw.schedule_preset, uw.time_zone, uw.name, uw.notify_on,
+       w.workflow_name, w.workflow_description, w.price, w.mastra_workflow_id
 FROM kainos_user_workflow uw
 JOIN kainos_workflow w ON uw.workflow_id = w.id
 JOIN kainos_user u ON uw.customer_id = u.id
-WHERE u.clerk_id = $1 AND u.deleted_at is NULL
+WHERE u.clerk_id = $1 AND uw.deleted_at IS NULL AND u.deleted_at is NULL AND w.deleted_at IS NULL
 `
 
 type GetUserWorkflowsByClerkIDRow struct {
@@ -147,9 +392,14 @@ type GetUserWorkflowsByClerkIDRow struct {
 	Status              *string          `json:"status"`
 	CreatedAt           pgtype.Timestamp `json:"created_at"`
 	UpdatedAt           pgtype.Timestamp `json:"updated_at"`
+	SchedulePreset      *string          `json:"schedule_preset"`
+	TimeZone            *string          `json:"time_zone"`
+	Name                *string          `json:"name"`
+	NotifyOn            string           `json:"notify_on"`
 	WorkflowName        string           `json:"workflow_name"`
 	WorkflowDescription string           `json:"workflow_description"`
 	Price               *float64         `json:"price"`
+	MastraWorkflowID    *string          `json:"mastra_workflow_id"`
 }
 
 // -- name: GetUserWorkflow :many
@@ -175,9 +425,14 @@ func (q *Queries) GetUserWorkflowsByClerkID(ctx context.Context, clerkID string)
 			&i.Status,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SchedulePreset,
+			&i.TimeZone,
+			&i.Name,
+			&i.NotifyOn,
 			&i.WorkflowName,
 			&i.WorkflowDescription,
 			&i.Price,
+			&i.MastraWorkflowID,
 		); err != nil {
 			return nil, err
 		}
@@ -190,7 +445,7 @@ func (q *Queries) GetUserWorkflowsByClerkID(ctx context.Context, clerkID string)
 }
 
 const getWorkflow = `-- name: GetWorkflow :many
-SELECT id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price from kainos_workflow
+SELECT id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue from kainos_workflow
 WHERE deleted_at is NULL
 `
 
@@ -211,6 +466,207 @@ func (q *Queries) GetWorkflow(ctx context.Context) ([]KainosWorkflow, error) {
 			&i.DeletedAt,
 			&i.UpdatedAt,
 			&i.Price,
+			&i.MastraWorkflowID,
+			&i.DefaultCronTime,
+			&i.DefaultSchedulePreset,
+			&i.DefaultTimeZone,
+			&i.InputSchema,
+			&i.TaskQueue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getWorkflowByID = `-- name: GetWorkflowByID :one
+SELECT id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue FROM kainos_workflow
+WHERE id = $1
+`
+
+func (q *Queries) GetWorkflowByID(ctx context.Context, id uuid.UUID) (KainosWorkflow, error) {
+	row := q.db.QueryRow(ctx, getWorkflowByID, id)
+	var i KainosWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowName,
+		&i.WorkflowDescription,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.UpdatedAt,
+		&i.Price,
+		&i.MastraWorkflowID,
+		&i.DefaultCronTime,
+		&i.DefaultSchedulePreset,
+		&i.DefaultTimeZone,
+		&i.InputSchema,
+		&i.TaskQueue,
+	)
+	return i, err
+}
+
+const getWorkflowNotificationTarget = `-- name: GetWorkflowNotificationTarget :one
+SELECT uw.id, uw.name, uw.notify_on, w.workflow_name, u.clerk_id, u.email, u.first_name
+FROM kainos_user_workflow uw
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+JOIN kainos_user u ON uw.customer_id = u.id
+WHERE uw.id = $1
+`
+
+type GetWorkflowNotificationTargetRow struct {
+	ID           uuid.UUID `json:"id"`
+	Name         *string   `json:"name"`
+	NotifyOn     string    `json:"notify_on"`
+	WorkflowName string    `json:"workflow_name"`
+	ClerkID      string    `json:"clerk_id"`
+	Email        string    `json:"email"`
+	FirstName    *string   `json:"first_name"`
+}
+
+// Who a finished run of the user workflow is reported to, and when
+func (q *Queries) GetWorkflowNotificationTarget(ctx context.Context, id uuid.UUID) (GetWorkflowNotificationTargetRow, error) {
+	row := q.db.QueryRow(ctx, getWorkflowNotificationTarget, id)
+	var i GetWorkflowNotificationTargetRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.NotifyOn,
+		&i.WorkflowName,
+		&i.ClerkID,
+		&i.Email,
+		&i.FirstName,
+	)
+	return i, err
+}
+
+const listUserWorkflowSchedules = `-- name: ListUserWorkflowSchedules :many
+SELECT uw.id, uw.workflow_id, uw.customer_id, uw.meta_data, uw.cron_time, uw.status, uw.created_at, uw.updated_at, uw.schedule_preset, uw.time_zone, uw.name, uw.notify_on, uw.deleted_at, (u.deleted_at IS NOT NULL)::bool AS user_deleted, (w.deleted_at IS NOT NULL)::bool AS workflow_deleted, w.task_queue
+FROM kainos_user_workflow uw
+JOIN kainos_user u ON uw.customer_id = u.id
+JOIN kainos_workflow w ON uw.workflow_id = w.id
+WHERE uw.deleted_at IS NULL
+ORDER BY uw.id
+`
+
+type ListUserWorkflowSchedulesRow struct {
+	KainosUserWorkflow KainosUserWorkflow `json:"kainos_user_workflow"`
+	UserDeleted        bool               `json:"user_deleted"`
+	WorkflowDeleted    bool               `json:"workflow_deleted"`
+	TaskQueue          *string            `json:"task_queue"`
+}
+
+// Every user workflow with whether its owner or catalog entry was deleted and the catalog task queue,
+// for reconciling Temporal schedules. Deleted user workflows are left out, so their schedules are orphans.
+func (q *Queries) ListUserWorkflowSchedules(ctx context.Context) ([]ListUserWorkflowSchedulesRow, error) {
+	rows, err := q.db.Query(ctx, listUserWorkflowSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserWorkflowSchedulesRow{}
+	for rows.Next() {
+		var i ListUserWorkflowSchedulesRow
+		if err := rows.Scan(
+			&i.KainosUserWorkflow.ID,
+			&i.KainosUserWorkflow.WorkflowID,
+			&i.KainosUserWorkflow.CustomerID,
+			&i.KainosUserWorkflow.MetaData,
+			&i.KainosUserWorkflow.CronTime,
+			&i.KainosUserWorkflow.Status,
+			&i.KainosUserWorkflow.CreatedAt,
+			&i.KainosUserWorkflow.UpdatedAt,
+			&i.KainosUserWorkflow.SchedulePreset,
+			&i.KainosUserWorkflow.TimeZone,
+			&i.KainosUserWorkflow.Name,
+			&i.KainosUserWorkflow.NotifyOn,
+			&i.KainosUserWorkflow.DeletedAt,
+			&i.UserDeleted,
+			&i.WorkflowDeleted,
+			&i.TaskQueue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserWorkflowsByWorkflowID = `-- name: ListUserWorkflowsByWorkflowID :many
+SELECT id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at FROM kainos_user_workflow
+WHERE workflow_id = $1 AND deleted_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListUserWorkflowsByWorkflowID(ctx context.Context, workflowID uuid.UUID) ([]KainosUserWorkflow, error) {
+	rows, err := q.db.Query(ctx, listUserWorkflowsByWorkflowID, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []KainosUserWorkflow{}
+	for rows.Next() {
+		var i KainosUserWorkflow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowID,
+			&i.CustomerID,
+			&i.MetaData,
+			&i.CronTime,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SchedulePreset,
+			&i.TimeZone,
+			&i.Name,
+			&i.NotifyOn,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWorkflows = `-- name: ListWorkflows :many
+SELECT id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue FROM kainos_workflow
+WHERE $1::bool OR deleted_at IS NULL
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListWorkflows(ctx context.Context, includeDeleted bool) ([]KainosWorkflow, error) {
+	rows, err := q.db.Query(ctx, listWorkflows, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []KainosWorkflow{}
+	for rows.Next() {
+		var i KainosWorkflow
+		if err := rows.Scan(
+			&i.ID,
+			&i.WorkflowName,
+			&i.WorkflowDescription,
+			&i.CreatedAt,
+			&i.DeletedAt,
+			&i.UpdatedAt,
+			&i.Price,
+			&i.MastraWorkflowID,
+			&i.DefaultCronTime,
+			&i.DefaultSchedulePreset,
+			&i.DefaultTimeZone,
+			&i.InputSchema,
+			&i.TaskQueue,
 		); err != nil {
 			return nil, err
 		}
@@ -222,24 +678,199 @@ func (q *Queries) GetWorkflow(ctx context.Context) ([]KainosWorkflow, error) {
 	return items, nil
 }
 
+const lockUserWorkflows = `-- name: LockUserWorkflows :exec
+SELECT pg_advisory_xact_lock(hashtext('user_workflows:' || $1::text))
+`
+
+// Serializes instance creation of one user until the end of the transaction
+func (q *Queries) LockUserWorkflows(ctx context.Context, clerkID string) error {
+	_, err := q.db.Exec(ctx, lockUserWorkflows, clerkID)
+	return err
+}
+
+const renameUserWorkflow = `-- name: RenameUserWorkflow :one
+UPDATE kainos_user_workflow
+SET name = $1, updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
+`
+
+type RenameUserWorkflowParams struct {
+	Name *string   `json:"name"`
+	ID   uuid.UUID `json:"id"`
+}
+
+func (q *Queries) RenameUserWorkflow(ctx context.Context, arg RenameUserWorkflowParams) (KainosUserWorkflow, error) {
+	row := q.db.QueryRow(ctx, renameUserWorkflow, arg.Name, arg.ID)
+	var i KainosUserWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const softDeleteUserWorkflow = `-- name: SoftDeleteUserWorkflow :one
+UPDATE kainos_user_workflow
+SET deleted_at = NOW(), status = 'OFF', updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
+`
+
+func (q *Queries) SoftDeleteUserWorkflow(ctx context.Context, id uuid.UUID) (KainosUserWorkflow, error) {
+	row := q.db.QueryRow(ctx, softDeleteUserWorkflow, id)
+	var i KainosUserWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const softDeleteWorkflow = `-- name: SoftDeleteWorkflow :one
+UPDATE kainos_workflow
+SET deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue
+`
+
+func (q *Queries) SoftDeleteWorkflow(ctx context.Context, id uuid.UUID) (KainosWorkflow, error) {
+	row := q.db.QueryRow(ctx, softDeleteWorkflow, id)
+	var i KainosWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowName,
+		&i.WorkflowDescription,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.UpdatedAt,
+		&i.Price,
+		&i.MastraWorkflowID,
+		&i.DefaultCronTime,
+		&i.DefaultSchedulePreset,
+		&i.DefaultTimeZone,
+		&i.InputSchema,
+		&i.TaskQueue,
+	)
+	return i, err
+}
+
+const updateUserWorkflowMetaData = `-- name: UpdateUserWorkflowMetaData :one
+UPDATE kainos_user_workflow
+SET meta_data = $1, updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
+`
+
+type UpdateUserWorkflowMetaDataParams struct {
+	MetaData []byte    `json:"meta_data"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateUserWorkflowMetaData(ctx context.Context, arg UpdateUserWorkflowMetaDataParams) (KainosUserWorkflow, error) {
+	row := q.db.QueryRow(ctx, updateUserWorkflowMetaData, arg.MetaData, arg.ID)
+	var i KainosUserWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateUserWorkflowNotifyOn = `-- name: UpdateUserWorkflowNotifyOn :one
+UPDATE kainos_user_workflow
+SET notify_on = $1, updated_at = NOW()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
+`
+
+type UpdateUserWorkflowNotifyOnParams struct {
+	NotifyOn string    `json:"notify_on"`
+	ID       uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateUserWorkflowNotifyOn(ctx context.Context, arg UpdateUserWorkflowNotifyOnParams) (KainosUserWorkflow, error) {
+	row := q.db.QueryRow(ctx, updateUserWorkflowNotifyOn, arg.NotifyOn, arg.ID)
+	var i KainosUserWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowID,
+		&i.CustomerID,
+		&i.MetaData,
+		&i.CronTime,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
 const updateUserWorkflowSchedule = `-- name: UpdateUserWorkflowSchedule :one
 UPDATE kainos_user_workflow
 SET
     cron_time = $1,
-    status = $2,
+    schedule_preset = $2,
+    time_zone = $3,
+    status = $4,
     updated_at = NOW()
-WHERE id = $3
-RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at
+WHERE id = $5 AND deleted_at IS NULL
+RETURNING id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
 `
 
 type UpdateUserWorkflowScheduleParams struct {
-	CronTime *string   `json:"cron_time"`
-	Status   *string   `json:"status"`
-	ID       uuid.UUID `json:"id"`
+	CronTime       *string   `json:"cron_time"`
+	SchedulePreset *string   `json:"schedule_preset"`
+	TimeZone       *string   `json:"time_zone"`
+	Status         *string   `json:"status"`
+	ID             uuid.UUID `json:"id"`
 }
 
 func (q *Queries) UpdateUserWorkflowSchedule(ctx context.Context, arg UpdateUserWorkflowScheduleParams) (KainosUserWorkflow, error) {
-	row := q.db.QueryRow(ctx, updateUserWorkflowSchedule, arg.CronTime, arg.Status, arg.ID)
+	row := q.db.QueryRow(ctx, updateUserWorkflowSchedule,
+		arg.CronTime,
+		arg.SchedulePreset,
+		arg.TimeZone,
+		arg.Status,
+		arg.ID,
+	)
 	var i KainosUserWorkflow
 	err := row.Scan(
 		&i.ID,
@@ -250,6 +881,11 @@ func (q *Queries) UpdateUserWorkflowSchedule(ctx context.Context, arg UpdateUser
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -257,8 +893,8 @@ func (q *Queries) UpdateUserWorkflowSchedule(ctx context.Context, arg UpdateUser
 const updateUserWorkflowStatus = `-- name: UpdateUserWorkflowStatus :one
 UPDATE kainos_user_workflow
 SET status = $1, updated_at = NOW()
-WHERE id = $2
-returning id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at
+WHERE id = $2 AND deleted_at IS NULL
+returning id, workflow_id, customer_id, meta_data, cron_time, status, created_at, updated_at, schedule_preset, time_zone, name, notify_on, deleted_at
 `
 
 type UpdateUserWorkflowStatusParams struct {
@@ -278,6 +914,73 @@ func (q *Queries) UpdateUserWorkflowStatus(ctx context.Context, arg UpdateUserWo
 		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SchedulePreset,
+		&i.TimeZone,
+		&i.Name,
+		&i.NotifyOn,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateWorkflow = `-- name: UpdateWorkflow :one
+UPDATE kainos_workflow
+SET
+    workflow_name = $1,
+    workflow_description = $2,
+    price = $3,
+    mastra_workflow_id = $4,
+    default_cron_time = $5,
+    default_schedule_preset = $6,
+    default_time_zone = $7,
+    input_schema = $8,
+    task_queue = $9,
+    updated_at = NOW()
+WHERE id = $10 AND deleted_at IS NULL
+RETURNING id, workflow_name, workflow_description, created_at, deleted_at, updated_at, price, mastra_workflow_id, default_cron_time, default_schedule_preset, default_time_zone, input_schema, task_queue
+`
+
+type UpdateWorkflowParams struct {
+	WorkflowName          string    `json:"workflow_name"`
+	WorkflowDescription   string    `json:"workflow_description"`
+	Price                 *float64  `json:"price"`
+	MastraWorkflowID      *string   `json:"mastra_workflow_id"`
+	DefaultCronTime       *string   `json:"default_cron_time"`
+	DefaultSchedulePreset *string   `json:"default_schedule_preset"`
+	DefaultTimeZone       *string   `json:"default_time_zone"`
+	InputSchema           []byte    `json:"input_schema"`
+	TaskQueue             *string   `json:"task_queue"`
+	ID                    uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateWorkflow(ctx context.Context, arg UpdateWorkflowParams) (KainosWorkflow, error) {
+	row := q.db.QueryRow(ctx, updateWorkflow,
+		arg.WorkflowName,
+		arg.WorkflowDescription,
+		arg.Price,
+		arg.MastraWorkflowID,
+		arg.DefaultCronTime,
+		arg.DefaultSchedulePreset,
+		arg.DefaultTimeZone,
+		arg.InputSchema,
+		arg.TaskQueue,
+		arg.ID,
+	)
+	var i KainosWorkflow
+	err := row.Scan(
+		&i.ID,
+		&i.WorkflowName,
+		&i.WorkflowDescription,
+		&i.CreatedAt,
+		&i.DeletedAt,
+		&i.UpdatedAt,
+		&i.Price,
+		&i.MastraWorkflowID,
+		&i.DefaultCronTime,
+		&i.DefaultSchedulePreset,
+		&i.DefaultTimeZone,
+		&i.InputSchema,
+		&i.TaskQueue,
 	)
 	return i, err
 }
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"stock-agent.io/internal/types"
+)
+
+// SubjectWorkflowProgressPrefix - Live events of a run go to progress.workflow.<temporal run id>. They are
+// plain NATS messages, outside the workflow.> stream: nobody listening means nobody needs them.
+const SubjectWorkflowProgressPrefix = "progress.workflow."
+
+// progressBuffer bounds the events held for a slow stream; later ones are dropped rather than blocking NATS
+const progressBuffer = 256
+
+// Broker metrics, served with the other expvars on /api/v1/admin/debug/vars
+var (
+	progressMetrics = expvar.NewMap("progress_broker")
+	progressDropped = new(expvar.Int)
+)
+
+func init() {
+	progressMetrics.Set("dropped_total", progressDropped)
+}
+
+// ProgressBroker relays the live events of runs from the worker to the execution streams
+type ProgressBroker struct {
+	nc *nats.Conn
+}
+
+func NewProgressBroker(nc *nats.Conn) *ProgressBroker {
+	return &ProgressBroker{nc: nc}
+}
+
+// Publish sends an event of a run. Progress is best effort, so failures are only logged.
+func (b *ProgressBroker) Publish(runID string, event types.ExecutionStreamEvent) {
+	if b == nil || b.nc == nil || !b.nc.IsConnected() {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Debug().Err(err).Str("run_id", runID).Msg("Failed to marshal progress event")
+		return
+	}
+	if err := b.nc.Publish(SubjectWorkflowProgressPrefix+runID, data); err != nil {
+		log.Debug().Err(err).Str("run_id", runID).Msg("Failed to publish progress event")
+	}
+}
+
+// Subscribe receives the events of a run until unsubscribe is called
+func (b *ProgressBroker) Subscribe(runID string) (events <-chan types.ExecutionStreamEvent, unsubscribe func(), err error) {
+	if b == nil || b.nc == nil {
+		return nil, nil, fmt.Errorf("NATS connection is not available")
+	}
+
+	ch := make(chan types.ExecutionStreamEvent, progressBuffer)
+	sub, err := b.nc.Subscribe(SubjectWorkflowProgressPrefix+runID, func(msg *nats.Msg) {
+		deliverProgress(ch, runID, msg.Data)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to subscribe to progress of run %s: %w", runID, err)
+	}
+
+	return ch, func() { _ = sub.Unsubscribe() }, nil
+}
+
+// deliverProgress queues an event for the stream without blocking; events past a full buffer are
+// dropped and counted
+func deliverProgress(ch chan<- types.ExecutionStreamEvent, runID string, data []byte) {
+	var event types.ExecutionStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return
+	}
+	select {
+	case ch <- event:
+	default:
+		progressDropped.Add(1)
+		log.Debug().Str("run_id", runID).Str("event_type", event.Type).Msg("Dropped progress event of a slow stream")
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+
+	"stock-agent.io/internal/types"
+)
+
+func TestDeliverProgress_DropsWhenFull(t *testing.T) {
+	ch := make(chan types.ExecutionStreamEvent, 2)
+	data, err := json.Marshal(types.ExecutionStreamEvent{Type: types.StreamEventToken, Token: "a"})
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	before := progressDropped.Value()
+	for i := 0; i < 5; i++ {
+		deliverProgress(ch, "run-1", data)
+	}
+
+	if len(ch) != 2 {
+		t.Errorf("queued %d events, want 2", len(ch))
+	}
+	if dropped := progressDropped.Value() - before; dropped != 3 {
+		t.Errorf("dropped_total grew by %d, want 3", dropped)
+	}
+	if event := <-ch; event.Type != types.StreamEventToken || event.Token != "a" {
+		t.Errorf("queued %+v, want the token event", event)
+	}
+}
+
+func TestDeliverProgress_SkipsInvalidEvents(t *testing.T) {
+	ch := make(chan types.ExecutionStreamEvent, 1)
+	before := progressDropped.Value()
+
+	deliverProgress(ch, "run-1", []byte("not json"))
+
+	if len(ch) != 0 {
+		t.Errorf("queued %d events, want none", len(ch))
+	}
+	if progressDropped.Value() != before {
+		t.Error("an invalid event was counted as dropped")
+	}
+}
+
+func TestProgressBroker_WithoutConnection(t *testing.T) {
+	var broker *ProgressBroker
+	broker.Publish("run-1", types.ExecutionStreamEvent{Type: types.StreamEventToken})
+
+	for _, b := range []*ProgressBroker{nil, NewProgressBroker(nil)} {
+		if _, _, err := b.Subscribe("run-1"); err == nil {
+			t.Error("Subscribe without a NATS connection returned no error")
+		}
+	}
+}
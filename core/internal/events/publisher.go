@@ -8,6 +8,13 @@ import (
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+)
+
+const (
+	SubjectUserCreated = "user.created"
+	SubjectUserUpdated = "user.updated"
+	SubjectUserDeleted = "user.deleted"
 )
 
 type Publisher struct {
@@ -26,28 +33,33 @@ func NewPublisher(nc *nats.Conn) *Publisher {
 	return &Publisher{nc: nc}
 }
 
-func (p *Publisher) PublishUserCreated(userID, email, firstName, lastName string) error {
-	event := &Event{
+// NewUserCreatedEvent builds the user.created event sent when a Clerk user signs up
+func NewUserCreatedEvent(userID, email, firstName, lastName string) *Event {
+	return newUserEvent(SubjectUserCreated, userID, email, firstName, lastName)
+}
+
+// NewUserUpdatedEvent builds the user.updated event sent when a Clerk user changes
+func NewUserUpdatedEvent(userID, email, firstName, lastName string) *Event {
+	return newUserEvent(SubjectUserUpdated, userID, email, firstName, lastName)
+}
+
+// NewUserDeletedEvent builds the user.deleted event sent when a Clerk user is removed
+func NewUserDeletedEvent(userID string) *Event {
+	return &Event{
 		ID:        uuid.New().String(),
-		Type:      "user.created",
+		Type:      SubjectUserDeleted,
 		Timestamp: time.Now().UTC(),
 		Source:    "core-api",
 		Data: map[string]interface{}{
-			"user_id":    userID,
-			"email":      email,
-			"name":       fmt.Sprintf("%s %s", firstName, lastName),
-			"first_name": firstName,
-			"last_name":  lastName,
+			"user_id": userID,
 		},
 	}
-
-	return p.publish("user.created", event)
 }
 
-func (p *Publisher) PublishUserUpdated(userID, email, firstName, lastName string) error {
-	event := &Event{
+func newUserEvent(eventType, userID, email, firstName, lastName string) *Event {
+	return &Event{
 		ID:        uuid.New().String(),
-		Type:      "user.updated",
+		Type:      eventType,
 		Timestamp: time.Now().UTC(),
 		Source:    "core-api",
 		Data: map[string]interface{}{
@@ -58,22 +70,37 @@ func (p *Publisher) PublishUserUpdated(userID, email, firstName, lastName string
 			"last_name":  lastName,
 		},
 	}
-
-	return p.publish("user.updated", event)
 }
 
-func (p *Publisher) PublishUserDeleted(userID string) error {
-	event := &Event{
-		ID:        uuid.New().String(),
-		Type:      "user.deleted",
-		Timestamp: time.Now().UTC(),
-		Source:    "core-api",
-		Data: map[string]interface{}{
-			"user_id": userID,
-		},
+// OutboxParams turns the event into an outbox row published on the subject named by its type
+func (e *Event) OutboxParams() (db.CreateOutboxEventParams, error) {
+	id, err := uuid.Parse(e.ID)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("invalid event id: %w", err)
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	return p.publish("user.deleted", event)
+	return db.CreateOutboxEventParams{
+		ID:      id,
+		Subject: e.Type,
+		Payload: payload,
+	}, nil
+}
+
+func (p *Publisher) PublishUserCreated(userID, email, firstName, lastName string) error {
+	return p.publish(SubjectUserCreated, NewUserCreatedEvent(userID, email, firstName, lastName))
+}
+
+func (p *Publisher) PublishUserUpdated(userID, email, firstName, lastName string) error {
+	return p.publish(SubjectUserUpdated, NewUserUpdatedEvent(userID, email, firstName, lastName))
+}
+
+func (p *Publisher) PublishUserDeleted(userID string) error {
+	return p.publish(SubjectUserDeleted, NewUserDeletedEvent(userID))
 }
 
 func (p *Publisher) publish(subject string, event *Event) error {
@@ -0,0 +1,266 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+const (
+	// outboxLeaseSeconds is how long a claimed row is hidden from other relays while it is published
+	outboxLeaseSeconds    = 30
+	outboxPublishTimeout  = 5 * time.Second
+	outboxCleanupInterval = time.Hour
+)
+
+// Relay metrics, served with the other expvars on /api/v1/admin/debug/vars
+var (
+	relayMetrics    = expvar.NewMap("outbox_relay")
+	relayPublished  = new(expvar.Int)
+	relayFailed     = new(expvar.Int)
+	relayPending    = new(expvar.Int)
+	relayLagSeconds = new(expvar.Float)
+)
+
+func init() {
+	relayMetrics.Set("published_total", relayPublished)
+	relayMetrics.Set("failed_total", relayFailed)
+	relayMetrics.Set("pending", relayPending)
+	relayMetrics.Set("lag_seconds", relayLagSeconds)
+}
+
+// Relay publishes the events written to kainos_event_outbox to JetStream.
+// Rows are only marked sent once JetStream acknowledged them, so every event is
+// delivered at least once; consumers dedupe on the event id, which is also sent
+// as the Nats-Msg-Id header.
+type Relay struct {
+	store db.Store
+	js    nats.JetStreamContext
+	cfg   *configs.AppConfig
+
+	streamReady bool
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewRelay(store db.Store, nc *nats.Conn, cfg *configs.AppConfig) (*Relay, error) {
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	return &Relay{
+		store: store,
+		js:    js,
+		cfg:   cfg,
+	}, nil
+}
+
+// Start runs the relay loop in the background until Stop is called
+func (r *Relay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+
+	log.Info().
+		Str("stream", r.cfg.OutboxStream).
+		Dur("poll_interval", r.cfg.OutboxPollInterval).
+		Msg("Outbox relay started")
+}
+
+// Stop stops the relay loop and waits for the current batch to finish
+func (r *Relay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	log.Info().Msg("Outbox relay stopped")
+}
+
+func (r *Relay) run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.OutboxPollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(outboxCleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			r.relayBatch(ctx)
+			r.updateLag(ctx)
+		case <-cleanup.C:
+			r.cleanup(ctx)
+		}
+	}
+}
+
+// relayBatch publishes one batch of due outbox rows
+func (r *Relay) relayBatch(ctx context.Context) {
+	if !r.streamReady {
+		if err := r.ensureStreams(); err != nil {
+			log.Error().Err(err).Msg("Outbox streams are not available")
+			return
+		}
+		r.streamReady = true
+	}
+
+	events, err := r.store.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LeaseSeconds: outboxLeaseSeconds,
+		BatchSize:    int32(r.cfg.OutboxBatchSize),
+	})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Msg("Failed to claim outbox events")
+		}
+		return
+	}
+
+	for _, event := range events {
+		if err := r.publish(ctx, event); err != nil {
+			relayFailed.Add(1)
+			r.markFailed(ctx, event, err)
+			continue
+		}
+
+		if err := r.store.MarkOutboxEventSent(ctx, event.ID); err != nil {
+			// The lease expires and the event is published again; consumers dedupe on the id
+			log.Error().Err(err).Str("event_id", event.ID.String()).Msg("Failed to mark outbox event sent")
+			continue
+		}
+
+		relayPublished.Add(1)
+		log.Info().
+			Str("event_id", event.ID.String()).
+			Str("subject", event.Subject).
+			Int32("attempts", event.Attempts).
+			Msg("Outbox event published")
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, event db.KainosEventOutbox) error {
+	publishCtx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+	defer cancel()
+
+	msg := nats.NewMsg(event.Subject)
+	msg.Data = event.Payload
+	msg.Header.Set(nats.MsgIdHdr, event.ID.String())
+
+	_, err := r.js.PublishMsg(msg, nats.Context(publishCtx))
+	return err
+}
+
+func (r *Relay) markFailed(ctx context.Context, event db.KainosEventOutbox, publishErr error) {
+	backoff := r.backoff(event.Attempts)
+	errMsg := publishErr.Error()
+
+	log.Warn().
+		Err(publishErr).
+		Str("event_id", event.ID.String()).
+		Str("subject", event.Subject).
+		Int32("attempts", event.Attempts+1).
+		Dur("retry_in", backoff).
+		Msg("Failed to publish outbox event")
+
+	err := r.store.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+		LastError:     &errMsg,
+		NextAttemptAt: pgtype.Timestamp{Time: time.Now().UTC().Add(backoff), Valid: true},
+		ID:            event.ID,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("event_id", event.ID.String()).Msg("Failed to record outbox publish failure")
+	}
+}
+
+// backoff doubles the retry delay with every failed attempt, up to OutboxMaxBackoff
+func (r *Relay) backoff(attempts int32) time.Duration {
+	delay := r.cfg.OutboxPollInterval
+	for i := int32(0); i < attempts && delay < r.cfg.OutboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, r.cfg.OutboxMaxBackoff)
+}
+
+func (r *Relay) updateLag(ctx context.Context) {
+	lag, err := r.store.GetOutboxLag(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Msg("Failed to get outbox lag")
+		}
+		return
+	}
+
+	relayPending.Set(lag.Pending)
+	relayLagSeconds.Set(lag.OldestPendingSeconds)
+}
+
+// cleanup deletes sent outbox events and the Svix message ids of webhooks past their retention
+func (r *Relay) cleanup(ctx context.Context) {
+	now := time.Now().UTC()
+
+	deleted, err := r.store.DeleteSentOutboxEvents(ctx, pgtype.Timestamp{
+		Time:  now.Add(-r.cfg.OutboxRetention),
+		Valid: true,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete sent outbox events")
+	} else if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("Deleted sent outbox events")
+	}
+
+	deleted, err = r.store.DeleteWebhookMessagesBefore(ctx, pgtype.Timestamp{
+		Time:  now.Add(-r.cfg.WebhookMessageRetention),
+		Valid: true,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to delete webhook message ids")
+	} else if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msg("Deleted webhook message ids")
+	}
+}
+
+// ensureStreams creates the JetStream streams holding the user and workflow events if they do not exist yet
+func (r *Relay) ensureStreams() error {
+	if err := r.ensureStream(r.cfg.OutboxStream, "user.>"); err != nil {
+		return err
+	}
+	return r.ensureStream(r.cfg.WorkflowEventsStream, "workflow.>")
+}
+
+func (r *Relay) ensureStream(name, subject string) error {
+	_, err := r.js.StreamInfo(name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return err
+	}
+
+	_, err = r.js.AddStream(&nats.StreamConfig{
+		Name:       name,
+		Subjects:   []string{subject},
+		Storage:    nats.FileStorage,
+		Duplicates: 2 * time.Minute,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", name, err)
+	}
+
+	log.Info().Str("stream", name).Msg("Created outbox stream")
+	return nil
+}
@@ -0,0 +1,143 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+type fakeStore struct {
+	db.Store
+	due    []db.KainosEventOutbox
+	claims []db.ClaimOutboxEventsParams
+	sent   []uuid.UUID
+	failed []db.MarkOutboxEventFailedParams
+}
+
+func (f *fakeStore) ClaimOutboxEvents(ctx context.Context, arg db.ClaimOutboxEventsParams) ([]db.KainosEventOutbox, error) {
+	f.claims = append(f.claims, arg)
+	claimed := f.due
+	f.due = nil
+	return claimed, nil
+}
+
+func (f *fakeStore) MarkOutboxEventSent(ctx context.Context, id uuid.UUID) error {
+	f.sent = append(f.sent, id)
+	return nil
+}
+
+func (f *fakeStore) MarkOutboxEventFailed(ctx context.Context, arg db.MarkOutboxEventFailedParams) error {
+	f.failed = append(f.failed, arg)
+	return nil
+}
+
+// fakeJetStream acknowledges every message except those on failSubject
+type fakeJetStream struct {
+	nats.JetStreamContext
+	failSubject string
+	published   []*nats.Msg
+}
+
+func (f *fakeJetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	if msg.Subject == f.failSubject {
+		return nil, errors.New("no responders")
+	}
+	f.published = append(f.published, msg)
+	return &nats.PubAck{}, nil
+}
+
+func newTestRelay(store *fakeStore, js *fakeJetStream) *Relay {
+	return &Relay{
+		store: store,
+		js:    js,
+		cfg: &configs.AppConfig{
+			OutboxBatchSize:    50,
+			OutboxPollInterval: time.Second,
+			OutboxMaxBackoff:   time.Minute,
+		},
+		streamReady: true,
+	}
+}
+
+func outboxEvent(subject string, attempts int32) db.KainosEventOutbox {
+	return db.KainosEventOutbox{ID: uuid.New(), Subject: subject, Payload: []byte(`{}`), Attempts: attempts}
+}
+
+func TestRelayBatch_LeasesAndPublishes(t *testing.T) {
+	created, deleted := outboxEvent("user.created", 0), outboxEvent("user.deleted", 2)
+	store := &fakeStore{due: []db.KainosEventOutbox{created, deleted}}
+	js := &fakeJetStream{}
+
+	newTestRelay(store, js).relayBatch(context.Background())
+
+	want := db.ClaimOutboxEventsParams{LeaseSeconds: outboxLeaseSeconds, BatchSize: 50}
+	if len(store.claims) != 1 || store.claims[0] != want {
+		t.Errorf("claims = %+v, want one leased batch %+v", store.claims, want)
+	}
+	if len(js.published) != 2 {
+		t.Fatalf("published %d messages, want 2", len(js.published))
+	}
+	for i, event := range []db.KainosEventOutbox{created, deleted} {
+		msg := js.published[i]
+		// Consumers and JetStream dedupe on the event id
+		if msg.Subject != event.Subject || msg.Header.Get(nats.MsgIdHdr) != event.ID.String() {
+			t.Errorf("message %d = %s with id %q, want %s with %s", i, msg.Subject, msg.Header.Get(nats.MsgIdHdr), event.Subject, event.ID)
+		}
+	}
+	if len(store.sent) != 2 || store.sent[0] != created.ID || store.sent[1] != deleted.ID {
+		t.Errorf("sent = %v, want both events", store.sent)
+	}
+	if len(store.failed) != 0 {
+		t.Errorf("failed = %+v, want none", store.failed)
+	}
+}
+
+func TestRelayBatch_FailedPublishWaitsForBackoff(t *testing.T) {
+	event := outboxEvent("user.created", 2)
+	store := &fakeStore{due: []db.KainosEventOutbox{event}}
+	js := &fakeJetStream{failSubject: "user.created"}
+
+	before := time.Now().UTC()
+	newTestRelay(store, js).relayBatch(context.Background())
+
+	if len(store.sent) != 0 {
+		t.Errorf("sent = %v, want nothing", store.sent)
+	}
+	if len(store.failed) != 1 {
+		t.Fatalf("failed = %+v, want the event", store.failed)
+	}
+	failed := store.failed[0]
+	if failed.ID != event.ID || failed.LastError == nil || *failed.LastError != "no responders" {
+		t.Errorf("failed = %+v, want the event with the publish error", failed)
+	}
+	// Two earlier attempts: the poll interval doubled twice
+	if wait := failed.NextAttemptAt.Time.Sub(before); wait < 4*time.Second || wait > 5*time.Second {
+		t.Errorf("next attempt in %s, want about 4s", wait)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	relay := newTestRelay(&fakeStore{}, &fakeJetStream{})
+
+	cases := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{6, time.Minute},
+		{40, time.Minute},
+	}
+	for _, tc := range cases {
+		if got := relay.backoff(tc.attempts); got != tc.want {
+			t.Errorf("backoff(%d) = %s, want %s", tc.attempts, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "stock-agent.io/db/sqlc"
+)
+
+const SubjectWorkflowCompleted = "workflow.completed"
+
+// WorkflowCompletedData is the data of a workflow.completed event, sent once per finished run
+type WorkflowCompletedData struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+
+	UserWorkflowID     string `json:"user_workflow_id"`
+	WorkflowName       string `json:"workflow_name"`
+	TemporalWorkflowID string `json:"temporal_workflow_id"`
+	TemporalRunID      string `json:"temporal_run_id"`
+
+	// Status is SUCCEEDED, FAILED or CANCELLED; Output is the Mastra result, partial for a cancelled run
+	Status     string          `json:"status"`
+	Output     json.RawMessage `json:"output,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	FinishedAt time.Time       `json:"finished_at"`
+
+	// NotifyOn is the user workflow's notification setting: always, failure or never
+	NotifyOn     string `json:"notify_on"`
+	ExecutionURL string `json:"execution_url"`
+}
+
+// NewWorkflowCompletedData - Who a finished run is reported to and where it can be seen; the
+// caller fills in the outcome
+func NewWorkflowCompletedData(userWorkflowID uuid.UUID, target db.GetWorkflowNotificationTargetRow, appPublicURL, temporalWorkflowID, temporalRunID string) WorkflowCompletedData {
+	data := WorkflowCompletedData{
+		UserID:             target.ClerkID,
+		Email:              target.Email,
+		UserWorkflowID:     userWorkflowID.String(),
+		WorkflowName:       target.WorkflowName,
+		TemporalWorkflowID: temporalWorkflowID,
+		TemporalRunID:      temporalRunID,
+		NotifyOn:           target.NotifyOn,
+		ExecutionURL: fmt.Sprintf("%s/workflows/%s/executions/%s",
+			strings.TrimRight(appPublicURL, "/"), userWorkflowID, url.PathEscape(temporalRunID)),
+	}
+	if target.FirstName != nil {
+		data.FirstName = *target.FirstName
+	}
+	if target.Name != nil {
+		data.WorkflowName = *target.Name
+	}
+	return data
+}
+
+// NewWorkflowCompletedEvent builds the workflow.completed event of a finished run. Its id is derived
+// from the run, so writing the event again for the same run does not send it twice.
+func NewWorkflowCompletedEvent(data WorkflowCompletedData) (*Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow completed data: %w", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow completed data: %w", err)
+	}
+
+	return &Event{
+		ID:        uuid.NewSHA1(uuid.NameSpaceURL, []byte(SubjectWorkflowCompleted+":"+data.TemporalWorkflowID+"/"+data.TemporalRunID)).String(),
+		Type:      SubjectWorkflowCompleted,
+		Timestamp: data.FinishedAt,
+		Source:    "core-api",
+		Data:      fields,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/internal/webhooks"
+)
+
+// RecordWorkflowStart - Activity that writes the RUNNING execution row for the current run
+func (m *Manager) RecordWorkflowStart(ctx context.Context, userWorkflowID string) error {
+	id, err := uuid.Parse(userWorkflowID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid user workflow id", "InvalidArgument", err)
+	}
+
+	execution := activity.GetInfo(ctx).WorkflowExecution
+
+	// Run-now reserves the row before starting the workflow; scheduled runs have none yet
+	attached, err := m.store.AttachWorkflowExecutionRunID(ctx, db.AttachWorkflowExecutionRunIDParams{
+		TemporalRunID:      execution.RunID,
+		TemporalWorkflowID: execution.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach run id: %w", err)
+	}
+	if attached == 0 {
+		_, err = m.store.CreateWorkflowExecution(ctx, db.CreateWorkflowExecutionParams{
+			ID:                 uuid.New(),
+			UserWorkflowID:     id,
+			TemporalWorkflowID: execution.ID,
+			TemporalRunID:      execution.RunID,
+			Status:             types.ExecutionStatusRunning,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create workflow execution: %w", err)
+		}
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID).
+		Str("temporal_workflow_id", execution.ID).
+		Str("temporal_run_id", execution.RunID).
+		Msg("Workflow execution started")
+
+	return nil
+}
+
+// StoreWorkflowResult - Activity that finishes the execution row with the run's output or error and queues its webhooks
+func (m *Manager) StoreWorkflowResult(ctx context.Context, userWorkflowID string, outcome types.WorkflowRunOutcome) error {
+	execution := activity.GetInfo(ctx).WorkflowExecution
+
+	params := db.FinishWorkflowExecutionParams{
+		Status:             outcome.Status,
+		TemporalWorkflowID: execution.ID,
+		TemporalRunID:      execution.RunID,
+	}
+
+	if outcome.Result != nil {
+		output, err := json.Marshal(outcome.Result)
+		if err != nil {
+			return temporal.NewNonRetryableApplicationError("failed to marshal workflow result", "InvalidArgument", err)
+		}
+		params.Output = output
+	}
+
+	if outcome.Error != "" {
+		params.Error = &outcome.Error
+	}
+
+	// The owner's webhook endpoints get the finished row in the same transaction
+	_, err := m.store.FinishWorkflowExecutionTx(ctx, db.FinishWorkflowExecutionTxParams{
+		FinishWorkflowExecutionParams: params,
+		WebhookEvent:                  webhooks.NewExecutionEvent,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Runs of the worker that predates RecordWorkflowStart have no row to finish
+		log.Warn().
+			Str("user_workflow_id", userWorkflowID).
+			Str("temporal_run_id", execution.RunID).
+			Msg("No workflow execution to finish, run started before runs were recorded")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to finish workflow execution: %w", err)
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID).
+		Str("temporal_workflow_id", execution.ID).
+		Str("temporal_run_id", execution.RunID).
+		Str("status", outcome.Status).
+		Msg("Workflow execution finished")
+
+	return nil
+}
+
+// PublishWorkflowCompleted - Activity that writes the run's workflow.completed event to the outbox.
+// The event id is derived from the run, so a retried attempt does not queue it twice.
+func (m *Manager) PublishWorkflowCompleted(ctx context.Context, userWorkflowID string, outcome types.WorkflowRunOutcome) error {
+	id, err := uuid.Parse(userWorkflowID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid user workflow id", "InvalidArgument", err)
+	}
+
+	target, err := m.store.GetWorkflowNotificationTarget(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Warn().Str("user_workflow_id", userWorkflowID).Msg("User workflow deleted before its completion event was published")
+			return nil
+		}
+		return fmt.Errorf("failed to get notification target: %w", err)
+	}
+
+	execution := activity.GetInfo(ctx).WorkflowExecution
+	data := events.NewWorkflowCompletedData(id, target, m.cfg.AppPublicURL, execution.ID, execution.RunID)
+	data.Status, data.Error, data.FinishedAt = outcome.Status, outcome.Error, time.Now().UTC()
+	if outcome.Result != nil {
+		data.Output = outcome.Result.Result
+	}
+
+	event, err := events.NewWorkflowCompletedEvent(data)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to build workflow completed event", "InvalidArgument", err)
+	}
+	params, err := event.OutboxParams()
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to build workflow completed event", "InvalidArgument", err)
+	}
+
+	if err := m.store.CreateOutboxEvent(ctx, params); err != nil {
+		return fmt.Errorf("failed to write workflow completed event: %w", err)
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID).
+		Str("temporal_run_id", execution.RunID).
+		Str("event_id", event.ID).
+		Msg("Workflow completed event queued")
+
+	return nil
+}
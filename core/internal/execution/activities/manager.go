@@ -3,23 +3,48 @@ package activities
 import (
 	"stock-agent.io/configs"
 	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
 	"stock-agent.io/pkg/circuitBreaker"
 )
 
+// Activity type names. Workflows schedule activities by these names and the worker registers them
+// under the same ones, so renaming a Go method does not break workflows already in flight.
+const (
+	RecordWorkflowStartName      = "RecordWorkflowStart"
+	ResolveWorkflowInputName     = "ResolveWorkflowInput"
+	CallMastraAPIName            = "CallMastraAPI"
+	StoreWorkflowResultName      = "StoreWorkflowResult"
+	PublishWorkflowCompletedName = "PublishWorkflowCompleted"
+)
+
 type Manager struct {
 	circuitBreaker *circuitBreaker.Client
 	store          db.Store
 	cfg            *configs.AppConfig
+	progress       *events.ProgressBroker
 }
 
 func NewManager(
 	circuitBreaker *circuitBreaker.Client,
 	store db.Store,
 	cfg *configs.AppConfig,
+	progress *events.ProgressBroker,
 ) *Manager {
 	return &Manager{
 		circuitBreaker: circuitBreaker,
 		store:          store,
 		cfg:            cfg,
+		progress:       progress,
+	}
+}
+
+// Activities returns every activity of the manager keyed by the name it is registered under
+func (m *Manager) Activities() map[string]interface{} {
+	return map[string]interface{}{
+		RecordWorkflowStartName:      m.RecordWorkflowStart,
+		ResolveWorkflowInputName:     m.ResolveWorkflowInput,
+		CallMastraAPIName:            m.CallMastraAPI,
+		StoreWorkflowResultName:      m.StoreWorkflowResult,
+		PublishWorkflowCompletedName: m.PublishWorkflowCompleted,
 	}
 }
@@ -0,0 +1,431 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/pkg/circuitBreaker"
+)
+
+type fakeStore struct {
+	db.Store
+	userWorkflow db.GetUserWorkflowByIDRow
+	catalog      db.KainosWorkflow
+	target       db.GetWorkflowNotificationTargetRow
+	outbox       []db.CreateOutboxEventParams
+	finishErr    error
+	finished     []db.FinishWorkflowExecutionParams
+}
+
+func (f *fakeStore) FinishWorkflowExecutionTx(ctx context.Context, arg db.FinishWorkflowExecutionTxParams) (db.KainosWorkflowExecution, error) {
+	if f.finishErr != nil {
+		return db.KainosWorkflowExecution{}, f.finishErr
+	}
+	f.finished = append(f.finished, arg.FinishWorkflowExecutionParams)
+	return db.KainosWorkflowExecution{Status: arg.Status}, nil
+}
+
+func (f *fakeStore) GetUserWorkflowByID(ctx context.Context, id uuid.UUID) (db.GetUserWorkflowByIDRow, error) {
+	return f.userWorkflow, nil
+}
+
+func (f *fakeStore) GetWorkflowByID(ctx context.Context, id uuid.UUID) (db.KainosWorkflow, error) {
+	return f.catalog, nil
+}
+
+func (f *fakeStore) GetWorkflowNotificationTarget(ctx context.Context, id uuid.UUID) (db.GetWorkflowNotificationTargetRow, error) {
+	return f.target, nil
+}
+
+func (f *fakeStore) CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) error {
+	f.outbox = append(f.outbox, arg)
+	return nil
+}
+
+func (f *fakeStore) UpdateWorkflowExecutionAttempt(ctx context.Context, arg db.UpdateWorkflowExecutionAttemptParams) (db.KainosWorkflowExecution, error) {
+	return db.KainosWorkflowExecution{Attempt: arg.Attempt}, nil
+}
+
+func newTestManager(t *testing.T, handler http.HandlerFunc, metaData string) (*Manager, string) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &configs.AppConfig{
+		MastraBaseURL:        server.URL,
+		MastraAPIKey:         "test-key",
+		MastraWorkflowID:     "financialWorkflow",
+		MastraRequestTimeout: 5 * time.Second,
+		AppPublicURL:         "https://app.example.com/",
+	}
+
+	client := circuitBreaker.NewCircuitBreakerClient(circuitBreaker.DefaultCircuitBreakerConfig("mastra-test")).
+		SetBaseURL(cfg.MastraBaseURL).
+		SetTimeout(cfg.MastraRequestTimeout).
+		SetHeaders(map[string]string{"Authorization": "Bearer " + cfg.MastraAPIKey})
+
+	userWorkflowID := uuid.New()
+	store := &fakeStore{userWorkflow: db.GetUserWorkflowByIDRow{ID: userWorkflowID, MetaData: []byte(metaData)}}
+
+	return NewManager(client, store, cfg, nil), userWorkflowID.String()
+}
+
+func TestCallMastraAPI_Success(t *testing.T) {
+	var gotPath, gotAuth string
+	var gotBody types.MastraWorkflowRequest
+
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{"summary":"AAPL is up"},"steps":{"fetch-stock-data":{"status":"success"}}}`))
+	}, `{"symbol":"AAPL"}`)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.CallMastraAPI, activity.RegisterOptions{Name: CallMastraAPIName})
+
+	val, err := env.ExecuteActivity(CallMastraAPIName, userWorkflowID, uuid.New().String(), types.WorkflowParams(nil))
+	if err != nil {
+		t.Fatalf("CallMastraAPI returned error: %v", err)
+	}
+
+	var result types.MastraWorkflowResult
+	if err := val.Get(&result); err != nil {
+		t.Fatalf("decode activity result: %v", err)
+	}
+
+	if gotPath != "/api/workflows/financialWorkflow/start-async" {
+		t.Errorf("unexpected path %q", gotPath)
+	}
+	if gotAuth != "Bearer test-key" {
+		t.Errorf("unexpected Authorization header %q", gotAuth)
+	}
+	if string(gotBody.InputData) != `{"symbol":"AAPL"}` {
+		t.Errorf("meta_data not sent as inputData, got %s", gotBody.InputData)
+	}
+	if result.Status != MastraStatusSuccess {
+		t.Errorf("unexpected status %q", result.Status)
+	}
+	if string(result.Result) != `{"summary":"AAPL is up"}` {
+		t.Errorf("unexpected result %s", result.Result)
+	}
+	if _, ok := result.Steps["fetch-stock-data"]; !ok {
+		t.Errorf("expected fetch-stock-data step in result, got %v", result.Steps)
+	}
+	if result.RunID == "" {
+		t.Error("expected a run id")
+	}
+}
+
+func TestCallMastraAPI_Params(t *testing.T) {
+	var gotBody types.MastraWorkflowRequest
+
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","result":{}}`))
+	}, `{"symbol":"AAPL"}`)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.CallMastraAPI, activity.RegisterOptions{Name: CallMastraAPIName})
+
+	_, err := env.ExecuteActivity(CallMastraAPIName, userWorkflowID, uuid.New().String(), types.WorkflowParams{"symbol": "MSFT"})
+	if err != nil {
+		t.Fatalf("CallMastraAPI returned error: %v", err)
+	}
+
+	if string(gotBody.InputData) != `{"symbol":"MSFT"}` {
+		t.Errorf("params not sent as inputData, got %s", gotBody.InputData)
+	}
+}
+
+func TestResolveWorkflowInput_InvalidParams(t *testing.T) {
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {}, `{"tickers": []}`)
+	manager.store.(*fakeStore).catalog = db.KainosWorkflow{InputSchema: []byte(`{
+		"type": "object",
+		"properties": {
+			"tickers": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+			"risk_level": {"type": "string", "enum": ["low", "high"], "default": "low"}
+		}
+	}`)}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.ResolveWorkflowInput, activity.RegisterOptions{Name: ResolveWorkflowInputName})
+
+	_, err := env.ExecuteActivity(ResolveWorkflowInputName, userWorkflowID, json.RawMessage(nil))
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "InvalidWorkflowInput" {
+		t.Fatalf("expected a non-retryable InvalidWorkflowInput error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "tickers must have at least 1 items") {
+		t.Errorf("expected the field error in the message, got %v", err)
+	}
+
+	val, err := env.ExecuteActivity(ResolveWorkflowInputName, userWorkflowID, json.RawMessage(`{"tickers": ["AAPL"]}`))
+	if err != nil {
+		t.Fatalf("ResolveWorkflowInput returned error: %v", err)
+	}
+	var params types.WorkflowParams
+	if err := val.Get(&params); err != nil {
+		t.Fatalf("decode activity result: %v", err)
+	}
+	if params["risk_level"] != "low" {
+		t.Errorf("expected the default risk_level, got %v", params)
+	}
+}
+
+func TestCallMastraAPI_ClientErrorIsNonRetryable(t *testing.T) {
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"Workflow not found"}`))
+	}, `{}`)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.CallMastraAPI, activity.RegisterOptions{Name: CallMastraAPIName})
+
+	_, err := env.ExecuteActivity(CallMastraAPIName, userWorkflowID, uuid.New().String(), types.WorkflowParams(nil))
+	if err == nil {
+		t.Fatal("expected an error for a 404 response")
+	}
+
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Fatalf("expected non-retryable application error, got %v", err)
+	}
+}
+
+func TestCallMastraAPI_FailedRun(t *testing.T) {
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"failed","error":"agent timed out"}`))
+	}, ``)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.CallMastraAPI, activity.RegisterOptions{Name: CallMastraAPIName})
+
+	_, err := env.ExecuteActivity(CallMastraAPIName, userWorkflowID, uuid.New().String(), types.WorkflowParams(nil))
+	if err == nil || !strings.Contains(err.Error(), "agent timed out") {
+		t.Fatalf("expected failed run error, got %v", err)
+	}
+}
+
+func TestPublishWorkflowCompleted_QueuesEvent(t *testing.T) {
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {}, `{}`)
+	store := manager.store.(*fakeStore)
+	name := "Tech watchlist"
+	store.target = db.GetWorkflowNotificationTargetRow{
+		Name:         &name,
+		NotifyOn:     types.NotifyOnFailure,
+		WorkflowName: "Financial analysis",
+		ClerkID:      "user_123",
+		Email:        "david@example.com",
+	}
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.PublishWorkflowCompleted, activity.RegisterOptions{Name: PublishWorkflowCompletedName})
+
+	outcome := types.WorkflowRunOutcome{
+		Status: types.ExecutionStatusSucceeded,
+		Result: &types.MastraWorkflowResult{Status: MastraStatusSuccess, Result: json.RawMessage(`{"summary":"AAPL up 2%"}`)},
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := env.ExecuteActivity(PublishWorkflowCompletedName, userWorkflowID, outcome); err != nil {
+			t.Fatalf("PublishWorkflowCompleted returned error: %v", err)
+		}
+	}
+
+	if len(store.outbox) != 2 || store.outbox[0].ID != store.outbox[1].ID {
+		t.Fatalf("expected both attempts to write the same event id, got %+v", store.outbox)
+	}
+	if store.outbox[0].Subject != events.SubjectWorkflowCompleted {
+		t.Errorf("unexpected subject %q", store.outbox[0].Subject)
+	}
+
+	var event struct {
+		Data events.WorkflowCompletedData `json:"data"`
+	}
+	if err := json.Unmarshal(store.outbox[0].Payload, &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	data := event.Data
+	if data.WorkflowName != name || data.NotifyOn != types.NotifyOnFailure || data.Email != "david@example.com" {
+		t.Errorf("unexpected event data %+v", data)
+	}
+	if string(data.Output) != `{"summary":"AAPL up 2%"}` {
+		t.Errorf("unexpected output %s", data.Output)
+	}
+	wantURL := "https://app.example.com/workflows/" + userWorkflowID + "/executions/" + data.TemporalRunID
+	if data.ExecutionURL != wantURL {
+		t.Errorf("expected execution url %s, got %s", wantURL, data.ExecutionURL)
+	}
+}
+
+func TestCancelMastraRun_ReturnsPartialOutput(t *testing.T) {
+	var cancelled bool
+	manager, _ := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/workflows/financialWorkflow/runs/run-1/cancel":
+			cancelled = true
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"message":"Workflow run cancelled"}`))
+		case "/api/workflows/financialWorkflow/runs/run-1/execution-result":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"status":"canceled","steps":{"fetch-stock-data":{"status":"success"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}, `{}`)
+
+	err := manager.cancelMastraRun("financialWorkflow", "run-1")
+
+	var canceledErr *temporal.CanceledError
+	if !errors.As(err, &canceledErr) {
+		t.Fatalf("expected a CanceledError, got %v", err)
+	}
+	if !cancelled {
+		t.Error("expected the Mastra run to be cancelled")
+	}
+
+	var partial types.MastraWorkflowResult
+	if !canceledErr.HasDetails() || canceledErr.Details(&partial) != nil {
+		t.Fatal("expected the partial output as error details")
+	}
+	if _, ok := partial.Steps["fetch-stock-data"]; !ok || partial.RunID != "run-1" {
+		t.Errorf("unexpected partial output %+v", partial)
+	}
+}
+
+func TestCallMastraAPI_Stream(t *testing.T) {
+	var streamPath, resultPath string
+
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/streamVNext") {
+			streamPath = r.URL.Path + "?" + r.URL.RawQuery
+			_, _ = w.Write([]byte(
+				`{"type":"workflow-step-start","payload":{"id":"fetch-stock-data"}}` + "\x1e" +
+					`{"type":"workflow-step-result","payload":{"id":"fetch-stock-data","status":"success"}}` + "\x1e" +
+					`{"type":"workflow-finish","payload":{}}` + "\x1e"))
+			return
+		}
+		resultPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"status":"success","result":{"summary":"AAPL is up"}}`))
+	}, `{"symbol":"AAPL"}`)
+	manager.cfg.MastraStream = true
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.CallMastraAPI, activity.RegisterOptions{Name: CallMastraAPIName})
+
+	val, err := env.ExecuteActivity(CallMastraAPIName, userWorkflowID, uuid.New().String(), types.WorkflowParams(nil))
+	if err != nil {
+		t.Fatalf("CallMastraAPI returned error: %v", err)
+	}
+
+	var result types.MastraWorkflowResult
+	if err := val.Get(&result); err != nil {
+		t.Fatalf("decode activity result: %v", err)
+	}
+
+	if !strings.HasPrefix(streamPath, "/api/workflows/financialWorkflow/streamVNext?runId=") {
+		t.Errorf("unexpected stream path %q", streamPath)
+	}
+	if !strings.HasSuffix(resultPath, "/execution-result") {
+		t.Errorf("expected the result to be read from execution-result, got %q", resultPath)
+	}
+	if result.Status != MastraStatusSuccess || string(result.Result) != `{"summary":"AAPL is up"}` {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestMastraTracker_PublishesStepsAndTokens(t *testing.T) {
+	var published []types.ExecutionStreamEvent
+	tracker := newMastraTracker(func(event types.ExecutionStreamEvent) {
+		published = append(published, event)
+	})
+
+	stream := `{"type":"workflow-step-start","payload":{"id":"fetch-stock-data"}}` + "\x1e" +
+		`{"type":"workflow-step-result","payload":{"id":"fetch-stock-data","status":"success"}}` + "\x1e" +
+		`{"type":"workflow-step-start","payload":{"id":"generate-analysis"}}` + "\n" +
+		`not json` + "\n" +
+		`{"type":"workflow-step-output","payload":{"id":"generate-analysis","output":{"type":"text-delta","payload":{"text":"AAPL"}}}}` + "\x1e" +
+		`{"type":"step-output","payload":{"id":"generate-analysis","output":" is up"}}`
+
+	if err := readMastraStream(strings.NewReader(stream), tracker.handle); err != nil {
+		t.Fatalf("readMastraStream returned error: %v", err)
+	}
+
+	var tokens string
+	var steps []string
+	for _, event := range published {
+		switch event.Type {
+		case types.StreamEventToken:
+			tokens += event.Token
+		case types.StreamEventStep:
+			steps = append(steps, event.Step+":"+event.Status)
+		}
+	}
+	if tokens != "AAPL is up" {
+		t.Errorf("unexpected tokens %q", tokens)
+	}
+	if strings.Join(steps, ",") != "fetch-stock-data:running,fetch-stock-data:success,generate-analysis:running" {
+		t.Errorf("unexpected step events %v", steps)
+	}
+
+	snapshot := tracker.Snapshot()
+	if snapshot.Step != "generate-analysis" || len(snapshot.CompletedSteps) != 1 || snapshot.CompletedSteps[0] != "fetch-stock-data" {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+}
+
+func TestStoreWorkflowResult_RunOfTheFirstWorker(t *testing.T) {
+	manager, userWorkflowID := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {}, `{}`)
+	store := manager.store.(*fakeStore)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivityWithOptions(manager.StoreWorkflowResult, activity.RegisterOptions{Name: StoreWorkflowResultName})
+
+	// The first worker passed the mock text as the result
+	if _, err := env.ExecuteActivity(StoreWorkflowResultName, userWorkflowID, "Mock result from Mastra AI"); err != nil {
+		t.Fatalf("StoreWorkflowResult returned error: %v", err)
+	}
+	if len(store.finished) != 1 || store.finished[0].Status != types.ExecutionStatusSucceeded ||
+		string(store.finished[0].Output) != `{"mastra_workflow_id":"","run_id":"","status":"","result":"Mock result from Mastra AI"}` {
+		t.Errorf("finished %+v, want the text stored as a succeeded result", store.finished)
+	}
+
+	// Its runs never recorded a row
+	store.finishErr = fmt.Errorf("failed to finish workflow execution: %w", pgx.ErrNoRows)
+	if _, err := env.ExecuteActivity(StoreWorkflowResultName, userWorkflowID, "Mock result from Mastra AI"); err != nil {
+		t.Errorf("StoreWorkflowResult without a row returned error: %v", err)
+	}
+}
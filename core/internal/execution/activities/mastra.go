@@ -0,0 +1,251 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+const (
+	MastraStatusSuccess = "success"
+	MastraStatusFailed  = "failed"
+)
+
+const (
+	// Cancellation only reaches an activity through its heartbeats, so the Mastra call heartbeats
+	// well within the heartbeat timeout ExecuteMastraWorkflow sets while the request is in flight
+	mastraHeartbeatInterval = 3 * time.Second
+	// Budget for fetching partial output and cancelling the Mastra run once cancelled
+	mastraCleanupTimeout = 10 * time.Second
+)
+
+// mastraRunResponse is the raw body returned by Mastra's start-async endpoint
+type mastraRunResponse struct {
+	Status string                     `json:"status"`
+	Result json.RawMessage            `json:"result"`
+	Error  json.RawMessage            `json:"error"`
+	Steps  map[string]json.RawMessage `json:"steps"`
+}
+
+// CallMastraAPI - Activity that runs the Mastra workflow with the validated params as input.
+// Runs started before ResolveWorkflowInput existed pass nil and send meta_data unchecked.
+func (m *Manager) CallMastraAPI(ctx context.Context, userWorkflowID, workflowID string, params types.WorkflowParams) (*types.MastraWorkflowResult, error) {
+	id, err := uuid.Parse(userWorkflowID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid user workflow id", "InvalidArgument", err)
+	}
+
+	userWorkflow, err := m.store.GetUserWorkflowByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user workflow: %w", err)
+	}
+
+	inputData := json.RawMessage(userWorkflow.MetaData)
+	if params != nil {
+		if inputData, err = json.Marshal(params); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError("failed to marshal workflow params", "InvalidArgument", err)
+		}
+	}
+	if len(inputData) == 0 {
+		inputData = []byte("{}")
+	}
+
+	// Catalog entries name their own Mastra workflow; older ones run the configured default
+	mastraWorkflowID := m.cfg.MastraWorkflowID
+	if userWorkflow.MastraWorkflowID != nil && *userWorkflow.MastraWorkflowID != "" {
+		mastraWorkflowID = *userWorkflow.MastraWorkflowID
+	}
+
+	runID := uuid.New().String()
+	tracker := newMastraTracker(nil)
+	if activity.IsActivity(ctx) {
+		info := activity.GetInfo(ctx)
+		runID = fmt.Sprintf("%s-%d", info.WorkflowExecution.RunID, info.Attempt)
+		m.recordAttempt(ctx, info)
+
+		temporalRunID := info.WorkflowExecution.RunID
+		tracker = newMastraTracker(func(event types.ExecutionStreamEvent) {
+			m.progress.Publish(temporalRunID, event)
+		})
+
+		stopHeartbeat := startHeartbeat(ctx, tracker)
+		defer stopHeartbeat()
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID).
+		Str("workflow_id", workflowID).
+		Str("mastra_workflow_id", mastraWorkflowID).
+		Str("run_id", runID).
+		Bool("stream", m.cfg.MastraStream).
+		Msg("Calling Mastra AI API")
+
+	request := types.MastraWorkflowRequest{InputData: inputData}
+	var runResponse mastraRunResponse
+	if m.cfg.MastraStream {
+		runResponse, err = m.streamMastraRun(ctx, mastraWorkflowID, runID, request, tracker)
+	} else {
+		runResponse, err = m.startMastraRun(ctx, mastraWorkflowID, runID, request)
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, m.cancelMastraRun(mastraWorkflowID, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if runResponse.Status != MastraStatusSuccess {
+		return nil, fmt.Errorf("mastra workflow %s finished with status %q: %s", mastraWorkflowID, runResponse.Status, string(runResponse.Error))
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID).
+		Str("run_id", runID).
+		Str("status", runResponse.Status).
+		Msg("Mastra AI workflow completed")
+
+	return &types.MastraWorkflowResult{
+		MastraWorkflowID: mastraWorkflowID,
+		RunID:            runID,
+		Status:           runResponse.Status,
+		Result:           runResponse.Result,
+		Steps:            runResponse.Steps,
+	}, nil
+}
+
+// startMastraRun runs the Mastra workflow with start-async, which answers once the run is over
+func (m *Manager) startMastraRun(ctx context.Context, mastraWorkflowID, runID string, request types.MastraWorkflowRequest) (mastraRunResponse, error) {
+	url := fmt.Sprintf("/api/workflows/%s/start-async?runId=%s", mastraWorkflowID, runID)
+
+	var runResponse mastraRunResponse
+	resp, err := m.circuitBreaker.PostWithResult(ctx, url, request, &runResponse)
+	if err != nil {
+		return runResponse, fmt.Errorf("mastra request failed: %w", err)
+	}
+
+	// 4xx means the request itself is wrong (unknown workflow, invalid input), so retrying will not help
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return runResponse, mastraClientError(resp.StatusCode(), resp.String())
+	}
+	return runResponse, nil
+}
+
+// streamMastraRun runs the Mastra workflow through its stream endpoint, handing every step
+// transition and token to the tracker, then reads the result of the finished run
+func (m *Manager) streamMastraRun(ctx context.Context, mastraWorkflowID, runID string, request types.MastraWorkflowRequest, tracker *mastraTracker) (mastraRunResponse, error) {
+	url := fmt.Sprintf("/api/workflows/%s/streamVNext?runId=%s", mastraWorkflowID, runID)
+
+	resp, err := m.circuitBreaker.PostStream(ctx, url, request)
+	if err != nil {
+		return mastraRunResponse{}, fmt.Errorf("mastra request failed: %w", err)
+	}
+	body := resp.RawBody()
+	defer body.Close()
+
+	if resp.StatusCode() >= http.StatusBadRequest {
+		text, _ := io.ReadAll(io.LimitReader(body, 4096))
+		return mastraRunResponse{}, mastraClientError(resp.StatusCode(), string(text))
+	}
+
+	if err := readMastraStream(body, tracker.handle); err != nil {
+		return mastraRunResponse{}, fmt.Errorf("mastra stream failed: %w", err)
+	}
+
+	// The stream reports steps as they happen; the run's result is read once it is over
+	var runResponse mastraRunResponse
+	resultURL := fmt.Sprintf("/api/workflows/%s/runs/%s/execution-result", mastraWorkflowID, runID)
+	resultResp, err := m.circuitBreaker.GetWithResult(ctx, resultURL, &runResponse)
+	if err != nil {
+		return runResponse, fmt.Errorf("failed to get mastra result: %w", err)
+	}
+	if resultResp.StatusCode() >= http.StatusBadRequest {
+		return runResponse, fmt.Errorf("failed to get mastra result: mastra returned %d: %s", resultResp.StatusCode(), resultResp.String())
+	}
+	return runResponse, nil
+}
+
+func mastraClientError(statusCode int, body string) error {
+	return temporal.NewNonRetryableApplicationError(
+		fmt.Sprintf("mastra returned %d: %s", statusCode, body),
+		"MastraClientError",
+		nil,
+	)
+}
+
+// startHeartbeat heartbeats the activity with the tracker's progress until the returned stop function is called.
+// Once Temporal reports the activity cancelled the heartbeat cancels ctx, which aborts the Mastra request.
+func startHeartbeat(ctx context.Context, tracker *mastraTracker) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(mastraHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx, tracker.Snapshot())
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// cancelMastraRun asks Mastra to stop a cancelled run and returns a CanceledError carrying
+// whatever output the run produced so far
+func (m *Manager) cancelMastraRun(mastraWorkflowID, runID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mastraCleanupTimeout)
+	defer cancel()
+
+	runURL := fmt.Sprintf("/api/workflows/%s/runs/%s", mastraWorkflowID, runID)
+
+	if _, err := m.circuitBreaker.Post(ctx, runURL+"/cancel", nil); err != nil {
+		log.Warn().Err(err).Str("run_id", runID).Msg("Failed to cancel Mastra run")
+	}
+
+	var runResponse mastraRunResponse
+	resp, err := m.circuitBreaker.GetWithResult(ctx, runURL+"/execution-result", &runResponse)
+	if err != nil || resp.StatusCode() >= http.StatusBadRequest || (len(runResponse.Result) == 0 && len(runResponse.Steps) == 0) {
+		log.Info().Str("run_id", runID).Msg("Mastra run cancelled without partial output")
+		return temporal.NewCanceledError()
+	}
+
+	log.Info().Str("run_id", runID).Int("steps", len(runResponse.Steps)).Msg("Mastra run cancelled with partial output")
+	return temporal.NewCanceledError(types.MastraWorkflowResult{
+		MastraWorkflowID: mastraWorkflowID,
+		RunID:            runID,
+		Status:           runResponse.Status,
+		Result:           runResponse.Result,
+		Steps:            runResponse.Steps,
+	})
+}
+
+// recordAttempt stores the current activity attempt on the execution row. A failure here
+// must not fail the Mastra call, so it is only logged.
+func (m *Manager) recordAttempt(ctx context.Context, info activity.Info) {
+	_, err := m.store.UpdateWorkflowExecutionAttempt(ctx, db.UpdateWorkflowExecutionAttemptParams{
+		Attempt:            info.Attempt,
+		TemporalWorkflowID: info.WorkflowExecution.ID,
+		TemporalRunID:      info.WorkflowExecution.RunID,
+	})
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("temporal_workflow_id", info.WorkflowExecution.ID).
+			Int32("attempt", info.Attempt).
+			Msg("Failed to record workflow execution attempt")
+	}
+}
@@ -0,0 +1,159 @@
+package activities
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"stock-agent.io/internal/types"
+)
+
+const (
+	// Step results can carry a whole report, so records may be large
+	mastraMaxRecordSize = 4 << 20
+	// Mastra ends every JSON record of a workflow stream with an ASCII record separator
+	mastraRecordSeparator = '\x1e'
+)
+
+// mastraChunk is one record of Mastra's workflow stream. Newer servers prefix the types with
+// "workflow-"; both spellings are accepted.
+type mastraChunk struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type mastraStepPayload struct {
+	ID       string          `json:"id"`
+	StepName string          `json:"stepName"`
+	Status   string          `json:"status"`
+	Output   json.RawMessage `json:"output"`
+}
+
+func (p mastraStepPayload) step() string {
+	if p.ID != "" {
+		return p.ID
+	}
+	return p.StepName
+}
+
+// readMastraStream calls handle for every record of the stream until it ends
+func readMastraStream(body io.Reader, handle func(mastraChunk)) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), mastraMaxRecordSize)
+	scanner.Split(splitMastraRecords)
+
+	for scanner.Scan() {
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+		var chunk mastraChunk
+		if err := json.Unmarshal(record, &chunk); err != nil {
+			// One unreadable record must not fail the run; the result is read separately
+			continue
+		}
+		handle(chunk)
+	}
+	return scanner.Err()
+}
+
+// splitMastraRecords splits on the record separator, and on newlines for servers sending NDJSON
+func splitMastraRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\x1e\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// mastraTracker follows the steps of a streamed run for the heartbeat details and publishes
+// step transitions and agent tokens as they arrive
+type mastraTracker struct {
+	mu       sync.Mutex
+	progress types.MastraProgress
+	publish  func(types.ExecutionStreamEvent)
+}
+
+func newMastraTracker(publish func(types.ExecutionStreamEvent)) *mastraTracker {
+	return &mastraTracker{
+		progress: types.MastraProgress{CompletedSteps: []string{}},
+		publish:  publish,
+	}
+}
+
+// Snapshot returns the progress so far, for the heartbeat details
+func (t *mastraTracker) Snapshot() types.MastraProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := t.progress
+	snapshot.CompletedSteps = append([]string{}, t.progress.CompletedSteps...)
+	return snapshot
+}
+
+func (t *mastraTracker) handle(chunk mastraChunk) {
+	var payload mastraStepPayload
+	_ = json.Unmarshal(chunk.Payload, &payload)
+
+	event := types.ExecutionStreamEvent{Step: payload.step(), Time: time.Now().UTC()}
+
+	switch chunk.Type {
+	case "workflow-step-start", "step-start":
+		event.Type, event.Status = types.StreamEventStep, "running"
+		t.mu.Lock()
+		t.progress.Step = event.Step
+		t.mu.Unlock()
+	case "workflow-step-result", "step-result":
+		event.Type, event.Status = types.StreamEventStep, payload.Status
+		t.mu.Lock()
+		t.progress.CompletedSteps = append(t.progress.CompletedSteps, event.Step)
+		if t.progress.Step == event.Step {
+			t.progress.Step = ""
+		}
+		t.mu.Unlock()
+	case "workflow-step-output", "step-output":
+		event.Type, event.Token = types.StreamEventToken, tokenText(payload.Output)
+		if event.Token == "" {
+			return
+		}
+	default:
+		return
+	}
+
+	if t.publish != nil {
+		t.publish(event)
+	}
+}
+
+// tokenText extracts the text a step wrote to its stream: a plain string, or a text-delta
+// chunk of an agent stream piped through
+func tokenText(output json.RawMessage) string {
+	var text string
+	if json.Unmarshal(output, &text) == nil {
+		return text
+	}
+
+	var delta struct {
+		Text      string `json:"text"`
+		TextDelta string `json:"textDelta"`
+		Payload   struct {
+			Text string `json:"text"`
+		} `json:"payload"`
+	}
+	if json.Unmarshal(output, &delta) != nil {
+		return ""
+	}
+	switch {
+	case delta.Text != "":
+		return delta.Text
+	case delta.TextDelta != "":
+		return delta.TextDelta
+	default:
+		return delta.Payload.Text
+	}
+}
@@ -0,0 +1,82 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/temporal"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/utils"
+)
+
+// ResolveWorkflowInput - Activity that validates the user workflow's meta_data, or inputOverride when
+// a run-now request supplied one, against the catalog input schema. Invalid parameters fail the run
+// without retries; the field errors are attached as details.
+func (m *Manager) ResolveWorkflowInput(ctx context.Context, userWorkflowID string, inputOverride json.RawMessage) (types.WorkflowParams, error) {
+	id, err := uuid.Parse(userWorkflowID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid user workflow id", "InvalidArgument", err)
+	}
+
+	userWorkflow, err := m.store.GetUserWorkflowByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user workflow: %w", err)
+	}
+
+	catalog, err := m.store.GetWorkflowByID(ctx, userWorkflow.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load catalog workflow: %w", err)
+	}
+
+	input := userWorkflow.MetaData
+	// A nil override reaches the activity as the JSON literal null
+	if len(inputOverride) > 0 && string(inputOverride) != "null" {
+		input = inputOverride
+	}
+
+	params, fieldErrors, err := ValidateParams(catalog.InputSchema, input)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("catalog input schema is invalid", "InvalidInputSchema", err)
+	}
+	if len(fieldErrors) > 0 {
+		return nil, temporal.NewNonRetryableApplicationError(
+			"invalid workflow parameters: "+describeFieldErrors(fieldErrors),
+			"InvalidWorkflowInput",
+			nil,
+			fieldErrors,
+		)
+	}
+
+	return params, nil
+}
+
+// ValidateParams checks input against a catalog input schema and fills in its defaults.
+// Without a schema any JSON object is accepted. err is only set for a broken schema.
+func ValidateParams(inputSchema, input []byte) (types.WorkflowParams, []utils.FieldError, error) {
+	if len(bytes.TrimSpace(inputSchema)) == 0 || bytes.Equal(bytes.TrimSpace(inputSchema), []byte("null")) {
+		inputSchema = []byte(`{"type": "object"}`)
+	}
+
+	schema, err := utils.ParseInputSchema(inputSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params, fieldErrors := schema.Validate(input)
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors, nil
+	}
+	return params, nil, nil
+}
+
+func describeFieldErrors(fieldErrors []utils.FieldError) string {
+	parts := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		parts = append(parts, fieldError.Field+" "+fieldError.Message)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kinds of Registration
+const (
+	KindWorkflow = "workflow"
+	KindActivity = "activity"
+)
+
+// Registration is a workflow or activity type served by a worker
+type Registration struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	TaskQueue string `json:"task_queue"`
+	// BuildID is empty for unversioned workers
+	BuildID string `json:"build_id,omitempty"`
+	// Function is the Go function behind the type, to tell which code serves a name
+	Function string `json:"function"`
+}
+
+// Registry records every type the workers register, for the admin endpoint
+type Registry struct {
+	mu            sync.RWMutex
+	registrations []Registration
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(kind, name, taskQueue, buildID string, fn interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.registrations = append(r.registrations, Registration{
+		Name:      name,
+		Kind:      kind,
+		TaskQueue: taskQueue,
+		BuildID:   buildID,
+		Function:  functionName(fn),
+	})
+}
+
+// Workflows lists the registered workflow types sorted by name
+func (r *Registry) Workflows() []Registration {
+	return r.list(KindWorkflow)
+}
+
+// Activities lists the registered activity types sorted by name
+func (r *Registry) Activities() []Registration {
+	return r.list(KindActivity)
+}
+
+func (r *Registry) list(kind string) []Registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Registration, 0, len(r.registrations))
+	for _, registration := range r.registrations {
+		if registration.Kind == kind {
+			list = append(list, registration)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Name != list[j].Name {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].TaskQueue < list[j].TaskQueue
+	})
+	return list
+}
+
+// functionName returns the Go name of fn, without the -fm suffix of method values
+func functionName(fn interface{}) string {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return ""
+	}
+	name := runtime.FuncForPC(value.Pointer()).Name()
+	return strings.TrimSuffix(name, "-fm")
+}
+
+// TaskQueues lists the task queues served by the registered workflows, sorted
+func (r *Registry) TaskQueues() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	queues := make([]string, 0)
+	for _, registration := range r.registrations {
+		if registration.Kind == KindWorkflow && !seen[registration.TaskQueue] {
+			seen[registration.TaskQueue] = true
+			queues = append(queues, registration.TaskQueue)
+		}
+	}
+
+	sort.Strings(queues)
+	return queues
+}
@@ -0,0 +1,64 @@
+package worker
+
+import "testing"
+
+type testActivities struct{}
+
+func (a *testActivities) Fetch() error {
+	return nil
+}
+
+func TestRegistry_List(t *testing.T) {
+	registry := NewRegistry()
+	registry.add(KindWorkflow, "Zeta", "heavy-queue", "", testWorkflow)
+	registry.add(KindActivity, "Fetch", "default-queue", "b1", testActivity)
+	registry.add(KindWorkflow, "Alpha", "heavy-queue", "", testWorkflow)
+	registry.add(KindWorkflow, "Alpha", "default-queue", "", testWorkflow)
+
+	tests := []struct {
+		name string
+		got  []Registration
+		want []string
+	}{
+		{name: "workflows by name then queue", got: registry.Workflows(), want: []string{"Alpha/default-queue", "Alpha/heavy-queue", "Zeta/heavy-queue"}},
+		{name: "activities", got: registry.Activities(), want: []string{"Fetch/default-queue"}},
+		{name: "empty registry", got: NewRegistry().Workflows(), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.got) != len(tt.want) {
+				t.Fatalf("listed %+v, want %v", tt.got, tt.want)
+			}
+			for i, registration := range tt.got {
+				if got := registration.Name + "/" + registration.TaskQueue; got != tt.want[i] {
+					t.Errorf("registration %d = %s, want %s", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+
+	if queues := registry.TaskQueues(); len(queues) != 2 || queues[0] != "default-queue" || queues[1] != "heavy-queue" {
+		t.Errorf("TaskQueues = %v, want [default-queue heavy-queue]", queues)
+	}
+}
+
+func TestFunctionName(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   interface{}
+		want string
+	}{
+		{name: "function", fn: testWorkflow, want: "stock-agent.io/internal/execution/worker.testWorkflow"},
+		{name: "method value drops -fm", fn: (&testActivities{}).Fetch, want: "stock-agent.io/internal/execution/worker.(*testActivities).Fetch"},
+		{name: "not a function", fn: "Fetch", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := functionName(tt.fn); got != tt.want {
+				t.Errorf("functionName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
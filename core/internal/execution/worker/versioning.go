@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/configs"
+)
+
+// Values of APP_TEMPORAL_VERSIONING_BEHAVIOR
+const (
+	VersioningPinned      = "pinned"
+	VersioningAutoUpgrade = "auto_upgrade"
+)
+
+// DeploymentOptions maps the versioning config onto the worker. Without a build id the worker is
+// unversioned and polls every run of its task queue, as before versioning was introduced.
+func DeploymentOptions(cfg *configs.AppConfig) (worker.DeploymentOptions, error) {
+	if cfg.TemporalBuildID == "" {
+		return worker.DeploymentOptions{}, nil
+	}
+	if cfg.TemporalDeploymentName == "" {
+		return worker.DeploymentOptions{}, fmt.Errorf("APP_TEMPORAL_DEPLOYMENT_NAME is required with APP_TEMPORAL_BUILD_ID")
+	}
+
+	var behavior workflow.VersioningBehavior
+	switch cfg.TemporalVersioningBehavior {
+	case VersioningPinned:
+		behavior = workflow.VersioningBehaviorPinned
+	case VersioningAutoUpgrade:
+		behavior = workflow.VersioningBehaviorAutoUpgrade
+	default:
+		return worker.DeploymentOptions{}, fmt.Errorf("APP_TEMPORAL_VERSIONING_BEHAVIOR must be %s or %s, got %q",
+			VersioningPinned, VersioningAutoUpgrade, cfg.TemporalVersioningBehavior)
+	}
+
+	return worker.DeploymentOptions{
+		UseVersioning: true,
+		Version: worker.WorkerDeploymentVersion{
+			DeploymentName: cfg.TemporalDeploymentName,
+			BuildId:        cfg.TemporalBuildID,
+		},
+		DefaultVersioningBehavior: behavior,
+	}, nil
+}
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/configs"
+)
+
+func TestDeploymentOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		cfg      configs.AppConfig
+		versions bool
+		behavior workflow.VersioningBehavior
+		err      string
+	}{
+		{name: "unversioned without a build id", cfg: configs.AppConfig{TemporalDeploymentName: "core-api", TemporalVersioningBehavior: VersioningPinned}},
+		{
+			name:     "pinned",
+			cfg:      configs.AppConfig{TemporalBuildID: "b1", TemporalDeploymentName: "core-api", TemporalVersioningBehavior: VersioningPinned},
+			versions: true,
+			behavior: workflow.VersioningBehaviorPinned,
+		},
+		{
+			name:     "auto upgrade",
+			cfg:      configs.AppConfig{TemporalBuildID: "b1", TemporalDeploymentName: "core-api", TemporalVersioningBehavior: VersioningAutoUpgrade},
+			versions: true,
+			behavior: workflow.VersioningBehaviorAutoUpgrade,
+		},
+		{
+			name: "build id without deployment name",
+			cfg:  configs.AppConfig{TemporalBuildID: "b1", TemporalVersioningBehavior: VersioningPinned},
+			err:  "APP_TEMPORAL_DEPLOYMENT_NAME is required",
+		},
+		{
+			name: "unknown behavior",
+			cfg:  configs.AppConfig{TemporalBuildID: "b1", TemporalDeploymentName: "core-api", TemporalVersioningBehavior: "latest"},
+			err:  `got "latest"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, err := DeploymentOptions(&tc.cfg)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("DeploymentOptions error = %v, want one containing %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeploymentOptions returned error: %v", err)
+			}
+
+			if options.UseVersioning != tc.versions {
+				t.Errorf("UseVersioning = %v, want %v", options.UseVersioning, tc.versions)
+			}
+			if !tc.versions {
+				return
+			}
+			if options.Version.BuildId != tc.cfg.TemporalBuildID || options.Version.DeploymentName != tc.cfg.TemporalDeploymentName {
+				t.Errorf("Version = %+v, want %s/%s", options.Version, tc.cfg.TemporalDeploymentName, tc.cfg.TemporalBuildID)
+			}
+			if options.DefaultVersioningBehavior != tc.behavior {
+				t.Errorf("DefaultVersioningBehavior = %v, want %v", options.DefaultVersioningBehavior, tc.behavior)
+			}
+		})
+	}
+}
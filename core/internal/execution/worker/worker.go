@@ -4,8 +4,10 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 	"stock-agent.io/configs"
 )
 
@@ -13,45 +15,71 @@ type Worker struct {
 	worker         worker.Worker
 	temporalClient client.Client
 	taskQueue      string
+	buildID        string
+	registry       *Registry
 }
 
-func New(cfg *configs.AppConfig, temporalClient client.Client, taskQueue string) *Worker {
+// New creates the worker polling options.TaskQueue with the concurrency and rate limits of options,
+// versioned by the build id of the config when one is set
+func New(cfg *configs.AppConfig, temporalClient client.Client, options configs.TemporalWorkerConfig, registry *Registry) (*Worker, error) {
+	deploymentOptions, err := DeploymentOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	workerOptions := worker.Options{
-		MaxConcurrentActivityExecutionSize:      10,
-		MaxConcurrentWorkflowTaskExecutionSize:  10,
-		MaxConcurrentLocalActivityExecutionSize: 10,
+		MaxConcurrentActivityExecutionSize:      options.MaxConcurrentActivities,
+		MaxConcurrentWorkflowTaskExecutionSize:  options.MaxConcurrentWorkflowTasks,
+		MaxConcurrentLocalActivityExecutionSize: options.MaxConcurrentLocalActivities,
+		MaxConcurrentActivityTaskPollers:        options.ActivityPollers,
+		MaxConcurrentWorkflowTaskPollers:        options.WorkflowPollers,
+		WorkerActivitiesPerSecond:               options.ActivitiesPerSecond,
+		TaskQueueActivitiesPerSecond:            options.TaskQueueActivitiesPerSecond,
 		EnableLoggingInReplay:                   true,
-		Identity:                                cfg.AppName,
+		Identity:                                cfg.AppName + "@" + options.TaskQueue,
 		DeadlockDetectionTimeout:                time.Minute,
 		MaxHeartbeatThrottleInterval:            time.Second * 60,
 		DefaultHeartbeatThrottleInterval:        time.Second * 30,
+		DeploymentOptions:                       deploymentOptions,
 	}
 
-	w := worker.New(temporalClient, taskQueue, workerOptions)
+	w := worker.New(temporalClient, options.TaskQueue, workerOptions)
 
 	return &Worker{
 		worker:         w,
 		temporalClient: temporalClient,
-		taskQueue:      taskQueue,
-	}
+		taskQueue:      options.TaskQueue,
+		buildID:        deploymentOptions.Version.BuildId,
+		registry:       registry,
+	}, nil
+}
+
+// TaskQueue is the task queue the worker polls
+func (w *Worker) TaskQueue() string {
+	return w.taskQueue
 }
 
-func (w *Worker) RegisterWorkflow(workflow interface{}) {
-	w.worker.RegisterWorkflow(workflow)
-	log.Info().Msgf("Registered workflows: %T", workflow)
+// RegisterWorkflow registers fn under an explicit workflow type name
+func (w *Worker) RegisterWorkflow(name string, fn interface{}) {
+	w.worker.RegisterWorkflowWithOptions(fn, workflow.RegisterOptions{Name: name})
+	w.registry.add(KindWorkflow, name, w.taskQueue, w.buildID, fn)
+	log.Info().Str("workflow", name).Str("task_queue", w.taskQueue).Msg("Registered workflow")
 }
 
-func (w *Worker) RegisterActivity(activity interface{}) {
-	w.worker.RegisterActivity(activity)
-	log.Info().Msgf("Registered activity: %T", activity)
+// RegisterActivity registers fn under an explicit activity type name
+func (w *Worker) RegisterActivity(name string, fn interface{}) {
+	w.worker.RegisterActivityWithOptions(fn, activity.RegisterOptions{Name: name})
+	w.registry.add(KindActivity, name, w.taskQueue, w.buildID, fn)
+	log.Info().Str("activity", name).Str("task_queue", w.taskQueue).Msg("Registered activity")
 }
 
+// Start begins polling without blocking; Stop must be called to shut the worker down
 func (w *Worker) Start() error {
-	log.Info().Str("task_queue", w.taskQueue).Msg("Starting Temporal worker")
-	return w.worker.Run(worker.InterruptCh())
+	log.Info().Str("task_queue", w.taskQueue).Str("build_id", w.buildID).Msg("Starting Temporal worker")
+	return w.worker.Start()
 }
 
 func (w *Worker) Stop() {
-	log.Info().Msg("Stopping Temporal worker")
+	log.Info().Str("task_queue", w.taskQueue).Msg("Stopping Temporal worker")
 	w.worker.Stop()
 }
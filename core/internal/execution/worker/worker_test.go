@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/configs"
+)
+
+func testWorkflow(ctx workflow.Context) error {
+	return nil
+}
+
+func testActivity(ctx context.Context) error {
+	return nil
+}
+
+// newTestWorker creates a worker on a client that never connects; nothing here starts polling
+func newTestWorker(t *testing.T, cfg *configs.AppConfig, registry *Registry) (*Worker, error) {
+	t.Helper()
+
+	temporalClient, err := client.NewLazyClient(client.Options{HostPort: "localhost:7233"})
+	if err != nil {
+		t.Fatalf("NewLazyClient returned error: %v", err)
+	}
+	t.Cleanup(temporalClient.Close)
+
+	return New(cfg, temporalClient, configs.TemporalWorkerConfig{TaskQueue: "heavy-queue"}, registry)
+}
+
+func TestNew_RegistersWithBuildID(t *testing.T) {
+	registry := NewRegistry()
+	cfg := &configs.AppConfig{AppName: "core-api", TemporalBuildID: "b42", TemporalDeploymentName: "core-api", TemporalVersioningBehavior: VersioningPinned}
+
+	w, err := newTestWorker(t, cfg, registry)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	w.RegisterWorkflow("TestWorkflow", testWorkflow)
+	w.RegisterActivity("TestActivity", testActivity)
+
+	for _, registration := range append(registry.Workflows(), registry.Activities()...) {
+		if registration.BuildID != "b42" || registration.TaskQueue != "heavy-queue" {
+			t.Errorf("%s registered on %s with build %q, want heavy-queue with b42", registration.Name, registration.TaskQueue, registration.BuildID)
+		}
+	}
+}
+
+func TestNew_UnversionedHasNoBuildID(t *testing.T) {
+	registry := NewRegistry()
+
+	w, err := newTestWorker(t, &configs.AppConfig{AppName: "core-api"}, registry)
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	w.RegisterWorkflow("TestWorkflow", testWorkflow)
+
+	if workflows := registry.Workflows(); len(workflows) != 1 || workflows[0].BuildID != "" {
+		t.Errorf("Workflows = %+v, want one without a build id", workflows)
+	}
+}
+
+func TestNew_RejectsInvalidVersioning(t *testing.T) {
+	cfg := &configs.AppConfig{TemporalBuildID: "b42", TemporalDeploymentName: "core-api", TemporalVersioningBehavior: "latest"}
+
+	if _, err := newTestWorker(t, cfg, NewRegistry()); err == nil {
+		t.Error("New accepted an unknown versioning behavior")
+	}
+}
@@ -1,8 +1,28 @@
 package workflow
 
+// ExecuteMastraWorkflowName is the registered workflow type; schedules and running workflows refer to
+// it by name, so they do not depend on the Go method name
+const ExecuteMastraWorkflowName = "ExecuteMastraWorkflow"
+
+// ProgressQueryName is the query ExecuteMastraWorkflow answers with its types.WorkflowProgress
+const ProgressQueryName = "progress"
+
+// Manager holds the workflow definitions; everything with side effects lives on activities.Manager
 type Manager struct {
+	// notificationTaskQueue runs PublishWorkflowCompleted on its own workers; empty keeps it on the workflow's queue.
+	// Replay does not compare task queues, so changing it is safe for running workflows.
+	notificationTaskQueue string
+}
+
+func NewManager(notificationTaskQueue string) *Manager {
+	return &Manager{
+		notificationTaskQueue: notificationTaskQueue,
+	}
 }
 
-func NewManager() *Manager {
-	return &Manager{}
+// Workflows returns every workflow of the manager keyed by the name it is registered under
+func (m *Manager) Workflows() map[string]interface{} {
+	return map[string]interface{}{
+		ExecuteMastraWorkflowName: m.ExecuteMastraWorkflow,
+	}
 }
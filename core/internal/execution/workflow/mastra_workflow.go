@@ -1,81 +1,125 @@
 package workflow
 
 import (
-	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/types"
 )
 
-// ExecuteMastraWorkflow - Temporal workflow that calls Mastra AI
-func (m *Manager) ExecuteMastraWorkflow(ctx workflow.Context, userWorkflowID, workflowID string) error {
+// Cancellation only reaches an activity through its heartbeats; CallMastraAPI heartbeats well within this
+const mastraHeartbeatTimeout = 10 * time.Second
+
+// Change IDs for workflow.GetVersion around activities added after the first runs
+const (
+	recordStartChange      = "record-workflow-start"
+	paramsValidationChange = "validate-params"
+	completedEventChange   = "publish-completed-event"
+)
+
+// ExecuteMastraWorkflow - Temporal workflow that calls Mastra AI.
+// options was added after the first schedules were created; their runs decode it as the zero value.
+func (m *Manager) ExecuteMastraWorkflow(ctx workflow.Context, userWorkflowID, workflowID string, options types.WorkflowRunOptions) error {
 	// Set activity options
 	ao := workflow.ActivityOptions{
 		StartToCloseTimeout: 5 * time.Minute,
+		HeartbeatTimeout:    mastraHeartbeatTimeout,
+		// Let a cancelled CallMastraAPI report its partial output before the workflow moves on
+		WaitForCancellation: true,
 		RetryPolicy: &temporal.RetryPolicy{
 			MaximumAttempts: 3,
 		},
 	}
 	ctx = workflow.WithActivityOptions(ctx, ao)
 
-	// Call Mastra API activity
-	var result string
-	err := workflow.ExecuteActivity(ctx, m.CallMastraAPI, userWorkflowID, workflowID).Get(ctx, &result)
+	// Queries add nothing to the history, so runs started before the handler existed replay unchanged
+	progress := types.WorkflowProgress{Phase: types.ExecutionPhaseStarting}
+	err := workflow.SetQueryHandler(ctx, ProgressQueryName, func() (types.WorkflowProgress, error) {
+		return progress, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to call Mastra API: %w", err)
+		return fmt.Errorf("failed to register progress query: %w", err)
+	}
+
+	// Record the run before doing any work so it shows up in the execution history. Runs started
+	// before runs were recorded went straight to CallMastraAPI.
+	var canceledErr *temporal.CanceledError
+	if workflow.GetVersion(ctx, recordStartChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+		err = workflow.ExecuteActivity(ctx, activities.RecordWorkflowStartName, userWorkflowID).Get(ctx, nil)
+		if err != nil && !errors.As(err, &canceledErr) {
+			return fmt.Errorf("failed to record workflow start: %w", err)
+		}
+	}
+
+	// Runs started before parameters were validated send the override or meta_data as is
+	progress.Phase = types.ExecutionPhaseResolvingInput
+	var params types.WorkflowParams
+	var callErr error
+	if workflow.GetVersion(ctx, paramsValidationChange, workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		if len(options.InputOverride) > 0 {
+			callErr = json.Unmarshal(options.InputOverride, &params)
+		}
+	} else {
+		callErr = workflow.ExecuteActivity(ctx, activities.ResolveWorkflowInputName, userWorkflowID, options.InputOverride).Get(ctx, &params)
+	}
+
+	// Call Mastra API activity; once cancelled it returns right away with the same CanceledError
+	var result types.MastraWorkflowResult
+	progress.Phase = types.ExecutionPhaseRunning
+	if callErr == nil {
+		callErr = workflow.ExecuteActivity(ctx, activities.CallMastraAPIName, userWorkflowID, workflowID, params).Get(ctx, &result)
+	}
+
+	outcome := types.WorkflowRunOutcome{Status: types.ExecutionStatusSucceeded, Result: &result}
+	switch {
+	case errors.As(callErr, &canceledErr):
+		outcome = types.WorkflowRunOutcome{Status: types.ExecutionStatusCancelled, Error: "cancelled"}
+		var partial types.MastraWorkflowResult
+		if canceledErr.HasDetails() && canceledErr.Details(&partial) == nil {
+			outcome.Result = &partial
+		}
+	case callErr != nil:
+		outcome = types.WorkflowRunOutcome{Status: types.ExecutionStatusFailed, Error: callErr.Error()}
+	}
+
+	// A cancelled workflow context cannot schedule activities, so the result is stored from a disconnected one
+	storeCtx := ctx
+	if outcome.Status == types.ExecutionStatusCancelled {
+		storeCtx, _ = workflow.NewDisconnectedContext(ctx)
 	}
 
 	// Store result activity
-	err = workflow.ExecuteActivity(ctx, m.StoreWorkflowResult, userWorkflowID, result).Get(ctx, nil)
+	progress.Phase = types.ExecutionPhaseStoringResult
+	err = workflow.ExecuteActivity(storeCtx, activities.StoreWorkflowResultName, userWorkflowID, outcome).Get(storeCtx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to store result: %w", err)
 	}
 
-	return nil
-}
+	if workflow.GetVersion(storeCtx, completedEventChange, workflow.DefaultVersion, 1) != workflow.DefaultVersion {
+		publishCtx := storeCtx
+		if m.notificationTaskQueue != "" {
+			publishCtx = workflow.WithTaskQueue(storeCtx, m.notificationTaskQueue)
+		}
+		err = workflow.ExecuteActivity(publishCtx, activities.PublishWorkflowCompletedName, userWorkflowID, outcome).Get(publishCtx, nil)
+		if err != nil {
+			// The result is stored; a lost notification must not fail the run
+			workflow.GetLogger(ctx).Error("Failed to publish workflow completed event", "error", err)
+		}
+	}
 
-// CallMastraAPI - Activity that makes HTTP call to Mastra (Mock for now)
-func (m *Manager) CallMastraAPI(ctx context.Context, userWorkflowID, workflowID string) (string, error) {
-	// Mock Mastra API call
-	mastraURL := "https://api.mastra.ai/workflows/execute" // Example URL
-
-	log.Info().
-		Str("user_workflow_id", userWorkflowID).
-		Str("workflow_id", workflowID).
-		Str("mastra_url", mastraURL).
-		Msg("Calling Mastra AI API (MOCK)")
-
-	// TODO: Replace with actual HTTP call
-	// client := &http.Client{Timeout: 30 * time.Second}
-	// payload := map[string]interface{}{
-	//     "workflow_id": workflowID,
-	//     "user_workflow_id": userWorkflowID,
-	//     "timestamp": time.Now(),
-	// }
-	// resp, err := client.Post(mastraURL, "application/json", bytes.NewBuffer(jsonPayload))
-
-	// For now, return mock response
-	mockResult := fmt.Sprintf("Mock result from Mastra AI for workflow %s at %s", workflowID, time.Now().Format(time.RFC3339))
-
-	return mockResult, nil
-}
+	progress = types.WorkflowProgress{Phase: types.ExecutionPhaseFinished, Status: outcome.Status}
 
-// StoreWorkflowResult - Activity to store workflow execution result
-func (m *Manager) StoreWorkflowResult(ctx context.Context, userWorkflowID, result string) error {
-	log.Info().
-		Str("user_workflow_id", userWorkflowID).
-		Str("result", result).
-		Msg("Storing workflow result (MOCK)")
-
-	// TODO: Store in database
-	// m.store.CreateWorkflowExecution(ctx, db.CreateWorkflowExecutionParams{
-	//     UserWorkflowID: userWorkflowID,
-	//     Result: result,
-	//     ExecutedAt: time.Now(),
-	// })
+	if canceledErr != nil {
+		return canceledErr
+	}
+	if callErr != nil {
+		return fmt.Errorf("failed to call Mastra API: %w", callErr)
+	}
 
 	return nil
 }
@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/types"
+)
+
+// newTestEnvironment registers the workflows and activities under the names the worker uses,
+// so the activities can be mocked by name
+func newTestEnvironment(t *testing.T) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	for name, fn := range NewManager("").Workflows() {
+		env.RegisterWorkflowWithOptions(fn, workflow.RegisterOptions{Name: name})
+	}
+	for name, fn := range activities.NewManager(nil, nil, nil, nil).Activities() {
+		env.RegisterActivityWithOptions(fn, activity.RegisterOptions{Name: name})
+	}
+	return env
+}
+
+func TestExecuteMastraWorkflow_Cancelled(t *testing.T) {
+	userWorkflowID := uuid.New().String()
+	env := newTestEnvironment(t)
+
+	env.OnActivity(activities.RecordWorkflowStartName, mock.Anything, userWorkflowID).Return(nil)
+	env.OnActivity(activities.ResolveWorkflowInputName, mock.Anything, userWorkflowID, mock.Anything).Return(types.WorkflowParams{}, nil)
+	env.OnActivity(activities.CallMastraAPIName, mock.Anything, userWorkflowID, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, _, _ string, _ types.WorkflowParams) (*types.MastraWorkflowResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	var stored types.WorkflowRunOutcome
+	env.OnActivity(activities.StoreWorkflowResultName, mock.Anything, userWorkflowID, mock.Anything).Return(
+		func(_ context.Context, _ string, outcome types.WorkflowRunOutcome) error {
+			stored = outcome
+			return nil
+		})
+
+	env.OnActivity(activities.PublishWorkflowCompletedName, mock.Anything, userWorkflowID, mock.Anything).Return(nil)
+
+	env.RegisterDelayedCallback(env.CancelWorkflow, time.Second)
+	env.ExecuteWorkflow(ExecuteMastraWorkflowName, userWorkflowID, uuid.New().String(), types.WorkflowRunOptions{})
+
+	var canceledErr *temporal.CanceledError
+	if err := env.GetWorkflowError(); !errors.As(err, &canceledErr) {
+		t.Fatalf("expected the workflow to end cancelled, got %v", err)
+	}
+	if stored.Status != types.ExecutionStatusCancelled {
+		t.Fatalf("expected CANCELLED to be stored, got %q", stored.Status)
+	}
+
+	val, err := env.QueryWorkflow(ProgressQueryName)
+	if err != nil {
+		t.Fatalf("query progress: %v", err)
+	}
+	var progress types.WorkflowProgress
+	if err := val.Get(&progress); err != nil {
+		t.Fatalf("decode progress: %v", err)
+	}
+	if progress.Phase != types.ExecutionPhaseFinished || progress.Status != types.ExecutionStatusCancelled {
+		t.Fatalf("expected finished CANCELLED progress, got %+v", progress)
+	}
+}
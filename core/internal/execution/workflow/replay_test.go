@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/types"
+)
+
+// Histories of ExecuteMastraWorkflow, one per shape of run that may still be in flight:
+// baseline is the first worker (CallMastraAPI then StoreWorkflowResult, no version markers),
+// succeeded and failed the current one. They were built in the export format from each build's
+// command sequence because no cluster was at hand; replace them with recordings from
+// scripts/export-replay-histories.sh, keeping one per shape.
+const historiesGlob = "testdata/histories/*.json"
+
+func newTestReplayer(fn interface{}) worker.WorkflowReplayer {
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflowWithOptions(fn, workflow.RegisterOptions{Name: ExecuteMastraWorkflowName})
+	return replayer
+}
+
+// TestReplayHistories fails when a change to ExecuteMastraWorkflow is not deterministic for
+// runs started by an earlier build; guard such changes with workflow.GetVersion
+func TestReplayHistories(t *testing.T) {
+	files, err := filepath.Glob(historiesGlob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no histories match %s", historiesGlob)
+	}
+
+	replayer := newTestReplayer(NewManager("").ExecuteMastraWorkflow)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, file); err != nil {
+				t.Errorf("replay of %s failed: %v", file, err)
+			}
+		})
+	}
+}
+
+// TestReplayHistories_DetectsNonDeterminism makes sure the harness catches unguarded changes
+func TestReplayHistories_DetectsNonDeterminism(t *testing.T) {
+	// Records the start without a version marker, as ExecuteMastraWorkflow first did
+	ungatedStart := func(ctx workflow.Context, userWorkflowID, workflowID string, options types.WorkflowRunOptions) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+		if err := workflow.ExecuteActivity(ctx, activities.RecordWorkflowStartName, userWorkflowID).Get(ctx, nil); err != nil {
+			return err
+		}
+		return workflow.ExecuteActivity(ctx, activities.CallMastraAPIName, userWorkflowID, workflowID, types.WorkflowParams(nil)).Get(ctx, nil)
+	}
+	// Resolves the input before recording the start, as an unguarded refactor might
+	reordered := func(ctx workflow.Context, userWorkflowID, workflowID string, options types.WorkflowRunOptions) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: time.Minute})
+		var params types.WorkflowParams
+		if err := workflow.ExecuteActivity(ctx, activities.ResolveWorkflowInputName, userWorkflowID, options.InputOverride).Get(ctx, &params); err != nil {
+			return err
+		}
+		return workflow.ExecuteActivity(ctx, activities.RecordWorkflowStartName, userWorkflowID).Get(ctx, nil)
+	}
+
+	tests := []struct {
+		name    string
+		fn      interface{}
+		history string
+	}{
+		{name: "ungated start on a run of the first worker", fn: ungatedStart, history: "execute_mastra_workflow_baseline_succeeded.json"},
+		{name: "reordered activities", fn: reordered, history: "execute_mastra_workflow_succeeded.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestReplayer(tt.fn).ReplayWorkflowHistoryFromJSONFile(nil, filepath.Join("testdata/histories", tt.history))
+			// TMPRL1100 is the SDK's code for a history the workflow code no longer reproduces
+			if err == nil || !strings.Contains(err.Error(), "TMPRL1100") {
+				t.Fatalf("expected a nondeterminism error, got %v", err)
+			}
+		})
+	}
+}
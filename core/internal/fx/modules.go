@@ -1,15 +1,20 @@
 package fx
 
 import (
+	"context"
+
 	"github.com/clerk/clerk-sdk-go/v2"
 	"go.uber.org/fx"
 	"stock-agent.io/configs"
 	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/handlers/admin"
 	"stock-agent.io/internal/handlers/users"
+	webhookHandlers "stock-agent.io/internal/handlers/webhooks"
 	"stock-agent.io/internal/handlers/workflow"
 	"stock-agent.io/internal/middleware"
 	natsClient "stock-agent.io/internal/nats"
 	"stock-agent.io/internal/server"
+	"stock-agent.io/internal/webhooks"
 )
 
 var ConfigModule = fx.Module("config",
@@ -21,14 +26,42 @@ var NATSModule = fx.Module("nats",
 )
 
 var EventsModule = fx.Module("events",
-	fx.Provide(events.NewPublisher),
+	fx.Provide(events.NewPublisher, events.NewRelay, events.NewProgressBroker),
+	fx.Invoke(func(lc fx.Lifecycle, relay *events.Relay) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				relay.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				relay.Stop()
+				return nil
+			},
+		})
+	}),
+)
+
+var WebhooksModule = fx.Module("webhooks",
+	fx.Provide(webhooks.NewDispatcher),
+	fx.Invoke(func(lc fx.Lifecycle, dispatcher *webhooks.Dispatcher) {
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				dispatcher.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				dispatcher.Stop()
+				return nil
+			},
+		})
+	}),
 )
 
 var HandlersModule = fx.Module("handlers",
-	fx.Provide(
-		users.NewHandler,
-	),
+	fx.Provide(users.NewHandler),
 	fx.Provide(workflow.NewHandler),
+	fx.Provide(admin.NewHandler),
+	fx.Provide(webhookHandlers.NewHandler),
 )
 
 var MiddlewareModule = fx.Module("middleware",
@@ -43,7 +76,9 @@ var MiddlewareModule = fx.Module("middleware",
 				},
 			}
 		},
-		middleware.NewManager,
+		func(clerkSecret string, clerkConfig *clerk.ClientConfig, cfg *configs.AppConfig) *middleware.Manager {
+			return middleware.NewManager(clerkSecret, clerkConfig, cfg.AdminUserIDs)
+		},
 	),
 )
 
@@ -0,0 +1,16 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTemporalRegistry - Workflow and activity types the workers of this process serve, with the
+// task queue and Go function behind each name
+func (h *Handler) GetTemporalRegistry(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"workflows":  h.registry.Workflows(),
+		"activities": h.registry.Activities(),
+	})
+}
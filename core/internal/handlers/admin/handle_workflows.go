@@ -0,0 +1,399 @@
+package admin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/utils"
+)
+
+// Temporal rejects longer task queue names
+const maxTaskQueueLength = 1000
+
+// ListCatalogWorkflows - Catalog entries, soft-deleted ones too with ?include_deleted=true
+func (h *Handler) ListCatalogWorkflows(c *gin.Context) {
+	includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted"))
+
+	workflows, err := h.store.ListWorkflows(c.Request.Context(), includeDeleted)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list catalog workflows")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list catalog workflows"})
+		return
+	}
+
+	items := make([]types.CatalogWorkflowResponse, 0, len(workflows))
+	for _, workflow := range workflows {
+		items = append(items, toCatalogResponse(workflow))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflows": items,
+		"count":     len(items),
+	})
+}
+
+// GetCatalogWorkflow - Single catalog entry, including a soft-deleted one
+func (h *Handler) GetCatalogWorkflow(c *gin.Context) {
+	workflow, ok := h.catalogWorkflow(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workflow": toCatalogResponse(workflow)})
+}
+
+// CreateCatalogWorkflow - Add a catalog entry. Users get a row for it, with its default schedule
+// turned OFF, the next time their default workflows are provisioned.
+func (h *Handler) CreateCatalogWorkflow(c *gin.Context) {
+	var req types.CatalogWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "workflow_name is required"})
+		return
+	}
+
+	fields, err := mergeCatalogRequest(db.UpdateWorkflowParams{}, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workflow, err := h.store.CreateWorkflow(c.Request.Context(), db.CreateWorkflowParams{
+		ID:                    uuid.New(),
+		WorkflowName:          fields.WorkflowName,
+		WorkflowDescription:   fields.WorkflowDescription,
+		Price:                 fields.Price,
+		MastraWorkflowID:      fields.MastraWorkflowID,
+		DefaultCronTime:       fields.DefaultCronTime,
+		DefaultSchedulePreset: fields.DefaultSchedulePreset,
+		DefaultTimeZone:       fields.DefaultTimeZone,
+		InputSchema:           fields.InputSchema,
+		TaskQueue:             fields.TaskQueue,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create catalog workflow"})
+		return
+	}
+
+	log.Info().
+		Str("workflow_id", workflow.ID.String()).
+		Str("admin", c.GetString(types.UserIDContextKey)).
+		Msg("Catalog workflow created")
+	h.warnUnservedTaskQueue(workflow)
+
+	c.JSON(http.StatusCreated, gin.H{"workflow": toCatalogResponse(workflow)})
+}
+
+// UpdateCatalogWorkflow - Change the fields present in the body. Existing user schedules keep
+// their spec; a new default schedule only applies to rows provisioned afterwards. A new task
+// queue is applied to every user schedule of the workflow right away.
+func (h *Handler) UpdateCatalogWorkflow(c *gin.Context) {
+	var req types.CatalogWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, ok := h.catalogWorkflow(c)
+	if !ok {
+		return
+	}
+	if current.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "Catalog workflow is deleted"})
+		return
+	}
+
+	params, err := mergeCatalogRequest(db.UpdateWorkflowParams{
+		ID:                    current.ID,
+		WorkflowName:          current.WorkflowName,
+		WorkflowDescription:   current.WorkflowDescription,
+		Price:                 current.Price,
+		MastraWorkflowID:      current.MastraWorkflowID,
+		DefaultCronTime:       current.DefaultCronTime,
+		DefaultSchedulePreset: current.DefaultSchedulePreset,
+		DefaultTimeZone:       current.DefaultTimeZone,
+		InputSchema:           current.InputSchema,
+		TaskQueue:             current.TaskQueue,
+	}, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workflow, err := h.store.UpdateWorkflow(c.Request.Context(), params)
+	if err != nil {
+		// Deleted between the lookup and the update
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Catalog workflow is deleted"})
+			return
+		}
+		log.Error().Err(err).Str("workflow_id", current.ID.String()).Msg("Failed to update catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update catalog workflow"})
+		return
+	}
+
+	if valueOf(current.TaskQueue) == valueOf(workflow.TaskQueue) {
+		c.JSON(http.StatusOK, gin.H{"workflow": toCatalogResponse(workflow)})
+		return
+	}
+	h.warnUnservedTaskQueue(workflow)
+
+	userWorkflows, err := h.store.ListUserWorkflowsByWorkflowID(c.Request.Context(), workflow.ID)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", workflow.ID.String()).Msg("Failed to list user workflows of catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Catalog workflow updated but user schedules could not be moved to the new task queue"})
+		return
+	}
+
+	// Runs already started finish on the old queue; schedules that fail here are moved by the reconciler
+	note := fmt.Sprintf("Task queue of catalog workflow %s changed by %s", workflow.ID, c.GetString(types.UserIDContextKey))
+	updated, failed := 0, 0
+	for _, userWorkflow := range userWorkflows {
+		err := h.schedules.Apply(c.Request.Context(), userWorkflow, note)
+		if errors.Is(err, temporal.ErrNoSchedule) {
+			continue
+		}
+		if err != nil {
+			failed++
+			log.Error().Err(err).Str("user_workflow_id", userWorkflow.ID.String()).Msg("Failed to move schedule to the new task queue")
+			continue
+		}
+		updated++
+	}
+
+	log.Info().
+		Str("workflow_id", workflow.ID.String()).
+		Str("task_queue", valueOf(workflow.TaskQueue)).
+		Int("updated", updated).
+		Int("failed", failed).
+		Msg("Catalog workflow moved to a new task queue")
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow":          toCatalogResponse(workflow),
+		"schedules_updated": updated,
+		"schedules_failed":  failed,
+	})
+}
+
+// DeleteCatalogWorkflow - Soft-delete a catalog entry and pause every user's schedule for it.
+// User rows keep their status; schedules that fail to pause here are paused by the reconciler.
+func (h *Handler) DeleteCatalogWorkflow(c *gin.Context) {
+	current, ok := h.catalogWorkflow(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	workflow, err := h.store.SoftDeleteWorkflow(ctx, current.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Catalog workflow is already deleted"})
+			return
+		}
+		log.Error().Err(err).Str("workflow_id", current.ID.String()).Msg("Failed to delete catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete catalog workflow"})
+		return
+	}
+
+	userWorkflows, err := h.store.ListUserWorkflowsByWorkflowID(ctx, workflow.ID)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", workflow.ID.String()).Msg("Failed to list user workflows of deleted catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Catalog workflow deleted but user schedules could not be paused"})
+		return
+	}
+
+	note := fmt.Sprintf("Catalog workflow %s deleted by %s", workflow.ID, c.GetString(types.UserIDContextKey))
+	paused, failed := 0, 0
+	for _, userWorkflow := range userWorkflows {
+		if err := h.schedules.Pause(ctx, userWorkflow.ID, note); err != nil {
+			failed++
+			log.Error().Err(err).Str("user_workflow_id", userWorkflow.ID.String()).Msg("Failed to pause schedule of deleted catalog workflow")
+			continue
+		}
+		paused++
+	}
+
+	log.Info().
+		Str("workflow_id", workflow.ID.String()).
+		Int("paused", paused).
+		Int("failed", failed).
+		Msg("Catalog workflow deleted")
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflow":         toCatalogResponse(workflow),
+		"schedules_paused": paused,
+		"schedules_failed": failed,
+	})
+}
+
+// warnUnservedTaskQueue logs when no worker of this process polls the workflow's queue; a worker of
+// another deployment may, so it is not rejected
+func (h *Handler) warnUnservedTaskQueue(workflow db.KainosWorkflow) {
+	if workflow.TaskQueue == nil {
+		return
+	}
+	for _, taskQueue := range h.registry.TaskQueues() {
+		if taskQueue == *workflow.TaskQueue {
+			return
+		}
+	}
+	log.Warn().
+		Str("workflow_id", workflow.ID.String()).
+		Str("task_queue", *workflow.TaskQueue).
+		Msg("No Temporal worker in this process polls the catalog workflow's task queue")
+}
+
+func (h *Handler) catalogWorkflow(c *gin.Context) (db.KainosWorkflow, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		return db.KainosWorkflow{}, false
+	}
+
+	workflow, err := h.store.GetWorkflowByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Catalog workflow not found"})
+			return db.KainosWorkflow{}, false
+		}
+		log.Error().Err(err).Str("workflow_id", id.String()).Msg("Failed to get catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get catalog workflow"})
+		return db.KainosWorkflow{}, false
+	}
+
+	return workflow, true
+}
+
+// mergeCatalogRequest applies the fields present in req on top of current and validates the result
+func mergeCatalogRequest(current db.UpdateWorkflowParams, req types.CatalogWorkflowRequest) (db.UpdateWorkflowParams, error) {
+	merged := current
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return merged, errors.New("workflow_name must not be empty")
+		}
+		merged.WorkflowName = name
+	}
+	if req.Description != nil {
+		merged.WorkflowDescription = *req.Description
+	}
+	if req.Price != nil {
+		if *req.Price < 0 {
+			return merged, errors.New("price must not be negative")
+		}
+		merged.Price = req.Price
+	}
+	if req.MastraWorkflowID != nil {
+		merged.MastraWorkflowID = optionalString(*req.MastraWorkflowID)
+	}
+
+	if req.DefaultPreset != nil || req.DefaultCronTime != nil || req.DefaultTimeZone != nil {
+		preset, cron, timeZone := valueOf(current.DefaultSchedulePreset), valueOf(current.DefaultCronTime), valueOf(current.DefaultTimeZone)
+		// A preset replaces a cron and the other way round
+		if req.DefaultPreset != nil {
+			preset, cron = *req.DefaultPreset, ""
+		}
+		if req.DefaultCronTime != nil {
+			cron = *req.DefaultCronTime
+			if req.DefaultPreset == nil {
+				preset = ""
+			}
+		}
+		if req.DefaultTimeZone != nil {
+			timeZone = *req.DefaultTimeZone
+		}
+
+		merged.DefaultSchedulePreset, merged.DefaultCronTime, merged.DefaultTimeZone = nil, nil, nil
+		if strings.TrimSpace(preset) != "" || strings.TrimSpace(cron) != "" {
+			schedule, err := utils.ResolveSchedule(preset, cron, timeZone)
+			if err != nil {
+				return merged, fmt.Errorf("invalid default schedule: %w", err)
+			}
+			merged.DefaultSchedulePreset = optionalString(schedule.Preset)
+			merged.DefaultCronTime = optionalString(schedule.Cron)
+			merged.DefaultTimeZone = &schedule.TimeZone
+		}
+	}
+
+	if req.TaskQueue != nil {
+		taskQueue := strings.TrimSpace(*req.TaskQueue)
+		if len(taskQueue) > maxTaskQueueLength || strings.ContainsAny(taskQueue, " \t\n") {
+			return merged, fmt.Errorf("task_queue must be at most %d characters without whitespace", maxTaskQueueLength)
+		}
+		merged.TaskQueue = optionalString(taskQueue)
+	}
+
+	if req.InputSchema != nil {
+		schema := bytes.TrimSpace(req.InputSchema)
+		if bytes.Equal(schema, []byte("null")) {
+			merged.InputSchema = nil
+		} else {
+			if err := utils.CheckInputSchema(schema); err != nil {
+				return merged, err
+			}
+			merged.InputSchema = schema
+		}
+	}
+
+	return merged, nil
+}
+
+func toCatalogResponse(workflow db.KainosWorkflow) types.CatalogWorkflowResponse {
+	response := types.CatalogWorkflowResponse{
+		ID:               workflow.ID.String(),
+		Name:             workflow.WorkflowName,
+		Description:      workflow.WorkflowDescription,
+		Price:            workflow.Price,
+		MastraWorkflowID: workflow.MastraWorkflowID,
+		DefaultPreset:    workflow.DefaultSchedulePreset,
+		DefaultCronTime:  workflow.DefaultCronTime,
+		DefaultTimeZone:  workflow.DefaultTimeZone,
+		InputSchema:      workflow.InputSchema,
+		TaskQueue:        workflow.TaskQueue,
+		CreatedAt:        workflow.CreatedAt.Time,
+	}
+
+	if workflow.UpdatedAt.Valid {
+		updatedAt := workflow.UpdatedAt.Time
+		response.UpdatedAt = &updatedAt
+	}
+	if workflow.DeletedAt.Valid {
+		deletedAt := workflow.DeletedAt.Time
+		response.DeletedAt = &deletedAt
+	}
+
+	return response
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
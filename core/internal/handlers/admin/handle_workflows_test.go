@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func TestMergeCatalogRequest(t *testing.T) {
+	current := db.UpdateWorkflowParams{
+		WorkflowName:    "Daily report",
+		DefaultCronTime: stringPtr("0 9 * * *"),
+		DefaultTimeZone: stringPtr("Europe/Paris"),
+		TaskQueue:       stringPtr("heavy-queue"),
+		InputSchema:     []byte(`{"type": "object"}`),
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		check   func(t *testing.T, merged db.UpdateWorkflowParams)
+		wantErr string
+	}{
+		{
+			name: "absent fields are kept",
+			body: `{"workflow_description": "Every morning"}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if merged.WorkflowName != "Daily report" || valueOf(merged.DefaultCronTime) != "0 9 * * *" ||
+					valueOf(merged.TaskQueue) != "heavy-queue" || string(merged.InputSchema) != `{"type": "object"}` {
+					t.Errorf("merged %+v, want the current fields kept", merged)
+				}
+				if merged.WorkflowDescription != "Every morning" {
+					t.Errorf("description = %q, want Every morning", merged.WorkflowDescription)
+				}
+			},
+		},
+		{
+			name: "empty strings clear the schedule",
+			body: `{"default_preset": "", "default_cron_time": "", "default_time_zone": ""}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if merged.DefaultSchedulePreset != nil || merged.DefaultCronTime != nil || merged.DefaultTimeZone != nil {
+					t.Errorf("schedule = %v/%v/%v, want all cleared", merged.DefaultSchedulePreset, merged.DefaultCronTime, merged.DefaultTimeZone)
+				}
+			},
+		},
+		{
+			name: "empty cron alone clears the schedule",
+			body: `{"default_cron_time": ""}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if merged.DefaultCronTime != nil || merged.DefaultTimeZone != nil {
+					t.Errorf("schedule = %v/%v, want cleared", merged.DefaultCronTime, merged.DefaultTimeZone)
+				}
+			},
+		},
+		{
+			name: "preset replaces cron and keeps the zone",
+			body: `{"default_preset": "daily-9am"}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if valueOf(merged.DefaultSchedulePreset) != "daily-9am" || merged.DefaultCronTime != nil || valueOf(merged.DefaultTimeZone) != "Europe/Paris" {
+					t.Errorf("schedule = %v/%v/%v, want daily-9am in Europe/Paris", merged.DefaultSchedulePreset, merged.DefaultCronTime, merged.DefaultTimeZone)
+				}
+			},
+		},
+		{name: "invalid cron", body: `{"default_cron_time": "every day"}`, wantErr: "invalid default schedule"},
+		{
+			name: "null input_schema removes the schema",
+			body: `{"input_schema": null}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if merged.InputSchema != nil {
+					t.Errorf("input_schema = %s, want removed", merged.InputSchema)
+				}
+			},
+		},
+		{
+			name: "input_schema replaced",
+			body: `{"input_schema": {"type": "object", "properties": {"symbol": {"type": "string"}}}}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if !strings.Contains(string(merged.InputSchema), `"symbol"`) {
+					t.Errorf("input_schema = %s, want the new schema", merged.InputSchema)
+				}
+			},
+		},
+		{name: "input_schema with an unsupported keyword", body: `{"input_schema": {"type": "object", "oneOf": []}}`, wantErr: "unsupported keyword"},
+		{name: "input_schema not an object", body: `{"input_schema": {"type": "string"}}`, wantErr: `"type": "object"`},
+		{
+			name: "empty task_queue moves to the default queue",
+			body: `{"task_queue": ""}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if merged.TaskQueue != nil {
+					t.Errorf("task_queue = %q, want nil", valueOf(merged.TaskQueue))
+				}
+			},
+		},
+		{
+			name: "task_queue is trimmed",
+			body: `{"task_queue": " light-queue "}`,
+			check: func(t *testing.T, merged db.UpdateWorkflowParams) {
+				if valueOf(merged.TaskQueue) != "light-queue" {
+					t.Errorf("task_queue = %q, want light-queue", valueOf(merged.TaskQueue))
+				}
+			},
+		},
+		{name: "task_queue with whitespace", body: `{"task_queue": "light queue"}`, wantErr: "task_queue"},
+		{name: "task_queue too long", body: `{"task_queue": "` + strings.Repeat("q", maxTaskQueueLength+1) + `"}`, wantErr: "task_queue"},
+		{name: "empty name", body: `{"workflow_name": " "}`, wantErr: "workflow_name"},
+		{name: "negative price", body: `{"price": -1}`, wantErr: "price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req types.CatalogWorkflowRequest
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal returned error: %v", err)
+			}
+
+			merged, err := mergeCatalogRequest(current, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("mergeCatalogRequest = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mergeCatalogRequest returned error: %v", err)
+			}
+			tt.check(t, merged)
+		})
+	}
+}
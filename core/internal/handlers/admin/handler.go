@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/execution/worker"
+	"stock-agent.io/internal/middleware"
+	"stock-agent.io/internal/temporal"
+)
+
+type Handler struct {
+	reconciler       *temporal.Reconciler
+	schedules        *temporal.ScheduleManager
+	registry         *worker.Registry
+	middleWareManger *middleware.Manager
+	store            db.Store
+}
+
+func NewHandler(
+	reconciler *temporal.Reconciler,
+	schedules *temporal.ScheduleManager,
+	registry *worker.Registry,
+	middleWareManager *middleware.Manager,
+	store db.Store,
+) *Handler {
+	return &Handler{
+		reconciler:       reconciler,
+		schedules:        schedules,
+		registry:         registry,
+		middleWareManger: middleWareManager,
+		store:            store,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1/admin", h.middleWareManger.AuthMiddleware(), h.middleWareManger.AdminMiddleware())
+	{
+		// Drift between kainos_user_workflow and Temporal schedules
+		api.GET("/schedules/drift", h.GetScheduleDrift)
+		api.POST("/schedules/reconcile", h.ReconcileSchedules)
+
+		// Workflow and activity types served by the Temporal workers
+		api.GET("/temporal/registry", h.GetTemporalRegistry)
+
+		// Workflow catalog (kainos_workflow)
+		api.GET("/workflows", h.ListCatalogWorkflows)
+		api.POST("/workflows", h.CreateCatalogWorkflow)
+		api.GET("/workflows/:id", h.GetCatalogWorkflow)
+		api.PATCH("/workflows/:id", h.UpdateCatalogWorkflow)
+		api.DELETE("/workflows/:id", h.DeleteCatalogWorkflow)
+
+		// Runtime, outbox relay, webhook dispatcher and progress broker metrics (expvar)
+		api.GET("/debug/vars", gin.WrapH(expvar.Handler()))
+	}
+}
+
+// GetScheduleDrift - Report of the last reconcile run, or a fresh dry run with ?refresh=true
+func (h *Handler) GetScheduleDrift(c *gin.Context) {
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		report, err := h.reconciler.Reconcile(c.Request.Context(), true)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to check schedule drift")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schedule drift"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"report": report})
+		return
+	}
+
+	report := h.reconciler.LastReport()
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconciler has not finished a run yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// ReconcileSchedules - Run the reconciler now; ?dry_run=true only reports what it would change
+func (h *Handler) ReconcileSchedules(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	report, err := h.reconciler.Reconcile(c.Request.Context(), dryRun)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to reconcile schedules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reconcile schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
@@ -1,73 +1,119 @@
 package users
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"sync"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
+	"stock-agent.io/configs"
 	db "stock-agent.io/db/sqlc"
 	"stock-agent.io/internal/events"
 	"stock-agent.io/internal/types"
+	"stock-agent.io/pkg/svix"
 )
 
 type Handler struct {
-	store          db.Store
-	webhookKey     string
-	eventPublisher *events.Publisher
+	store           db.Store
+	cfg             *configs.AppConfig
+	webhookKey      string
+	webhookVerifier *svix.Verifier
+	eventPublisher  *events.Publisher
 }
 
-func NewHandler(store db.Store, webhookKey string, eventPublisher *events.Publisher) *Handler {
-	return &Handler{
-		store:          store,
-		webhookKey:     webhookKey,
-		eventPublisher: eventPublisher,
+// NewHandler verifies webhooks with cfg.SvixSecret. Several secrets may be given
+// comma separated while a secret is being rotated.
+func NewHandler(store db.Store, cfg *configs.AppConfig, eventPublisher *events.Publisher) (*Handler, error) {
+	webhookKey := cfg.SvixSecret
+	verifier, err := svix.NewVerifier(strings.Split(webhookKey, ",")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook verifier: %w", err)
 	}
+
+	return &Handler{
+		store:           store,
+		cfg:             cfg,
+		webhookKey:      webhookKey,
+		webhookVerifier: verifier,
+		eventPublisher:  eventPublisher,
+	}, nil
 }
 
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
 	router.POST("/webhooks/clerk", h.handleClerkWebhook)
 
-	// Test endpoints
-	api := router.Group("/api/v1")
-	{
-		api.POST("/test-user-event", h.handleTestUserEvent)
+	// Publishes real user events, so welcome emails go out: only served in development
+	if h.cfg.IsDevelopment() {
+		api := router.Group("/api/v1")
+		{
+			api.POST("/test-user-event", h.handleTestUserEvent)
+		}
 	}
 }
 
 func (h *Handler) handleClerkWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.webhookVerifier.Verify(payload, c.Request.Header); err != nil {
+		log.Warn().Err(err).Str("svix_id", c.GetHeader(svix.HeaderID)).Msg("Rejected Clerk webhook with invalid signature")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+		return
+	}
+
 	var webhookEvent types.ClerkWebhookEvent
-	if err := c.ShouldBindJSON(&webhookEvent); err != nil {
+	if err := json.Unmarshal(payload, &webhookEvent); err != nil {
 		log.Error().Err(err).Msg("Failed to bind webhook event")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
+	// The Svix message id is recorded in the transaction of the event, so a replayed
+	// delivery changes nothing and a failed one is retried
+	svixID := c.GetHeader(svix.HeaderID)
+
 	log.Info().
 		Str("event_type", webhookEvent.Type).
+		Str("svix_id", svixID).
 		Int64("timestamp", webhookEvent.Timestamp).
 		Msg("Received Clerk webhook")
 
 	switch webhookEvent.Type {
 	case "user.created":
-		h.handleUserCreated(c, webhookEvent.Data)
+		h.handleUserCreated(c, svixID, webhookEvent.Data)
 	case "user.updated":
-		h.handleUserUpdated(c, webhookEvent.Data)
+		h.handleUserUpdated(c, svixID, webhookEvent.Data)
 	case "user.deleted":
-		h.handleUserDeleted(c, webhookEvent.Data)
+		h.handleUserDeleted(c, svixID, webhookEvent.Data)
 	default:
 		log.Warn().Str("event_type", webhookEvent.Type).Msg("Unhandled webhook event type")
 		c.JSON(http.StatusOK, gin.H{"message": "Event type not handled"})
 		return
 	}
 
+	// The event handlers only write a response when they fail or the delivery is a replay
+	if c.Writer.Written() {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Webhook processed successfully"})
 }
 
-func (h *Handler) handleUserCreated(c *gin.Context, data json.RawMessage) {
+// skipReplayed answers a delivery of an already processed message with 200, since Svix keeps
+// retrying anything else
+func (h *Handler) skipReplayed(c *gin.Context, svixID string) {
+	log.Info().Str("svix_id", svixID).Msg("Skipped replayed Clerk webhook")
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook message already processed"})
+}
+
+func (h *Handler) handleUserCreated(c *gin.Context, svixID string, data json.RawMessage) {
 	var userData types.UserData
 	if err := json.Unmarshal(data, &userData); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal user data")
@@ -80,87 +126,50 @@ func (h *Handler) handleUserCreated(c *gin.Context, data json.RawMessage) {
 		email = userData.EmailAddresses[0].EmailAddress
 	}
 
-	// STEP 1: Save user to database
-	userID := uuid.New()
-	user, err := h.store.CreateUser(c.Request.Context(), db.CreateUserParams{
-		ID:        userID,
-		ClerkID:   userData.ID,
-		FirstName: &userData.FirstName,
-		Email:     email,
-	})
+	// The outbox relay publishes the event once the transaction commits (triggers email)
+	event, err := events.NewUserCreatedEvent(userData.ID, email, userData.FirstName, userData.LastName).OutboxParams()
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to create user in database")
+		log.Error().Err(err).Msg("Failed to build user created event")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	log.Info().
-		Str("user_id", user.ID.String()).
-		Str("clerk_id", userData.ID).
-		Msg("User created in database successfully")
-
-	// STEP 2: Subscribe to workflows in background with parallel processing
-	go func(userID uuid.UUID) {
-		ctx := context.Background()
-
-		workflows, err := h.store.GetWorkflow(ctx)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to get workflows")
+	// Save the user, its default subscriptions and the event in one transaction
+	result, err := h.store.ProvisionUserTx(c.Request.Context(), db.ProvisionUserTxParams{
+		ClerkID:          userData.ID,
+		FirstName:        &userData.FirstName,
+		Email:            email,
+		Plan:             userData.PlanOrNil(),
+		Event:            event,
+		InstanceLimit:    h.cfg.WorkflowInstanceLimit,
+		WebhookMessageID: svixID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrWebhookMessageProcessed) {
+			h.skipReplayed(c, svixID)
 			return
 		}
-
-		// Use WaitGroup for parallel workflow creation
-		var wg sync.WaitGroup
-
-		for _, workflow := range workflows {
-			wg.Add(1)
-
-			// Create each workflow in parallel
-			go func(wf db.KainosWorkflow) {
-				defer wg.Done()
-
-				status := "OFF"
-				_, err := h.store.CreateUserWorkflow(ctx, db.CreateUserWorkflowParams{
-					ID:         uuid.New(),
-					WorkflowID: wf.ID,
-					CustomerID: userID,
-					MetaData:   []byte("{}"),
-					Status:     &status,
-				})
-				if err != nil {
-					log.Error().
-						Err(err).
-						Str("workflow_id", wf.ID.String()).
-						Msg("Failed to create user workflow")
-				} else {
-					log.Info().
-						Str("user_id", userID.String()).
-						Str("workflow_name", wf.WorkflowName).
-						Msg("User subscribed to workflow")
-				}
-			}(workflow)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The upsert skips soft-deleted users. Answered with 200 so Svix does not keep
+			// retrying a delivery that will never succeed.
+			log.Warn().Str("clerk_id", userData.ID).Msg("Ignoring user.created for a deleted user")
+			c.JSON(http.StatusOK, gin.H{"message": "User was deleted, event ignored"})
+			return
 		}
-
-		// Wait for all workflows to complete
-		wg.Wait()
-		log.Info().
-			Str("user_id", userID.String()).
-			Int("workflow_count", len(workflows)).
-			Msg("All workflows subscribed successfully")
-	}(user.ID)
-
-	// STEP 3: Publish to NATS (triggers email)
-	if err := h.eventPublisher.PublishUserCreated(
-		userData.ID,
-		email,
-		userData.FirstName,
-		userData.LastName,
-	); err != nil {
-		log.Error().Err(err).Msg("Failed to publish user created event")
+		log.Error().Err(err).Msg("Failed to provision user in database")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
 	}
+
+	log.Info().
+		Str("user_id", result.User.ID.String()).
+		Str("clerk_id", userData.ID).
+		Bool("created", result.Created).
+		Int64("subscriptions_created", result.SubscriptionsCreated).
+		Msg("User provisioned in database successfully")
 }
 
-func (h *Handler) handleUserUpdated(c *gin.Context, data json.RawMessage) {
+func (h *Handler) handleUserUpdated(c *gin.Context, svixID string, data json.RawMessage) {
 	var userData types.UserData
 	if err := json.Unmarshal(data, &userData); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal user data")
@@ -173,11 +182,33 @@ func (h *Handler) handleUserUpdated(c *gin.Context, data json.RawMessage) {
 		email = userData.EmailAddresses[0].EmailAddress
 	}
 
-	_, err := h.store.UpdateUserByClerkID(c.Request.Context(), db.UpdateUserByClerkIDParams{
-		ClerkID:   userData.ID,
-		FirstName: &userData.FirstName,
-		Email:     email,
+	event, err := events.NewUserUpdatedEvent(userData.ID, email, userData.FirstName, userData.LastName).OutboxParams()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build user updated event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user in database"})
+		return
+	}
+
+	_, err = h.store.UpdateUserTx(c.Request.Context(), db.UpdateUserTxParams{
+		UpdateUserByClerkIDParams: db.UpdateUserByClerkIDParams{
+			ClerkID:   userData.ID,
+			FirstName: &userData.FirstName,
+			Email:     email,
+		},
+		Plan:             userData.PlanOrNil(),
+		Event:            event,
+		WebhookMessageID: svixID,
 	})
+	if errors.Is(err, db.ErrWebhookMessageProcessed) {
+		h.skipReplayed(c, svixID)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Unknown or soft-deleted users cannot be updated; a retry would not change that
+		log.Warn().Str("clerk_id", userData.ID).Msg("Ignoring user.updated for an unknown or deleted user")
+		c.JSON(http.StatusOK, gin.H{"message": "User not found, event ignored"})
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to update user in database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user in database"})
@@ -188,18 +219,9 @@ func (h *Handler) handleUserUpdated(c *gin.Context, data json.RawMessage) {
 		Str("user_id", userData.ID).
 		Str("email", email).
 		Msg("Processing user updated event")
-
-	if err := h.eventPublisher.PublishUserUpdated(
-		userData.ID,
-		email,
-		userData.FirstName,
-		userData.LastName,
-	); err != nil {
-		log.Error().Err(err).Msg("Failed to publish user updated event")
-	}
 }
 
-func (h *Handler) handleUserDeleted(c *gin.Context, data json.RawMessage) {
+func (h *Handler) handleUserDeleted(c *gin.Context, svixID string, data json.RawMessage) {
 	var deletedData types.DeletedUserData
 	if err := json.Unmarshal(data, &deletedData); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal deleted user data")
@@ -207,7 +229,28 @@ func (h *Handler) handleUserDeleted(c *gin.Context, data json.RawMessage) {
 		return
 	}
 
-	_, err := h.store.SoftDeleteUserByClerkID(c.Request.Context(), deletedData.ID)
+	event, err := events.NewUserDeletedEvent(deletedData.ID).OutboxParams()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build user deleted event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user in database"})
+		return
+	}
+
+	_, err = h.store.SoftDeleteUserTx(c.Request.Context(), db.SoftDeleteUserTxParams{
+		ClerkID:          deletedData.ID,
+		Event:            event,
+		WebhookMessageID: svixID,
+	})
+	if errors.Is(err, db.ErrWebhookMessageProcessed) {
+		h.skipReplayed(c, svixID)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Already deleted, or never created here; either way there is nothing left to do
+		log.Warn().Str("clerk_id", deletedData.ID).Msg("Ignoring user.deleted for an unknown or deleted user")
+		c.JSON(http.StatusOK, gin.H{"message": "User not found, event ignored"})
+		return
+	}
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to delete user in database")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user in database"})
@@ -217,10 +260,6 @@ func (h *Handler) handleUserDeleted(c *gin.Context, data json.RawMessage) {
 	log.Info().
 		Str("user_id", deletedData.ID).
 		Msg("Processing user deleted event")
-
-	if err := h.eventPublisher.PublishUserDeleted(deletedData.ID); err != nil {
-		log.Error().Err(err).Msg("Failed to publish user deleted event")
-	}
 }
 
 func (h *Handler) handleTestUserEvent(c *gin.Context) {
@@ -0,0 +1,194 @@
+package users
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/pkg/svix"
+)
+
+var testWebhookKey = []byte("test-webhook-key-0123456789abcdef")
+
+// fakeStore remembers the Svix message ids it provisioned users for, like kainos_webhook_message
+type fakeStore struct {
+	db.Store
+	messages map[string]bool
+	err      error
+}
+
+func (f *fakeStore) ProvisionUserTx(ctx context.Context, arg db.ProvisionUserTxParams) (db.ProvisionUserTxResult, error) {
+	if f.err != nil {
+		return db.ProvisionUserTxResult{}, f.err
+	}
+	if f.messages[arg.WebhookMessageID] {
+		return db.ProvisionUserTxResult{}, db.ErrWebhookMessageProcessed
+	}
+	f.messages[arg.WebhookMessageID] = true
+	return db.ProvisionUserTxResult{Created: true}, nil
+}
+
+func (f *fakeStore) UpdateUserTx(ctx context.Context, arg db.UpdateUserTxParams) (db.KainosUser, error) {
+	return db.KainosUser{}, f.err
+}
+
+func (f *fakeStore) SoftDeleteUserTx(ctx context.Context, arg db.SoftDeleteUserTxParams) (db.KainosUser, error) {
+	return db.KainosUser{}, f.err
+}
+
+func newTestHandler(t *testing.T, store *fakeStore) *gin.Engine {
+	t.Helper()
+
+	cfg := &configs.AppConfig{SvixSecret: "whsec_" + base64.StdEncoding.EncodeToString(testWebhookKey)}
+	h, err := NewHandler(store, cfg, nil)
+	if err != nil {
+		t.Fatalf("NewHandler returned error: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhooks/clerk", h.handleClerkWebhook)
+	return router
+}
+
+func deliver(router *gin.Engine, msgID, payload string) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, testWebhookKey)
+	fmt.Fprintf(mac, "%s.%s.%s", msgID, timestamp, payload)
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/clerk", strings.NewReader(payload))
+	request.Header.Set(svix.HeaderID, msgID)
+	request.Header.Set(svix.HeaderTimestamp, timestamp)
+	request.Header.Set(svix.HeaderSignature, "v1,"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func message(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body map[string]string
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return body["message"]
+}
+
+const userCreated = `{"type":"user.created","data":{"id":"user_1","first_name":"Ada","email_addresses":[{"email_address":"ada@example.com"}]}}`
+
+func TestClerkWebhook_ReplayIsAcknowledged(t *testing.T) {
+	router := newTestHandler(t, &fakeStore{messages: map[string]bool{}})
+
+	first := deliver(router, "msg_1", userCreated)
+	if first.Code != http.StatusOK || message(t, first) != "Webhook processed successfully" {
+		t.Fatalf("first delivery = %d %s", first.Code, first.Body)
+	}
+
+	// Svix only stops retrying on a 2xx
+	replay := deliver(router, "msg_1", userCreated)
+	if replay.Code != http.StatusOK || message(t, replay) != "Webhook message already processed" {
+		t.Errorf("replay = %d %s, want 200 already processed", replay.Code, replay.Body)
+	}
+}
+
+func TestClerkWebhook_FailureIsRetried(t *testing.T) {
+	router := newTestHandler(t, &fakeStore{messages: map[string]bool{}, err: errors.New("connection refused")})
+
+	recorder := deliver(router, "msg_1", userCreated)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d so Svix retries", recorder.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestClerkWebhook_RejectsBadSignature(t *testing.T) {
+	router := newTestHandler(t, &fakeStore{messages: map[string]bool{}})
+
+	request := httptest.NewRequest(http.MethodPost, "/webhooks/clerk", strings.NewReader(userCreated))
+	request.Header.Set(svix.HeaderID, "msg_1")
+	request.Header.Set(svix.HeaderTimestamp, strconv.FormatInt(time.Now().Unix(), 10))
+	request.Header.Set(svix.HeaderSignature, "v1,"+base64.StdEncoding.EncodeToString([]byte("forged")))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestClerkWebhook_CreatedForDeletedUserIsAcknowledged(t *testing.T) {
+	// The upsert finds the soft-deleted user and returns no row
+	router := newTestHandler(t, &fakeStore{messages: map[string]bool{}, err: pgx.ErrNoRows})
+
+	recorder := deliver(router, "msg_1", userCreated)
+	if recorder.Code != http.StatusOK || message(t, recorder) != "User was deleted, event ignored" {
+		t.Errorf("delivery = %d %s, want 200 ignored", recorder.Code, recorder.Body)
+	}
+}
+
+func TestClerkWebhook_UnknownUserIsAcknowledged(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+	}{
+		{name: "updated", payload: `{"type":"user.updated","data":{"id":"user_1","first_name":"Ada","email_addresses":[{"email_address":"ada@example.com"}]}}`},
+		{name: "deleted", payload: `{"type":"user.deleted","data":{"id":"user_1","deleted":true}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The update finds no live user with the Clerk id
+			router := newTestHandler(t, &fakeStore{messages: map[string]bool{}, err: pgx.ErrNoRows})
+
+			recorder := deliver(router, "msg_1", tt.payload)
+			if recorder.Code != http.StatusOK || message(t, recorder) != "User not found, event ignored" {
+				t.Errorf("delivery = %d %s, want 200 ignored", recorder.Code, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutes_TestUserEventOnlyInDevelopment(t *testing.T) {
+	tests := []struct {
+		environment string
+		want        int
+	}{
+		{environment: "development", want: http.StatusBadRequest},
+		{environment: "production", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			cfg := &configs.AppConfig{AppEnvironment: tt.environment, SvixSecret: "whsec_" + base64.StdEncoding.EncodeToString(testWebhookKey)}
+			h, err := NewHandler(&fakeStore{}, cfg, nil)
+			if err != nil {
+				t.Fatalf("NewHandler returned error: %v", err)
+			}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			h.RegisterRoutes(router)
+
+			// An empty body stops at validation, before anything is published
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/test-user-event", strings.NewReader("{}")))
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.want)
+			}
+		})
+	}
+}
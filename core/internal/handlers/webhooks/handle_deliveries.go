@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+	outbound "stock-agent.io/internal/webhooks"
+)
+
+const (
+	defaultDeliveryPageSize = 20
+	maxDeliveryPageSize     = 100
+)
+
+// ListDeliveries - Latest deliveries of an endpoint, newest first, with the outcome of their last attempt
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	endpoint, ok := h.ownedEndpoint(c)
+	if !ok {
+		return
+	}
+
+	pageSize := defaultDeliveryPageSize
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxDeliveryPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxDeliveryPageSize)})
+			return
+		}
+	}
+
+	deliveries, err := h.store.ListWebhookDeliveries(c.Request.Context(), db.ListWebhookDeliveriesParams{
+		EndpointID: endpoint.ID,
+		PageSize:   int32(pageSize),
+	})
+	if err != nil {
+		log.Error().Err(err).Str("endpoint_id", endpoint.ID.String()).Msg("Failed to list webhook deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook deliveries"})
+		return
+	}
+
+	response := make([]types.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, toDeliveryResponse(delivery))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": response})
+}
+
+// RedeliverDelivery - Send a delivery again right away, whatever its status, with a fresh set of attempts.
+// The payload and its event id are unchanged, so receivers can tell it is the same event.
+func (h *Handler) RedeliverDelivery(c *gin.Context) {
+	endpoint, ok := h.ownedEndpoint(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := h.store.RedeliverWebhookDelivery(c.Request.Context(), db.RedeliverWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+			return
+		}
+		log.Error().Err(err).Str("delivery_id", deliveryID.String()).Msg("Failed to redeliver webhook")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to redeliver webhook"})
+		return
+	}
+
+	log.Info().Str("delivery_id", delivery.ID.String()).Str("endpoint_id", endpoint.ID.String()).Msg("Webhook redelivery queued")
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "Redelivery queued",
+		"delivery": toDeliveryResponse(delivery),
+	})
+}
+
+func toDeliveryResponse(delivery db.KainosWebhookDelivery) types.WebhookDeliveryResponse {
+	response := types.WebhookDeliveryResponse{
+		ID:             delivery.ID.String(),
+		ExecutionID:    delivery.ExecutionID.String(),
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt.Time,
+		Payload:        delivery.Payload,
+	}
+
+	if delivery.Status == outbound.StatusPending && delivery.NextAttemptAt.Valid {
+		nextAttemptAt := delivery.NextAttemptAt.Time
+		response.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		deliveredAt := delivery.DeliveredAt.Time
+		response.DeliveredAt = &deliveredAt
+	}
+
+	return response
+}
@@ -0,0 +1,320 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/middleware"
+	"stock-agent.io/internal/types"
+	outbound "stock-agent.io/internal/webhooks"
+)
+
+const (
+	maxEndpointsPerUser  = 10
+	maxEndpointURLLength = 2048
+)
+
+type Handler struct {
+	middleWareManger *middleware.Manager
+	store            db.Store
+	cfg              *configs.AppConfig
+}
+
+func NewHandler(
+	middleWareManager *middleware.Manager,
+	store db.Store,
+	cfg *configs.AppConfig,
+) *Handler {
+	return &Handler{
+		middleWareManger: middleWareManager,
+		store:            store,
+		cfg:              cfg,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *gin.Engine) {
+	api := router.Group("/api/v1/webhooks", h.middleWareManger.AuthMiddleware())
+	{
+		// Endpoints receiving the user's workflow.completed and workflow.failed events
+		api.GET("", h.ListEndpoints)
+		api.POST("", h.CreateEndpoint)
+		api.PATCH("/:id", h.UpdateEndpoint)
+		api.DELETE("/:id", h.DeleteEndpoint)
+
+		// Delivery log of an endpoint
+		api.GET("/:id/deliveries", h.ListDeliveries)
+		api.POST("/:id/deliveries/:deliveryId/redeliver", h.RedeliverDelivery)
+	}
+}
+
+// ListEndpoints - Webhook endpoints of the authenticated user, without their secrets
+func (h *Handler) ListEndpoints(c *gin.Context) {
+	endpoints, err := h.store.ListWebhookEndpointsByClerkID(c.Request.Context(), c.GetString(types.UserIDContextKey))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list webhook endpoints")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook endpoints"})
+		return
+	}
+
+	response := make([]types.WebhookEndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		response = append(response, toEndpointResponse(endpoint, false))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"endpoints": response})
+}
+
+// CreateEndpoint - Register a webhook endpoint. The signing secret is generated here and only
+// returned in this response; receivers use it to verify the X-Kainos-Signature header.
+func (h *Handler) CreateEndpoint(c *gin.Context) {
+	var req types.CreateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpointURL, err := h.validateURL(req.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	clerkID := c.GetString(types.UserIDContextKey)
+
+	user, err := h.store.GetUserByClerkID(ctx, clerkID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	existing, err := h.store.ListWebhookEndpointsByClerkID(ctx, clerkID)
+	if err != nil {
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to list webhook endpoints")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+	if len(existing) >= maxEndpointsPerUser {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("At most %d webhook endpoints are allowed", maxEndpointsPerUser)})
+		return
+	}
+
+	secret, err := outbound.GenerateSecret()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate webhook secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	endpoint, err := h.store.CreateWebhookEndpoint(ctx, db.CreateWebhookEndpointParams{
+		ID:         uuid.New(),
+		CustomerID: user.ID,
+		Url:        endpointURL,
+		Secret:     secret,
+		Events:     events,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to create webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	log.Info().Str("endpoint_id", endpoint.ID.String()).Str("clerk_id", clerkID).Msg("Webhook endpoint created")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Webhook endpoint created successfully",
+		"endpoint": toEndpointResponse(endpoint, true),
+	})
+}
+
+// UpdateEndpoint - Change the URL, the events or turn an endpoint off; an inactive endpoint gets no new deliveries
+func (h *Handler) UpdateEndpoint(c *gin.Context) {
+	endpoint, ok := h.ownedEndpoint(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateWebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := db.UpdateWebhookEndpointParams{
+		Url:    endpoint.Url,
+		Events: endpoint.Events,
+		Active: endpoint.Active,
+		ID:     endpoint.ID,
+	}
+
+	var err error
+	if req.URL != nil {
+		if params.Url, err = h.validateURL(*req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Events != nil {
+		if params.Events, err = normalizeEvents(req.Events); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Active != nil {
+		params.Active = *req.Active
+	}
+
+	updated, err := h.store.UpdateWebhookEndpoint(c.Request.Context(), params)
+	if err != nil {
+		log.Error().Err(err).Str("endpoint_id", endpoint.ID.String()).Msg("Failed to update webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook endpoint"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Webhook endpoint updated successfully",
+		"endpoint": toEndpointResponse(updated, false),
+	})
+}
+
+// DeleteEndpoint - Delete an endpoint with its delivery log; pending deliveries are dropped
+func (h *Handler) DeleteEndpoint(c *gin.Context) {
+	endpoint, ok := h.ownedEndpoint(c)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWebhookEndpoint(c.Request.Context(), endpoint.ID); err != nil {
+		log.Error().Err(err).Str("endpoint_id", endpoint.ID.String()).Msg("Failed to delete webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook endpoint"})
+		return
+	}
+
+	log.Info().Str("endpoint_id", endpoint.ID.String()).Msg("Webhook endpoint deleted")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Webhook endpoint deleted successfully",
+		"endpoint_id": endpoint.ID.String(),
+	})
+}
+
+// ownedEndpoint loads the :id endpoint of the authenticated user, answering 400/404/500 itself
+func (h *Handler) ownedEndpoint(c *gin.Context) (db.KainosWebhookEndpoint, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook endpoint ID"})
+		return db.KainosWebhookEndpoint{}, false
+	}
+
+	endpoint, err := h.store.GetWebhookEndpointByIDAndClerkID(c.Request.Context(), db.GetWebhookEndpointByIDAndClerkIDParams{
+		ID:      id,
+		ClerkID: c.GetString(types.UserIDContextKey),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+			return db.KainosWebhookEndpoint{}, false
+		}
+		log.Error().Err(err).Msg("Failed to get webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhook endpoint"})
+		return db.KainosWebhookEndpoint{}, false
+	}
+
+	return endpoint, true
+}
+
+// validateURL accepts absolute http(s) URLs; production only delivers over https
+func (h *Handler) validateURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) > maxEndpointURLLength {
+		return "", fmt.Errorf("url must be at most %d characters", maxEndpointURLLength)
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+	if parsed.Scheme != "https" && h.cfg.IsProduction() {
+		return "", errors.New("url must use https")
+	}
+	if parsed.User != nil {
+		return "", errors.New("url must not contain credentials")
+	}
+	if !h.cfg.WebhookAllowPrivateNetworks && isInternalHost(parsed.Hostname()) {
+		return "", errors.New("url must point to a public address")
+	}
+
+	return parsed.String(), nil
+}
+
+// isInternalHost catches endpoints that are internal on their face. Hostnames resolving to
+// internal addresses are refused by the dispatcher when it dials them.
+func isInternalHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && outbound.IsBlockedAddress(ip)
+}
+
+// normalizeEvents dedupes the subscribed events, rejecting unknown ones
+func normalizeEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool, len(events))
+	normalized := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !outbound.IsEventType(event) {
+			return nil, fmt.Errorf("unknown event %q, expected one of %s", event, strings.Join(outbound.EventTypes, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, errors.New("events must not be empty")
+	}
+	return normalized, nil
+}
+
+func toEndpointResponse(endpoint db.KainosWebhookEndpoint, withSecret bool) types.WebhookEndpointResponse {
+	response := types.WebhookEndpointResponse{
+		ID:        endpoint.ID.String(),
+		URL:       endpoint.Url,
+		Events:    endpoint.Events,
+		Active:    endpoint.Active,
+		CreatedAt: endpoint.CreatedAt.Time,
+	}
+
+	if withSecret {
+		response.Secret = endpoint.Secret
+	}
+	if endpoint.UpdatedAt.Valid {
+		updatedAt := endpoint.UpdatedAt.Time
+		response.UpdatedAt = &updatedAt
+	}
+
+	return response
+}
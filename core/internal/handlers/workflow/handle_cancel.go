@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/api/serviceerror"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/internal/webhooks"
+)
+
+// CancelExecution - Request cancellation of a running execution. The workflow records it as
+// CANCELLED itself once the Mastra call stops; with ?terminate=true the run is killed right
+// away and the row is finished here, without partial output, along with its workflow.completed
+// event and webhook deliveries.
+func (w *Handler) CancelExecution(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	terminate := false
+	if value := c.Query("terminate"); value != "" {
+		var err error
+		if terminate, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "terminate must be true or false"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	execution, err := w.store.GetUserWorkflowExecution(ctx, db.GetUserWorkflowExecutionParams{
+		UserWorkflowID: owned.ID,
+		TemporalRunID:  c.Param("runId"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to get workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel workflow execution"})
+		return
+	}
+
+	if execution.Status != types.ExecutionStatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Execution is already %s", execution.Status)})
+		return
+	}
+
+	clerkID := c.GetString(types.UserIDContextKey)
+	if terminate {
+		err = w.runner.Terminate(ctx, execution.TemporalWorkflowID, execution.TemporalRunID, "Terminated by "+clerkID)
+	} else {
+		err = w.runner.Cancel(ctx, execution.TemporalWorkflowID, execution.TemporalRunID)
+	}
+
+	// The run already closed in Temporal; its row will catch up or was left behind by a crashed worker
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Execution is no longer running"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to cancel workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel workflow execution"})
+		return
+	}
+
+	if !terminate {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Cancellation requested",
+			"run_id":  execution.TemporalRunID,
+		})
+		return
+	}
+
+	// The terminated workflow never gets to publish its workflow.completed event, so it goes out here
+	reason := "terminated"
+	event, err := w.terminatedEvent(ctx, owned.ID, execution, reason)
+	if err != nil {
+		log.Error().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to build terminated workflow event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Workflow terminated but its execution could not be updated"})
+		return
+	}
+
+	finished, err := w.store.FinishWorkflowExecutionTx(ctx, db.FinishWorkflowExecutionTxParams{
+		FinishWorkflowExecutionParams: db.FinishWorkflowExecutionParams{
+			Status:             types.ExecutionStatusCancelled,
+			Error:              &reason,
+			TemporalWorkflowID: execution.TemporalWorkflowID,
+			TemporalRunID:      execution.TemporalRunID,
+		},
+		WebhookEvent: webhooks.NewExecutionEvent,
+		Event:        &event,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to record terminated workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Workflow terminated but its execution could not be updated"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Execution terminated",
+		"execution": toExecutionResponse(finished, true),
+	})
+}
+
+// terminatedEvent - The workflow.completed event of a run terminated on the user's request
+func (w *Handler) terminatedEvent(ctx context.Context, userWorkflowID uuid.UUID, execution db.KainosWorkflowExecution, reason string) (db.CreateOutboxEventParams, error) {
+	target, err := w.store.GetWorkflowNotificationTarget(ctx, userWorkflowID)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, fmt.Errorf("failed to get notification target: %w", err)
+	}
+
+	data := events.NewWorkflowCompletedData(userWorkflowID, target, w.cfg.AppPublicURL, execution.TemporalWorkflowID, execution.TemporalRunID)
+	data.Status, data.Error, data.FinishedAt = types.ExecutionStatusCancelled, reason, time.Now().UTC()
+
+	event, err := events.NewWorkflowCompletedEvent(data)
+	if err != nil {
+		return db.CreateOutboxEventParams{}, err
+	}
+	return event.OutboxParams()
+}
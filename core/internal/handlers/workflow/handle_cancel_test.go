@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/types"
+)
+
+func (f *fakeStore) GetWorkflowNotificationTarget(ctx context.Context, id uuid.UUID) (db.GetWorkflowNotificationTargetRow, error) {
+	return db.GetWorkflowNotificationTargetRow{ID: id, WorkflowName: "Daily report", ClerkID: "user_1", Email: "ada@example.com", NotifyOn: types.NotifyOnAlways}, nil
+}
+
+func (f *fakeStore) FinishWorkflowExecutionTx(ctx context.Context, arg db.FinishWorkflowExecutionTxParams) (db.KainosWorkflowExecution, error) {
+	f.finishedTx = append(f.finishedTx, arg)
+	return db.KainosWorkflowExecution{Status: arg.Status, TemporalRunID: arg.TemporalRunID}, nil
+}
+
+func TestCancelExecution_TerminateQueuesCompletedEvent(t *testing.T) {
+	h, store, temporalClient := newTestHandler(t)
+	store.executions = []db.KainosWorkflowExecution{execution(types.ExecutionStatusRunning)}
+	temporalClient.On("TerminateWorkflow", mock.Anything, "wf-1", "run-1", "Terminated by user_1").Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/:id/executions/:runId/cancel", func(c *gin.Context) {
+		c.Set(types.UserIDContextKey, "user_1")
+		h.CancelExecution(c)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/"+store.userWorkflow.ID.String()+"/executions/run-1/cancel?terminate=true", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d %s, want %d", recorder.Code, recorder.Body, http.StatusOK)
+	}
+
+	if len(store.finishedTx) != 1 {
+		t.Fatalf("finished %d executions, want 1", len(store.finishedTx))
+	}
+	finished := store.finishedTx[0]
+	if finished.Status != types.ExecutionStatusCancelled || finished.WebhookEvent == nil || finished.Event == nil {
+		t.Fatalf("finished %+v, want CANCELLED with webhook deliveries and an outbox event", finished)
+	}
+	if finished.Event.Subject != events.SubjectWorkflowCompleted {
+		t.Errorf("outbox subject = %s, want %s", finished.Event.Subject, events.SubjectWorkflowCompleted)
+	}
+
+	var event struct {
+		Data events.WorkflowCompletedData `json:"data"`
+	}
+	if err := json.Unmarshal(finished.Event.Payload, &event); err != nil {
+		t.Fatalf("outbox payload is not an event: %v", err)
+	}
+	if event.Data.Status != types.ExecutionStatusCancelled || event.Data.TemporalRunID != "run-1" || event.Data.Email != "ada@example.com" {
+		t.Errorf("event data = %+v, want the cancelled run-1 of ada@example.com", event.Data)
+	}
+}
@@ -0,0 +1,242 @@
+package workflow
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+const (
+	defaultExecutionPageSize = 20
+	maxExecutionPageSize     = 100
+)
+
+// ListExecutions - Paginated execution history of a user workflow, newest first
+func (w *Handler) ListExecutions(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	pageSize := defaultExecutionPageSize
+	if limit := c.Query("limit"); limit != "" {
+		pageSize, err = strconv.Atoi(limit)
+		if err != nil || pageSize < 1 || pageSize > maxExecutionPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxExecutionPageSize)})
+			return
+		}
+	}
+
+	var status *string
+	if s := strings.ToUpper(c.Query("status")); s != "" {
+		if !isExecutionStatus(s) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+			return
+		}
+		status = &s
+	}
+
+	startedAfter, err := parseTimeFilter(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC3339 timestamp"})
+		return
+	}
+
+	startedBefore, err := parseTimeFilter(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC3339 timestamp"})
+		return
+	}
+
+	var cursorStartedAt pgtype.Timestamp
+	var cursorID uuid.UUID
+	if cursor := c.Query("cursor"); cursor != "" {
+		cursorStartedAt, cursorID, err = decodeExecutionCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	executions, err := w.store.ListWorkflowExecutions(ctx, db.ListWorkflowExecutionsParams{
+		UserWorkflowID:  owned.ID,
+		Status:          status,
+		StartedAfter:    startedAfter,
+		StartedBefore:   startedBefore,
+		CursorStartedAt: cursorStartedAt,
+		CursorID:        cursorID,
+		PageSize:        int32(pageSize + 1),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list workflow executions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workflow executions"})
+		return
+	}
+
+	total, err := w.store.CountWorkflowExecutions(ctx, db.CountWorkflowExecutionsParams{
+		UserWorkflowID: owned.ID,
+		Status:         status,
+		StartedAfter:   startedAfter,
+		StartedBefore:  startedBefore,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count workflow executions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workflow executions"})
+		return
+	}
+
+	// One extra row was fetched to know whether another page exists
+	var nextCursor string
+	if len(executions) > pageSize {
+		executions = executions[:pageSize]
+		last := executions[len(executions)-1]
+		nextCursor = encodeExecutionCursor(last.StartedAt, last.ID)
+	}
+
+	items := make([]types.WorkflowExecutionResponse, 0, len(executions))
+	for _, execution := range executions {
+		items = append(items, toExecutionResponse(execution, false))
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"executions":  items,
+		"count":       len(items),
+		"next_cursor": nextCursor,
+	})
+}
+
+// GetExecution - Single execution of a user workflow, including its full output
+func (w *Handler) GetExecution(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	execution, err := w.store.GetUserWorkflowExecution(c.Request.Context(), db.GetUserWorkflowExecutionParams{
+		UserWorkflowID: owned.ID,
+		TemporalRunID:  c.Param("runId"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to get workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workflow execution"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"execution": toExecutionResponse(execution, true)})
+}
+
+// GetLatestExecution - Shortcut to the most recent finished execution and its output
+func (w *Handler) GetLatestExecution(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	execution, err := w.store.GetLatestWorkflowExecution(c.Request.Context(), owned.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workflow has not finished any run yet"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to get latest workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get latest workflow execution"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"execution": toExecutionResponse(execution, true)})
+}
+
+func toExecutionResponse(execution db.KainosWorkflowExecution, withOutput bool) types.WorkflowExecutionResponse {
+	response := types.WorkflowExecutionResponse{
+		ID:                 execution.ID.String(),
+		UserWorkflowID:     execution.UserWorkflowID.String(),
+		TemporalWorkflowID: execution.TemporalWorkflowID,
+		TemporalRunID:      execution.TemporalRunID,
+		Status:             execution.Status,
+		StartedAt:          execution.StartedAt.Time,
+		Error:              execution.Error,
+		Attempt:            execution.Attempt,
+	}
+
+	if execution.FinishedAt.Valid {
+		finishedAt := execution.FinishedAt.Time
+		response.FinishedAt = &finishedAt
+	}
+
+	if withOutput && len(execution.Output) > 0 {
+		response.Output = execution.Output
+	}
+
+	return response
+}
+
+func isExecutionStatus(status string) bool {
+	switch status {
+	case types.ExecutionStatusRunning, types.ExecutionStatusSucceeded, types.ExecutionStatusFailed, types.ExecutionStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func parseTimeFilter(value string) (pgtype.Timestamp, error) {
+	if value == "" {
+		return pgtype.Timestamp{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return pgtype.Timestamp{}, err
+	}
+
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}, nil
+}
+
+// Cursors are opaque to clients: base64 of "<started_at unix nanos>|<execution id>"
+func encodeExecutionCursor(startedAt pgtype.Timestamp, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d|%s", startedAt.Time.UnixNano(), id.String())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeExecutionCursor(cursor string) (pgtype.Timestamp, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pgtype.Timestamp{}, uuid.Nil, err
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return pgtype.Timestamp{}, uuid.Nil, fmt.Errorf("malformed cursor")
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return pgtype.Timestamp{}, uuid.Nil, err
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return pgtype.Timestamp{}, uuid.Nil, err
+	}
+
+	return pgtype.Timestamp{Time: time.Unix(0, nanos).UTC(), Valid: true}, id, nil
+}
@@ -0,0 +1,258 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+)
+
+const maxWorkflowNameLength = 100
+
+// CreateWorkflowInstance - Add a user workflow for a catalog workflow. A user may have several for
+// the same catalog workflow, each with its own params and schedule, up to the limit of their plan.
+func (w *Handler) CreateWorkflowInstance(c *gin.Context) {
+	var req types.CreateWorkflowInstanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	catalogID, err := uuid.Parse(req.WorkflowID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow_id"})
+		return
+	}
+
+	name, err := normalizeWorkflowName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	catalog, err := w.store.GetWorkflowByID(c.Request.Context(), catalogID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Catalog workflow not found"})
+			return
+		}
+		log.Error().Err(err).Str("catalog_workflow_id", catalogID.String()).Msg("Failed to get catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		return
+	}
+	if catalog.DeletedAt.Valid {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Catalog workflow not found"})
+		return
+	}
+
+	params, ok := w.validateParams(c, catalog.ID, req.Params)
+	if !ok {
+		return
+	}
+	metaData, err := json.Marshal(params)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode workflow params")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		return
+	}
+
+	w.createInstance(c, db.CreateUserWorkflowInstanceParams{
+		WorkflowID:     catalog.ID,
+		Name:           name,
+		MetaData:       metaData,
+		CronTime:       catalog.DefaultCronTime,
+		SchedulePreset: catalog.DefaultSchedulePreset,
+		TimeZone:       catalog.DefaultTimeZone,
+		NotifyOn:       types.NotifyOnAlways,
+	})
+}
+
+// CloneWorkflow - Copy a user workflow with its params, schedule and notification setting.
+// The copy starts OFF, so it gets its own Temporal schedule only once it is turned ON.
+func (w *Handler) CloneWorkflow(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var req types.CloneWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = fmt.Sprintf("%s (copy)", displayName(owned.Name, owned.WorkflowName))
+	}
+	name, err := normalizeWorkflowName(req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w.createInstance(c, db.CreateUserWorkflowInstanceParams{
+		WorkflowID:     owned.WorkflowID,
+		Name:           name,
+		MetaData:       owned.MetaData,
+		CronTime:       owned.CronTime,
+		SchedulePreset: owned.SchedulePreset,
+		TimeZone:       owned.TimeZone,
+		NotifyOn:       owned.NotifyOn,
+	})
+}
+
+// RenameWorkflow - Set the name a user workflow is shown with
+func (w *Handler) RenameWorkflow(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req types.RenameWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name, err := normalizeWorkflowName(*req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workflow, err := w.store.RenameUserWorkflow(c.Request.Context(), db.RenameUserWorkflowParams{
+		ID:   owned.ID,
+		Name: name,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since it was looked up
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to rename workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename workflow"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Workflow renamed successfully",
+		"workflow": workflow,
+	})
+}
+
+// DeleteWorkflow - Delete a user workflow and its Temporal schedule. Its execution history is kept.
+// A workflow with a run in flight cannot be deleted; cancel the run first.
+func (w *Handler) DeleteWorkflow(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	workflow, err := w.store.DeleteUserWorkflowTx(ctx, db.DeleteUserWorkflowTxParams{
+		ClerkID:        c.GetString(types.UserIDContextKey),
+		UserWorkflowID: owned.ID,
+		RunningSince:   time.Now().Add(-runningExecutionWindow),
+	})
+	if errors.Is(err, db.ErrUserWorkflowRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Workflow has a running execution, cancel it before deleting"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to delete workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workflow"})
+		return
+	}
+
+	// The schedule is dropped after the commit; if that fails the reconciler deletes it as an orphan
+	if err := w.schedules.Delete(ctx, temporal.ScheduleID(workflow.ID)); err != nil {
+		log.Warn().Err(err).Str("workflow_id", workflow.ID.String()).Msg("Failed to delete schedule of deleted workflow, left to the reconciler")
+	}
+
+	log.Info().Str("workflow_id", workflow.ID.String()).Msg("Workflow deleted")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Workflow deleted successfully",
+		"workflow_id": workflow.ID.String(),
+	})
+}
+
+// createInstance inserts params for the authenticated user once they are below their plan limit
+func (w *Handler) createInstance(c *gin.Context, params db.CreateUserWorkflowInstanceParams) {
+	clerkID := c.GetString(types.UserIDContextKey)
+
+	user, err := w.store.GetUserByClerkID(c.Request.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		return
+	}
+
+	limit := w.cfg.WorkflowInstanceLimit(user.Plan)
+	params.ID = uuid.New()
+	params.CustomerID = user.ID
+
+	workflow, err := w.store.CreateUserWorkflowInstanceTx(c.Request.Context(), db.CreateUserWorkflowInstanceTxParams{
+		CreateUserWorkflowInstanceParams: params,
+		ClerkID:                          clerkID,
+		MaxInstances:                     int64(limit),
+	})
+	if errors.Is(err, db.ErrTooManyWorkflowInstances) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("The %s plan allows %d workflows", user.Plan, limit),
+			"plan":  user.Plan,
+			"limit": limit,
+		})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to create workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		return
+	}
+
+	log.Info().
+		Str("workflow_id", workflow.ID.String()).
+		Str("catalog_workflow_id", workflow.WorkflowID.String()).
+		Str("clerk_id", clerkID).
+		Msg("Workflow created")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Workflow created successfully",
+		"workflow": workflow,
+	})
+}
+
+// normalizeWorkflowName trims name; an empty name is stored as NULL so the catalog name is shown
+func normalizeWorkflowName(name string) (*string, error) {
+	name = strings.TrimSpace(name)
+	if len([]rune(name)) > maxWorkflowNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", maxWorkflowNameLength)
+	}
+	return optionalString(name), nil
+}
+
+func displayName(name *string, catalogName string) string {
+	if name != nil {
+		return *name
+	}
+	return catalogName
+}
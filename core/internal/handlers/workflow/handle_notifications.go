@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+// UpdateWorkflowNotifications - Choose when finished runs of a user workflow are emailed
+func (w *Handler) UpdateWorkflowNotifications(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateNotificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.NotifyOn {
+	case types.NotifyOnAlways, types.NotifyOnFailure, types.NotifyOnNever:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notify_on must be always, failure or never"})
+		return
+	}
+
+	workflow, err := w.store.UpdateUserWorkflowNotifyOn(c.Request.Context(), db.UpdateUserWorkflowNotifyOnParams{
+		ID:       owned.ID,
+		NotifyOn: req.NotifyOn,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since it was looked up
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to update workflow notifications")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Workflow notifications updated successfully",
+		"workflow": workflow,
+	})
+}
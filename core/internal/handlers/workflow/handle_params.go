@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/types"
+)
+
+// GetWorkflowParams - Current parameters of a user workflow with the catalog input schema they follow
+func (w *Handler) GetWorkflowParams(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	inputSchema, ok := w.catalogInputSchema(c, owned.WorkflowID)
+	if !ok {
+		return
+	}
+
+	params := json.RawMessage(owned.MetaData)
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"params":       params,
+		"input_schema": inputSchema,
+	})
+}
+
+// UpdateWorkflowParams - Replace the parameters of a user workflow. They are validated against the
+// catalog input schema and stored with its defaults filled in; failures are listed per field.
+func (w *Handler) UpdateWorkflowParams(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateParamsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params, ok := w.validateParams(c, owned.WorkflowID, req.Params)
+	if !ok {
+		return
+	}
+
+	metaData, err := json.Marshal(params)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode workflow params")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow params"})
+		return
+	}
+
+	updated, err := w.store.UpdateUserWorkflowMetaData(c.Request.Context(), db.UpdateUserWorkflowMetaDataParams{
+		ID:       owned.ID,
+		MetaData: metaData,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since it was looked up
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to update workflow params")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow params"})
+		return
+	}
+
+	log.Info().Str("workflow_id", updated.ID.String()).Msg("Workflow params updated")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workflow params updated successfully",
+		"params":  json.RawMessage(updated.MetaData),
+	})
+}
+
+// validateParams writes a 400 with the field errors when input does not match the catalog schema
+func (w *Handler) validateParams(c *gin.Context, catalogID uuid.UUID, input json.RawMessage) (types.WorkflowParams, bool) {
+	inputSchema, ok := w.catalogInputSchema(c, catalogID)
+	if !ok {
+		return nil, false
+	}
+
+	params, fieldErrors, err := activities.ValidateParams(inputSchema, input)
+	if err != nil {
+		log.Error().Err(err).Str("catalog_workflow_id", catalogID.String()).Msg("Catalog input schema is invalid")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Workflow input schema is invalid"})
+		return nil, false
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid workflow params",
+			"fields": fieldErrors,
+		})
+		return nil, false
+	}
+
+	return params, true
+}
+
+func (w *Handler) catalogInputSchema(c *gin.Context, catalogID uuid.UUID) (json.RawMessage, bool) {
+	catalog, err := w.store.GetWorkflowByID(c.Request.Context(), catalogID)
+	if err != nil {
+		log.Error().Err(err).Str("catalog_workflow_id", catalogID.String()).Msg("Failed to get catalog workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workflow input schema"})
+		return nil, false
+	}
+
+	if len(catalog.InputSchema) == 0 {
+		return nil, true
+	}
+	return json.RawMessage(catalog.InputSchema), true
+}
@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+)
+
+// RUNNING rows older than this no longer count against the per-user limit; a run takes
+// at most three 5 minute Mastra attempts, so anything older was left by a crashed worker
+const runningExecutionWindow = 30 * time.Minute
+
+// RunWorkflow - Start a user workflow now, optionally with input replacing its params for this run.
+// The run is started with ExecuteWorkflow rather than the schedule's Trigger: Trigger passes the
+// schedule's fixed args, so no input override, picks a workflow id of its own that the execution
+// row could not be reserved under, and needs a schedule, which workflows without one lack.
+// Every request starts a new run; clients must not retry one that may have been accepted.
+func (w *Handler) RunWorkflow(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var req types.RunWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// An override is checked against the catalog schema here so a bad one fails fast with field errors
+	input := bytes.TrimSpace(req.Input)
+	if len(input) > 0 && !bytes.Equal(input, []byte("null")) {
+		if _, ok := w.validateParams(c, owned.WorkflowID, input); !ok {
+			return
+		}
+	} else {
+		input = nil
+	}
+
+	clerkID := c.GetString(types.UserIDContextKey)
+	options := types.WorkflowRunOptions{InputOverride: input, RequestedBy: clerkID}
+
+	ctx := c.Request.Context()
+	temporalWorkflowID := temporal.RunWorkflowID(owned.ID)
+
+	// The RUNNING row is committed before Temporal is called, so the limit holds without
+	// keeping the user's lock over the RPC and no run exists without its row
+	_, err := w.store.ReserveWorkflowRunTx(ctx, db.ReserveWorkflowRunTxParams{
+		ClerkID:            clerkID,
+		UserWorkflowID:     owned.ID,
+		TemporalWorkflowID: temporalWorkflowID,
+		MaxRunning:         int64(w.cfg.MaxRunningWorkflowsPerUser),
+		RunningSince:       time.Now().Add(-runningExecutionWindow),
+	})
+	if errors.Is(err, db.ErrTooManyRunningExecutions) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many workflows are running, wait for one to finish"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to reserve workflow run")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run workflow"})
+		return
+	}
+
+	ref, err := w.runner.RunNow(ctx, temporalWorkflowID, owned.ID, owned.WorkflowID, options)
+	if err != nil {
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to run workflow")
+		w.failReservedRun(ctx, temporalWorkflowID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run workflow"})
+		return
+	}
+
+	// RecordWorkflowStart attaches the run id as well, whichever comes first
+	if _, err := w.store.AttachWorkflowExecutionRunID(ctx, db.AttachWorkflowExecutionRunIDParams{
+		TemporalRunID:      ref.RunID,
+		TemporalWorkflowID: ref.WorkflowID,
+	}); err != nil {
+		log.Warn().Err(err).Str("temporal_workflow_id", ref.WorkflowID).Msg("Failed to attach run id, left to the workflow")
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Workflow run started",
+		"workflow_id": ref.WorkflowID,
+		"run_id":      ref.RunID,
+	})
+}
+
+// failReservedRun finishes the row reserved for a run that could not be started
+func (w *Handler) failReservedRun(ctx context.Context, temporalWorkflowID string, startErr error) {
+	message := "failed to start: " + startErr.Error()
+	_, err := w.store.FinishWorkflowExecution(ctx, db.FinishWorkflowExecutionParams{
+		Status:             types.ExecutionStatusFailed,
+		Error:              &message,
+		TemporalWorkflowID: temporalWorkflowID,
+		TemporalRunID:      "",
+	})
+	if err != nil {
+		// Left RUNNING, the row stops counting against the limit after runningExecutionWindow
+		log.Error().Err(err).Str("temporal_workflow_id", temporalWorkflowID).Msg("Failed to mark unstarted run as failed")
+	}
+}
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+)
+
+type fakeStore struct {
+	db.Store
+	userWorkflow db.GetUserWorkflowByIDAndClerkIDRow
+	reserveErr   error
+	reserved     []db.ReserveWorkflowRunTxParams
+	attached     []db.AttachWorkflowExecutionRunIDParams
+	finished     []db.FinishWorkflowExecutionParams
+	finishedTx   []db.FinishWorkflowExecutionTxParams
+	updated      []db.KainosUserWorkflow
+	// executions are returned by successive GetUserWorkflowExecution calls, the last one repeating
+	executions []db.KainosWorkflowExecution
+	user       db.KainosUser
+}
+
+func (f *fakeStore) GetUserWorkflowByIDAndClerkID(ctx context.Context, arg db.GetUserWorkflowByIDAndClerkIDParams) (db.GetUserWorkflowByIDAndClerkIDRow, error) {
+	return f.userWorkflow, nil
+}
+
+func (f *fakeStore) GetWorkflowByID(ctx context.Context, id uuid.UUID) (db.KainosWorkflow, error) {
+	return db.KainosWorkflow{ID: id}, nil
+}
+
+func (f *fakeStore) ReserveWorkflowRunTx(ctx context.Context, arg db.ReserveWorkflowRunTxParams) (db.KainosWorkflowExecution, error) {
+	if f.reserveErr != nil {
+		return db.KainosWorkflowExecution{}, f.reserveErr
+	}
+	f.reserved = append(f.reserved, arg)
+	return db.KainosWorkflowExecution{TemporalWorkflowID: arg.TemporalWorkflowID, Status: "RUNNING"}, nil
+}
+
+func (f *fakeStore) AttachWorkflowExecutionRunID(ctx context.Context, arg db.AttachWorkflowExecutionRunIDParams) (int64, error) {
+	f.attached = append(f.attached, arg)
+	return 1, nil
+}
+
+func (f *fakeStore) FinishWorkflowExecution(ctx context.Context, arg db.FinishWorkflowExecutionParams) (db.KainosWorkflowExecution, error) {
+	f.finished = append(f.finished, arg)
+	return db.KainosWorkflowExecution{}, nil
+}
+
+func (f *fakeStore) UpdateUserWorkflowStatus(ctx context.Context, arg db.UpdateUserWorkflowStatusParams) (db.KainosUserWorkflow, error) {
+	return f.update(arg.ID, f.userWorkflow.CronTime, f.userWorkflow.SchedulePreset, f.userWorkflow.TimeZone, arg.Status), nil
+}
+
+func (f *fakeStore) UpdateUserWorkflowSchedule(ctx context.Context, arg db.UpdateUserWorkflowScheduleParams) (db.KainosUserWorkflow, error) {
+	return f.update(arg.ID, arg.CronTime, arg.SchedulePreset, arg.TimeZone, arg.Status), nil
+}
+
+func (f *fakeStore) update(id uuid.UUID, cron, preset, timeZone, status *string) db.KainosUserWorkflow {
+	userWorkflow := db.KainosUserWorkflow{
+		ID:             id,
+		WorkflowID:     f.userWorkflow.WorkflowID,
+		CronTime:       cron,
+		SchedulePreset: preset,
+		TimeZone:       timeZone,
+		Status:         status,
+	}
+	f.updated = append(f.updated, userWorkflow)
+	return userWorkflow
+}
+
+func newTestHandler(t *testing.T) (*Handler, *fakeStore, *mocks.Client) {
+	t.Helper()
+
+	cfg := &configs.AppConfig{TemporalTaskQueue: "default-queue", MaxRunningWorkflowsPerUser: 3}
+	store := &fakeStore{userWorkflow: db.GetUserWorkflowByIDAndClerkIDRow{ID: uuid.New(), WorkflowID: uuid.New()}}
+	temporalClient := mocks.NewClient(t)
+	runner := temporal.NewRunner(temporalClient, temporal.NewTaskQueues(store, cfg))
+
+	return &Handler{store: store, runner: runner, cfg: cfg}, store, temporalClient
+}
+
+func runWorkflow(h *Handler, id uuid.UUID) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/:id/run", func(c *gin.Context) {
+		c.Set(types.UserIDContextKey, "user_1")
+		h.RunWorkflow(c)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/"+id.String()+"/run", nil))
+	return recorder
+}
+
+// expectStart stubs the Temporal start and returns the options it was called with
+func expectStart(t *testing.T, temporalClient *mocks.Client, startErr error) *client.StartWorkflowOptions {
+	t.Helper()
+
+	var options client.StartWorkflowOptions
+	call := temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { options = args.Get(1).(client.StartWorkflowOptions) })
+	if startErr != nil {
+		call.Return(nil, startErr)
+		return &options
+	}
+
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return(func() string { return options.ID })
+	run.On("GetRunID").Return("run-1")
+	call.Return(run, nil)
+	return &options
+}
+
+func TestRunWorkflow_Started(t *testing.T) {
+	h, store, temporalClient := newTestHandler(t)
+	options := expectStart(t, temporalClient, nil)
+
+	recorder := runWorkflow(h, store.userWorkflow.ID)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body)
+	}
+	if len(store.reserved) != 1 {
+		t.Fatalf("reserved %d runs, want 1", len(store.reserved))
+	}
+	reserved := store.reserved[0]
+	if reserved.ClerkID != "user_1" || reserved.MaxRunning != 3 || reserved.UserWorkflowID != store.userWorkflow.ID {
+		t.Errorf("reserved %+v, want user_1's workflow limited to 3", reserved)
+	}
+	if options.ID != reserved.TemporalWorkflowID {
+		t.Errorf("started under %q, want the reserved %q", options.ID, reserved.TemporalWorkflowID)
+	}
+
+	want := db.AttachWorkflowExecutionRunIDParams{TemporalRunID: "run-1", TemporalWorkflowID: reserved.TemporalWorkflowID}
+	if len(store.attached) != 1 || store.attached[0] != want {
+		t.Errorf("attached %+v, want %+v", store.attached, want)
+	}
+	if len(store.finished) != 0 {
+		t.Errorf("finished %+v, want nothing", store.finished)
+	}
+
+	var body map[string]string
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if body["workflow_id"] != reserved.TemporalWorkflowID || body["run_id"] != "run-1" {
+		t.Errorf("response = %v", body)
+	}
+}
+
+func TestRunWorkflow_TooManyRunning(t *testing.T) {
+	h, store, _ := newTestHandler(t)
+	store.reserveErr = db.ErrTooManyRunningExecutions
+
+	// The mocked client fails the test if Temporal is called
+	recorder := runWorkflow(h, store.userWorkflow.ID)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRunWorkflow_StartFailureFailsReservedRow(t *testing.T) {
+	h, store, temporalClient := newTestHandler(t)
+	expectStart(t, temporalClient, errors.New("unavailable"))
+
+	recorder := runWorkflow(h, store.userWorkflow.ID)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if len(store.reserved) != 1 || len(store.finished) != 1 {
+		t.Fatalf("reserved %d and finished %d rows, want 1 and 1", len(store.reserved), len(store.finished))
+	}
+
+	finished := store.finished[0]
+	if finished.TemporalWorkflowID != store.reserved[0].TemporalWorkflowID || finished.TemporalRunID != "" {
+		t.Errorf("finished %s/%q, want the reserved row", finished.TemporalWorkflowID, finished.TemporalRunID)
+	}
+	if finished.Status != types.ExecutionStatusFailed || finished.Error == nil {
+		t.Errorf("finished as %s with error %v, want FAILED with the start error", finished.Status, finished.Error)
+	}
+	if len(store.attached) != 0 {
+		t.Errorf("attached %+v, want nothing", store.attached)
+	}
+}
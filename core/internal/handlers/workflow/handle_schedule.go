@@ -0,0 +1,68 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/utils"
+)
+
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 50
+)
+
+// PreviewSchedule - Validate a preset or cron in a time zone and list its next fire times
+func (w *Handler) PreviewSchedule(c *gin.Context) {
+	var req types.SchedulePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count := req.Count
+	if count == 0 {
+		count = defaultPreviewCount
+	}
+	if count < 1 || count > maxPreviewCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", maxPreviewCount)})
+		return
+	}
+
+	// Same validation UpdateWorkflowSchedule applies before touching Temporal
+	schedule, err := utils.ResolveSchedule(req.Preset, req.CronTime, req.TimeZone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid schedule: %s", err.Error())})
+		return
+	}
+
+	// The interval warning follows the user's plan
+	clerkID := c.GetString(types.UserIDContextKey)
+	user, err := w.store.GetUserByClerkID(c.Request.Context(), clerkID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Error().Err(err).Str("clerk_id", clerkID).Msg("Failed to get user")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview schedule"})
+		return
+	}
+
+	now := time.Now()
+
+	c.JSON(http.StatusOK, types.SchedulePreviewResponse{
+		Preset:      schedule.Preset,
+		CronTime:    schedule.Cron,
+		TimeZone:    schedule.TimeZone,
+		Description: schedule.Describe(),
+		NextRuns:    schedule.NextRuns(now, count),
+		Warnings:    schedule.Warnings(now, w.cfg.ScheduleMinIntervalFor(user.Plan)),
+	})
+}
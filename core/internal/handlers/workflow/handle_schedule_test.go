@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+func (f *fakeStore) GetUserByClerkID(ctx context.Context, clerkID string) (db.KainosUser, error) {
+	return f.user, nil
+}
+
+func TestPreviewSchedule_WarnsAtThePlanInterval(t *testing.T) {
+	tests := []struct {
+		plan     string
+		wantWarn bool
+	}{
+		{plan: "free", wantWarn: true},
+		{plan: "pro", wantWarn: false},
+		{plan: "unknown", wantWarn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.plan, func(t *testing.T) {
+			h, store, _ := newTestHandler(t)
+			h.cfg.ScheduleMinIntervals = []string{"free:1h", "pro:15m"}
+			store.user = db.KainosUser{Plan: tt.plan}
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.POST("/schedule/preview", func(c *gin.Context) {
+				c.Set(types.UserIDContextKey, "user_1")
+				h.PreviewSchedule(c)
+			})
+			recorder := httptest.NewRecorder()
+			body := strings.NewReader(`{"cron_time": "*/30 * * * *", "time_zone": "UTC"}`)
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/schedule/preview", body))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+
+			var response types.SchedulePreviewResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Unmarshal returned error: %v", err)
+			}
+			warned := false
+			for _, warning := range response.Warnings {
+				warned = warned || strings.Contains(warning, "every "+time.Hour.String())
+			}
+			if warned != tt.wantWarn {
+				t.Errorf("warnings = %v, want an interval warning: %t", response.Warnings, tt.wantWarn)
+			}
+		})
+	}
+}
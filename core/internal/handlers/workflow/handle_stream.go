@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+const (
+	// Comment lines keep proxies from closing a stream that is quiet during a long step
+	streamKeepAliveInterval = 15 * time.Second
+	// How long a closed run may take to get its row finished, e.g. by the terminate path
+	streamResultTimeout  = 5 * time.Second
+	streamResultInterval = 500 * time.Millisecond
+)
+
+// StreamExecution - Server-Sent Events of a run: a progress snapshot, then step transitions and
+// agent tokens as Mastra produces them, and finally the execution with its output as the result
+// event. A finished run only sends the result.
+func (w *Handler) StreamExecution(c *gin.Context) {
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	execution, err := w.store.GetUserWorkflowExecution(ctx, db.GetUserWorkflowExecutionParams{
+		UserWorkflowID: owned.ID,
+		TemporalRunID:  c.Param("runId"),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Execution not found"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to get workflow execution")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stream workflow execution"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if execution.Status != types.ExecutionStatusRunning {
+		writeStreamEvent(c, types.StreamEventResult, toExecutionResponse(execution, true))
+		return
+	}
+
+	// Subscribe before taking the snapshot so no event falls between the two
+	live, unsubscribe, err := w.progress.Subscribe(execution.TemporalRunID)
+	if err != nil {
+		log.Warn().Err(err).Str("run_id", execution.TemporalRunID).Msg("Streaming execution without live events")
+	} else {
+		defer unsubscribe()
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		if err := w.runner.Wait(ctx, execution.TemporalWorkflowID, execution.TemporalRunID); err != nil && ctx.Err() == nil {
+			log.Warn().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to wait for workflow run")
+		}
+	}()
+
+	progress, err := w.runner.Progress(ctx, execution.TemporalWorkflowID, execution.TemporalRunID)
+	if err != nil {
+		log.Debug().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to get workflow progress")
+	} else {
+		writeStreamEvent(c, types.StreamEventProgress, progress)
+	}
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-live:
+			writeStreamEvent(c, event.Type, event)
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case <-closed:
+			finished, err := w.finishedExecution(ctx, execution)
+			if err != nil {
+				log.Error().Err(err).Str("run_id", execution.TemporalRunID).Msg("Failed to get finished workflow execution")
+				return
+			}
+			writeStreamEvent(c, types.StreamEventResult, toExecutionResponse(finished, true))
+			return
+		}
+	}
+}
+
+// finishedExecution rereads the row of a closed run until it is no longer RUNNING or
+// streamResultTimeout passes, and returns it either way
+func (w *Handler) finishedExecution(ctx context.Context, execution db.KainosWorkflowExecution) (db.KainosWorkflowExecution, error) {
+	deadline := time.Now().Add(streamResultTimeout)
+	for {
+		row, err := w.store.GetUserWorkflowExecution(ctx, db.GetUserWorkflowExecutionParams{
+			UserWorkflowID: execution.UserWorkflowID,
+			TemporalRunID:  execution.TemporalRunID,
+		})
+		if err != nil || row.Status != types.ExecutionStatusRunning || time.Now().After(deadline) {
+			return row, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return row, ctx.Err()
+		case <-time.After(streamResultInterval):
+		}
+	}
+}
+
+func writeStreamEvent(c *gin.Context, name string, data interface{}) {
+	c.SSEvent(name, data)
+	c.Writer.Flush()
+}
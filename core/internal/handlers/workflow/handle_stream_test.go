@@ -0,0 +1,152 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/execution/workflow"
+	"stock-agent.io/internal/types"
+)
+
+func (f *fakeStore) GetUserWorkflowExecution(ctx context.Context, arg db.GetUserWorkflowExecutionParams) (db.KainosWorkflowExecution, error) {
+	if len(f.executions) == 0 {
+		return db.KainosWorkflowExecution{}, pgx.ErrNoRows
+	}
+	execution := f.executions[0]
+	if len(f.executions) > 1 {
+		f.executions = f.executions[1:]
+	}
+	return execution, nil
+}
+
+type streamEvent struct {
+	name string
+	data string
+}
+
+// streamExecution requests the stream of run-1 and splits the response into its events
+func streamExecution(t *testing.T, h *Handler, id uuid.UUID) (*httptest.ResponseRecorder, []streamEvent) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/:id/executions/:runId/stream", func(c *gin.Context) {
+		c.Set(types.UserIDContextKey, "user_1")
+		h.StreamExecution(c)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+id.String()+"/executions/run-1/stream", nil))
+
+	var streamed []streamEvent
+	for _, block := range strings.Split(strings.TrimSpace(recorder.Body.String()), "\n\n") {
+		var event streamEvent
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event:"); ok {
+				event.name = name
+			} else if data, ok := strings.CutPrefix(line, "data:"); ok {
+				event.data = data
+			}
+		}
+		if event.name != "" {
+			streamed = append(streamed, event)
+		}
+	}
+	return recorder, streamed
+}
+
+func execution(status string) db.KainosWorkflowExecution {
+	return db.KainosWorkflowExecution{
+		ID:                 uuid.New(),
+		TemporalWorkflowID: "wf-1",
+		TemporalRunID:      "run-1",
+		Status:             status,
+	}
+}
+
+func resultStatus(t *testing.T, event streamEvent) string {
+	t.Helper()
+
+	var response types.WorkflowExecutionResponse
+	if err := json.Unmarshal([]byte(event.data), &response); err != nil {
+		t.Fatalf("result event %q is not an execution: %v", event.data, err)
+	}
+	return response.Status
+}
+
+func TestStreamExecution_NotFound(t *testing.T) {
+	h, store, _ := newTestHandler(t)
+
+	recorder, _ := streamExecution(t, h, store.userWorkflow.ID)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestStreamExecution_FinishedRunOnlySendsResult(t *testing.T) {
+	// The Temporal mock fails the test on any call, so a finished run must not reach Temporal
+	h, store, _ := newTestHandler(t)
+	store.executions = []db.KainosWorkflowExecution{execution(types.ExecutionStatusSucceeded)}
+
+	recorder, streamed := streamExecution(t, h, store.userWorkflow.ID)
+	if recorder.Code != http.StatusOK || !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status = %d, content type %q, want an event stream", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if len(streamed) != 1 || streamed[0].name != types.StreamEventResult {
+		t.Fatalf("streamed %+v, want one result event", streamed)
+	}
+	if status := resultStatus(t, streamed[0]); status != types.ExecutionStatusSucceeded {
+		t.Errorf("result status = %s, want %s", status, types.ExecutionStatusSucceeded)
+	}
+}
+
+func TestStreamExecution_RunningRunSendsProgressThenResult(t *testing.T) {
+	h, store, temporalClient := newTestHandler(t)
+	// Without NATS the stream goes on without live events
+	h.progress = events.NewProgressBroker(nil)
+	// The run is RUNNING when the stream starts and finished on the reread after it closes
+	store.executions = []db.KainosWorkflowExecution{
+		execution(types.ExecutionStatusRunning),
+		execution(types.ExecutionStatusSucceeded),
+	}
+
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", mock.Anything, nil).Return(nil)
+	temporalClient.On("GetWorkflow", mock.Anything, "wf-1", "run-1").Return(run)
+
+	progress, err := converter.GetDefaultDataConverter().ToPayloads(types.WorkflowProgress{Phase: "analyzing"})
+	if err != nil {
+		t.Fatalf("ToPayloads returned error: %v", err)
+	}
+	temporalClient.On("QueryWorkflow", mock.Anything, "wf-1", "run-1", workflow.ProgressQueryName).
+		Return(client.NewValue(progress), nil)
+	temporalClient.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "run-1").
+		Return(&workflowservice.DescribeWorkflowExecutionResponse{}, nil)
+
+	_, streamed := streamExecution(t, h, store.userWorkflow.ID)
+	if len(streamed) != 2 || streamed[0].name != types.StreamEventProgress || streamed[1].name != types.StreamEventResult {
+		t.Fatalf("streamed %+v, want a progress then a result event", streamed)
+	}
+
+	var snapshot types.ExecutionProgress
+	if err := json.Unmarshal([]byte(streamed[0].data), &snapshot); err != nil || snapshot.Phase != "analyzing" {
+		t.Errorf("progress event = %s, want phase analyzing", streamed[0].data)
+	}
+	if status := resultStatus(t, streamed[1]); status != types.ExecutionStatusSucceeded {
+		t.Errorf("result status = %s, want %s", status, types.ExecutionStatusSucceeded)
+	}
+}
@@ -2,56 +2,88 @@ package workflow
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/rs/zerolog/log"
-	"go.temporal.io/sdk/client"
+	"stock-agent.io/configs"
 	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
 	"stock-agent.io/internal/execution/workflow"
 	"stock-agent.io/internal/middleware"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/utils"
 )
 
 type Handler struct {
 	workflowManger   *workflow.Manager
-	schedulerClient  client.ScheduleClient
+	schedules        *temporal.ScheduleManager
+	runner           *temporal.Runner
+	progress         *events.ProgressBroker
 	middleWareManger *middleware.Manager
 	store            db.Store
+	cfg              *configs.AppConfig
 }
 
 func NewHandler(
 	workflowManger *workflow.Manager,
-	scheduleClient client.ScheduleClient,
+	schedules *temporal.ScheduleManager,
+	runner *temporal.Runner,
+	progress *events.ProgressBroker,
 	middleWareManager *middleware.Manager,
 	store db.Store,
+	cfg *configs.AppConfig,
 ) *Handler {
 	return &Handler{
 		workflowManger:   workflowManger,
-		schedulerClient:  scheduleClient,
+		schedules:        schedules,
+		runner:           runner,
+		progress:         progress,
 		middleWareManger: middleWareManager,
 		store:            store,
+		cfg:              cfg,
 	}
 }
 
 func (w *Handler) RegisterRoutes(router *gin.Engine) {
 	// Routes will be registered by the server module
-	api := router.Group("/api/v1/workflows")
+	api := router.Group("/api/v1/workflows", w.middleWareManger.AuthMiddleware())
 	{
 		// User workflow management
 		api.GET("/my-workflows", w.GetMyWorkflows)
+		api.POST("", w.CreateWorkflowInstance)
+		api.POST("/:id/clone", w.CloneWorkflow)
+		api.PATCH("/:id/name", w.RenameWorkflow)
+		api.DELETE("/:id", w.DeleteWorkflow)
+		api.POST("/schedule/preview", w.PreviewSchedule)
 		api.PATCH("/:id/schedule", w.UpdateWorkflowSchedule)
 		api.PATCH("/:id/status", w.UpdateWorkflowStatus)
+		api.GET("/:id/params", w.GetWorkflowParams)
+		api.PUT("/:id/params", w.UpdateWorkflowParams)
+		api.PATCH("/:id/notifications", w.UpdateWorkflowNotifications)
+		api.POST("/:id/run", w.RunWorkflow)
+
+		// Execution history
+		api.GET("/:id/executions", w.ListExecutions)
+		api.GET("/:id/executions/latest", w.GetLatestExecution)
+		api.GET("/:id/executions/:runId", w.GetExecution)
+		api.POST("/:id/executions/:runId/cancel", w.CancelExecution)
+	}
+
+	// EventSource cannot set headers, so the stream also takes the Clerk session cookie
+	stream := router.Group("/api/v1/workflows", w.middleWareManger.StreamAuthMiddleware())
+	{
+		stream.GET("/:id/executions/:runId/stream", w.StreamExecution)
 	}
 }
 
 func (w *Handler) GetMyWorkflows(c *gin.Context) {
-	clerkID := c.Query("clerk_id")
-	if clerkID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "clerk_id is required"})
-		return
-	}
+	clerkID := c.GetString(types.UserIDContextKey)
 
 	workflows, err := w.store.GetUserWorkflowsByClerkID(c.Request.Context(), clerkID)
 	if err != nil {
@@ -67,13 +99,12 @@ func (w *Handler) GetMyWorkflows(c *gin.Context) {
 }
 
 func (w *Handler) UpdateWorkflowSchedule(c *gin.Context) {
-	workflowID := c.Param("id")
-
-	var req struct {
-		CronTime string `json:"cron_time" binding:"required"` // e.g., "0 9 * * *"
-		Status   string `json:"status" binding:"required"`    // "ON" or "OFF"
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
 	}
 
+	var req types.UpdateScheduleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -85,54 +116,57 @@ func (w *Handler) UpdateWorkflowSchedule(c *gin.Context) {
 		return
 	}
 
-	// Parse workflow ID
-	id, err := uuid.Parse(workflowID)
+	schedule, err := utils.ResolveSchedule(req.Preset, req.CronTime, req.TimeZone)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid schedule: %s", err.Error())})
 		return
 	}
 
-	// Update in database
+	clerkID := c.GetString(types.UserIDContextKey)
+	note := fmt.Sprintf("Schedule set to %s (%s), status %s by %s", scheduleLabel(schedule), schedule.TimeZone, req.Status, clerkID)
+
+	// Only one of cron_time and schedule_preset is kept. Temporal is only called once the row
+	// is committed; a schedule that fails to follow is repaired by the reconciler.
 	workflow, err := w.store.UpdateUserWorkflowSchedule(c.Request.Context(), db.UpdateUserWorkflowScheduleParams{
-		ID:       id,
-		CronTime: &req.CronTime,
-		Status:   &req.Status,
+		ID:             owned.ID,
+		CronTime:       optionalString(schedule.Cron),
+		SchedulePreset: optionalString(schedule.Preset),
+		TimeZone:       &schedule.TimeZone,
+		Status:         &req.Status,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since it was loaded
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to update workflow")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow"})
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to update workflow schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow schedule"})
 		return
 	}
+	synced := w.applySchedule(c.Request.Context(), workflow, note)
 
-	// Handle Temporal scheduling
-	if req.Status == "ON" {
-		if err := w.scheduleWorkflow(c.Request.Context(), workflow); err != nil {
-			log.Error().Err(err).Msg("Failed to schedule workflow in Temporal")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule workflow"})
-			return
-		}
-		log.Info().
-			Str("workflow_id", workflow.ID.String()).
-			Str("cron_time", *workflow.CronTime).
-			Msg("Workflow scheduled in Temporal")
-	} else {
-		if err := w.deleteSchedule(c.Request.Context(), workflow.ID.String()); err != nil {
-			log.Error().Err(err).Msg("Failed to delete schedule")
-		}
-		log.Info().
-			Str("workflow_id", workflow.ID.String()).
-			Msg("Workflow schedule deleted from Temporal")
-	}
+	log.Info().
+		Str("workflow_id", workflow.ID.String()).
+		Str("preset", schedule.Preset).
+		Str("cron_time", schedule.Cron).
+		Str("time_zone", schedule.TimeZone).
+		Str("status", req.Status).
+		Msg("Workflow schedule updated")
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Workflow schedule updated successfully",
-		"workflow": workflow,
+		"message":         "Workflow schedule updated successfully",
+		"workflow":        workflow,
+		"schedule_synced": synced,
 	})
 }
 
 // UpdateWorkflowStatus - Just turn workflow ON/OFF without changing schedule
 func (w *Handler) UpdateWorkflowStatus(c *gin.Context) {
-	workflowID := c.Param("id")
+	owned, ok := w.ownedUserWorkflow(c)
+	if !ok {
+		return
+	}
 
 	var req struct {
 		Status string `json:"status" binding:"required"` // "ON" or "OFF"
@@ -149,73 +183,83 @@ func (w *Handler) UpdateWorkflowStatus(c *gin.Context) {
 		return
 	}
 
-	// Parse workflow ID
-	id, err := uuid.Parse(workflowID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+	if req.Status == "ON" && owned.CronTime == nil && owned.SchedulePreset == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a schedule before turning the workflow ON"})
 		return
 	}
 
-	// Update status in database
+	note := fmt.Sprintf("Status set to %s by %s", req.Status, c.GetString(types.UserIDContextKey))
+
+	// The schedule is paused or unpaused once the status is committed
 	workflow, err := w.store.UpdateUserWorkflowStatus(c.Request.Context(), db.UpdateUserWorkflowStatusParams{
-		ID:     id,
+		ID:     owned.ID,
 		Status: &req.Status,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since it was loaded
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to update workflow status")
+		log.Error().Err(err).Str("workflow_id", owned.ID.String()).Msg("Failed to update workflow status")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
 		return
 	}
-
-	// Handle Temporal scheduling
-	if req.Status == "ON" && workflow.CronTime != nil {
-		if err := w.scheduleWorkflow(c.Request.Context(), workflow); err != nil {
-			log.Error().Err(err).Msg("Failed to schedule workflow")
-		}
-	} else if req.Status == "OFF" {
-		if err := w.deleteSchedule(c.Request.Context(), workflow.ID.String()); err != nil {
-			log.Error().Err(err).Msg("Failed to delete schedule")
-		}
-	}
+	synced := w.applySchedule(c.Request.Context(), workflow, note)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Workflow status updated successfully",
-		"workflow": workflow,
+		"message":         "Workflow status updated successfully",
+		"workflow":        workflow,
+		"schedule_synced": synced,
 	})
 }
 
-// scheduleWorkflow - Create Temporal schedule
-func (w *Handler) scheduleWorkflow(ctx context.Context, workflow db.KainosUserWorkflow) error {
-	if workflow.CronTime == nil {
-		return fmt.Errorf("cron_time is required")
-	}
-
-	scheduleID := fmt.Sprintf("workflow-%s", workflow.ID.String())
-
-	// Create Temporal schedule
-	_, err := w.schedulerClient.Create(ctx, client.ScheduleOptions{
-		ID: scheduleID,
-		Spec: client.ScheduleSpec{
-			CronExpressions: []string{*workflow.CronTime},
-		},
-		Action: &client.ScheduleWorkflowAction{
-			ID:        workflow.ID.String(),
-			Workflow:  w.workflowManger.ExecuteMastraWorkflow, // We'll create this next
-			TaskQueue: "default",
-			Args: []interface{}{
-				workflow.ID.String(),
-				workflow.WorkflowID.String(),
-			},
-		},
+// applySchedule brings the Temporal schedule in line with a committed row and reports whether
+// it did; when Temporal fails the row stands and the reconciler applies it on its next pass
+func (w *Handler) applySchedule(ctx context.Context, userWorkflow db.KainosUserWorkflow, note string) bool {
+	if err := w.schedules.Apply(ctx, userWorkflow, note); err != nil {
+		log.Warn().Err(err).Str("workflow_id", userWorkflow.ID.String()).Msg("Failed to apply schedule, left to the reconciler")
+		return false
+	}
+	return true
+}
+
+// ownedUserWorkflow loads the user workflow in the :id path param for the authenticated user.
+// Workflows owned by someone else are reported as 404 so their existence is not leaked.
+func (w *Handler) ownedUserWorkflow(c *gin.Context) (db.GetUserWorkflowByIDAndClerkIDRow, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		return db.GetUserWorkflowByIDAndClerkIDRow{}, false
+	}
+
+	userWorkflow, err := w.store.GetUserWorkflowByIDAndClerkID(c.Request.Context(), db.GetUserWorkflowByIDAndClerkIDParams{
+		ID:      id,
+		ClerkID: c.GetString(types.UserIDContextKey),
 	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+			return db.GetUserWorkflowByIDAndClerkIDRow{}, false
+		}
+		log.Error().Err(err).Msg("Failed to get user workflow")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user workflow"})
+		return db.GetUserWorkflowByIDAndClerkIDRow{}, false
+	}
 
-	return err
+	return userWorkflow, true
 }
 
-// deleteSchedule - Delete Temporal schedule
-func (w *Handler) deleteSchedule(ctx context.Context, workflowID string) error {
-	scheduleID := fmt.Sprintf("workflow-%s", workflowID)
+func scheduleLabel(schedule *utils.Schedule) string {
+	if schedule.Preset != "" {
+		return schedule.Preset
+	}
+	return schedule.Cron
+}
 
-	handle := w.schedulerClient.GetHandle(ctx, scheduleID)
-	return handle.Delete(ctx)
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
 }
@@ -0,0 +1,128 @@
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+	"stock-agent.io/internal/temporal"
+	"stock-agent.io/internal/types"
+)
+
+// withSchedules gives the handler a schedule manager whose Temporal schedule does not exist
+// yet; describeErr replaces the not found answer, failing Apply
+func withSchedules(t *testing.T, h *Handler, store *fakeStore, describeErr error) *mocks.ScheduleClient {
+	t.Helper()
+
+	scheduleClient := mocks.NewScheduleClient(t)
+	handle := mocks.NewScheduleHandle(t)
+	scheduleClient.On("GetHandle", mock.Anything, mock.Anything).Return(handle)
+
+	if describeErr == nil {
+		describeErr = serviceerror.NewNotFound("schedule not found")
+		scheduleClient.On("Create", mock.Anything, mock.Anything).Return(handle, nil)
+	}
+	handle.On("Describe", mock.Anything).
+		Run(func(mock.Arguments) {
+			// Temporal is only called once the row is written
+			if len(store.updated) == 0 {
+				t.Error("schedule applied before the row was updated")
+			}
+		}).
+		Return((*client.ScheduleDescription)(nil), describeErr)
+
+	h.schedules = temporal.NewScheduleManager(scheduleClient, temporal.NewTaskQueues(store, h.cfg))
+	return scheduleClient
+}
+
+func patchWorkflow(h *Handler, path, body string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/:id/"+path, func(c *gin.Context) {
+		c.Set(types.UserIDContextKey, "user_1")
+		handler(c)
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPatch, "/"+h.store.(*fakeStore).userWorkflow.ID.String()+"/"+path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func scheduleSynced(t *testing.T, recorder *httptest.ResponseRecorder) bool {
+	t.Helper()
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+	}
+	var body struct {
+		ScheduleSynced bool `json:"schedule_synced"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return body.ScheduleSynced
+}
+
+func TestUpdateWorkflowSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		describeErr error
+		wantSynced  bool
+	}{
+		{name: "schedule created", wantSynced: true},
+		{name: "temporal unavailable keeps the row", describeErr: errors.New("unavailable"), wantSynced: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store, _ := newTestHandler(t)
+			withSchedules(t, h, store, tt.describeErr)
+
+			recorder := patchWorkflow(h, "schedule", `{"cron_time":"0 9 * * 1-5","time_zone":"UTC","status":"ON"}`, h.UpdateWorkflowSchedule)
+			if synced := scheduleSynced(t, recorder); synced != tt.wantSynced {
+				t.Errorf("schedule_synced = %v, want %v", synced, tt.wantSynced)
+			}
+			if len(store.updated) != 1 || *store.updated[0].CronTime != "0 9 * * 1-5" {
+				t.Errorf("updated %+v, want the new cron saved", store.updated)
+			}
+		})
+	}
+}
+
+func TestUpdateWorkflowStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		describeErr error
+		wantSynced  bool
+	}{
+		{name: "schedule created", wantSynced: true},
+		{name: "temporal unavailable keeps the row", describeErr: errors.New("unavailable"), wantSynced: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store, _ := newTestHandler(t)
+			cron, timeZone := "0 9 * * 1-5", "UTC"
+			store.userWorkflow.CronTime, store.userWorkflow.TimeZone = &cron, &timeZone
+			withSchedules(t, h, store, tt.describeErr)
+
+			recorder := patchWorkflow(h, "status", `{"status":"ON"}`, h.UpdateWorkflowStatus)
+			if synced := scheduleSynced(t, recorder); synced != tt.wantSynced {
+				t.Errorf("schedule_synced = %v, want %v", synced, tt.wantSynced)
+			}
+			if len(store.updated) != 1 || *store.updated[0].Status != "ON" {
+				t.Errorf("updated %+v, want the status saved", store.updated)
+			}
+		})
+	}
+}
@@ -2,15 +2,33 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/jwt"
 	"github.com/gin-gonic/gin"
 	"stock-agent.io/internal/types"
 )
 
+// SessionCookie - Cookie in which Clerk keeps the session JWT of the app's origin
+const SessionCookie = "__session"
+
 func (m *Manager) AuthMiddleware() gin.HandlerFunc {
+	return m.authMiddleware(false)
+}
+
+// StreamAuthMiddleware is AuthMiddleware that also accepts the Clerk session cookie, since a
+// browser EventSource cannot send an Authorization header. Only read-only stream routes use it.
+func (m *Manager) StreamAuthMiddleware() gin.HandlerFunc {
+	return m.authMiddleware(true)
+}
+
+func (m *Manager) authMiddleware(allowCookie bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, ok := clerk.SessionClaimsFromContext(c.Request.Context())
+		if !ok {
+			claims, ok = m.verifySessionToken(c, sessionToken(c, allowCookie))
+		}
 		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
@@ -31,3 +49,45 @@ func (m *Manager) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminMiddleware lets through Clerk users listed in APP_ADMIN_USER_IDS.
+// It must run after AuthMiddleware.
+func (m *Manager) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.adminUserIDs[c.GetString(types.UserIDContextKey)] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// sessionToken is the bearer token of the Authorization header, or with allowCookie and no
+// header, the session cookie
+func sessionToken(c *gin.Context, allowCookie bool) string {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	if token == "" && allowCookie {
+		token, _ = c.Cookie(SessionCookie)
+	}
+	return token
+}
+
+// verifySessionToken verifies a Clerk session JWT and stores its claims on the request context
+func (m *Manager) verifySessionToken(c *gin.Context, token string) (*clerk.SessionClaims, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	claims, err := jwt.Verify(c.Request.Context(), &jwt.VerifyParams{
+		Token:      token,
+		JWKSClient: m.jwksClient,
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	c.Request = c.Request.WithContext(clerk.ContextWithSessionClaims(c.Request.Context(), claims))
+	return claims, true
+}
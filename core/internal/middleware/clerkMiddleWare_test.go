@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSessionToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		cookie      string
+		allowCookie bool
+		want        string
+	}{
+		{name: "bearer header", header: "Bearer header-token", want: "header-token"},
+		{name: "cookie ignored by default", cookie: "cookie-token", want: ""},
+		{name: "cookie on stream routes", cookie: "cookie-token", allowCookie: true, want: "cookie-token"},
+		{name: "header wins over cookie", header: "Bearer header-token", cookie: "cookie-token", allowCookie: true, want: "header-token"},
+		{name: "neither", allowCookie: true, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				request.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = request
+
+			if got := sessionToken(c, tt.allowCookie); got != tt.want {
+				t.Errorf("sessionToken = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
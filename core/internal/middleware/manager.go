@@ -2,18 +2,29 @@ package middleware
 
 import (
 	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/jwks"
 	"github.com/clerk/clerk-sdk-go/v2/user"
 )
 
 type Manager struct {
-	clerkSecret string
-	userClient  *user.Client
+	clerkSecret  string
+	userClient   *user.Client
+	jwksClient   *jwks.Client
+	adminUserIDs map[string]bool
 }
 
-func NewManager(clerkSecret string, cfg *clerk.ClientConfig) *Manager {
+func NewManager(clerkSecret string, cfg *clerk.ClientConfig, adminUserIDs []string) *Manager {
 	userClient := user.NewClient(cfg)
+
+	admins := make(map[string]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
 	return &Manager{
-		clerkSecret: clerkSecret,
-		userClient:  userClient,
+		clerkSecret:  clerkSecret,
+		userClient:   userClient,
+		jwksClient:   jwks.NewClient(cfg),
+		adminUserIDs: admins,
 	}
 }
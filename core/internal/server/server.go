@@ -15,7 +15,9 @@ import (
 	"go.uber.org/fx"
 	"stock-agent.io/configs"
 	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/handlers/admin"
 	"stock-agent.io/internal/handlers/users"
+	"stock-agent.io/internal/handlers/webhooks"
 	"stock-agent.io/internal/handlers/workflow"
 	"stock-agent.io/internal/middleware"
 )
@@ -102,9 +104,13 @@ func RegisterRoutes(
 	server *HTTPServer,
 	userHandler *users.Handler,
 	workflowHandler *workflow.Handler,
+	adminHandler *admin.Handler,
+	webhookHandler *webhooks.Handler,
 ) {
 	userHandler.RegisterRoutes(server.router)
 	workflowHandler.RegisterRoutes(server.router)
+	adminHandler.RegisterRoutes(server.router)
+	webhookHandler.RegisterRoutes(server.router)
 }
 
 func (s *HTTPServer) Start(lc fx.Lifecycle) {
@@ -3,12 +3,14 @@ package temporal
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 
 	"github.com/rs/zerolog/log"
 	"go.temporal.io/sdk/client"
 	"go.uber.org/fx"
 	"stock-agent.io/configs"
 	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/events"
 	"stock-agent.io/internal/execution/activities"
 	"stock-agent.io/internal/execution/worker"
 	"stock-agent.io/internal/execution/workflow"
@@ -43,20 +45,55 @@ func NewTemporalClient(cfg *configs.AppConfig) (client.Client, error) {
 	return temporalClient, nil
 }
 
-func NewWorkflowManager() *workflow.Manager {
-	return workflow.NewManager()
+func NewWorkflowManager(cfg *configs.AppConfig) *workflow.Manager {
+	return workflow.NewManager(cfg.TemporalNotificationTaskQueue)
 }
 
-func NewActivityManager(circuitBreakerClient *circuitBreaker.Client, store db.Store, cfg *configs.AppConfig) *activities.Manager {
-	return activities.NewManager(circuitBreakerClient, store, cfg)
+func NewActivityManager(circuitBreakerClient *circuitBreaker.Client, store db.Store, cfg *configs.AppConfig, progress *events.ProgressBroker) *activities.Manager {
+	return activities.NewManager(circuitBreakerClient, store, cfg, progress)
 }
 
-func NewWorker(cfg *configs.AppConfig, temporalClient client.Client) *worker.Worker {
-	return worker.New(cfg, temporalClient, "default")
+// NewWorkers creates one worker per task queue of APP_TEMPORAL_WORKERS
+func NewWorkers(cfg *configs.AppConfig, temporalClient client.Client, registry *worker.Registry) ([]*worker.Worker, error) {
+	workerConfigs, err := cfg.TemporalWorkerConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	workers := make([]*worker.Worker, 0, len(workerConfigs))
+	served := make(map[string]bool, len(workerConfigs))
+	for _, workerConfig := range workerConfigs {
+		w, err := worker.New(cfg, temporalClient, workerConfig, registry)
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, w)
+		served[workerConfig.TaskQueue] = true
+	}
+
+	// Another deployment may poll these queues; without one, runs on them wait until it does
+	for _, taskQueue := range []string{cfg.TemporalTaskQueue, cfg.TemporalNotificationTaskQueue} {
+		if taskQueue != "" && !served[taskQueue] {
+			log.Warn().Str("task_queue", taskQueue).Msg("No Temporal worker in this process polls the task queue")
+		}
+	}
+
+	return workers, nil
 }
 
-func NewCircuitBreakerClient() *circuitBreaker.Client {
-	return &circuitBreaker.Client{}
+func NewCircuitBreakerClient(cfg *configs.AppConfig) *circuitBreaker.Client {
+	breakerConfig := circuitBreaker.DefaultCircuitBreakerConfig("mastra")
+	breakerConfig.Timeout = cfg.MastraBreakerTimeout
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if cfg.MastraAPIKey != "" {
+		headers["Authorization"] = "Bearer " + cfg.MastraAPIKey
+	}
+
+	return circuitBreaker.NewCircuitBreakerClient(breakerConfig).
+		SetBaseURL(cfg.MastraBaseURL).
+		SetTimeout(cfg.MastraRequestTimeout).
+		SetHeaders(headers)
 }
 
 func NewScheduleClient(temporalClient client.Client) client.ScheduleClient {
@@ -69,32 +106,67 @@ func TemporalModule() fx.Option {
 			NewTemporalClient,
 			NewWorkflowManager,
 			NewActivityManager,
-			NewWorker,
+			worker.NewRegistry,
+			NewWorkers,
+			NewTaskQueues,
 			NewCircuitBreakerClient,
 			NewScheduleClient,
+			NewScheduleManager,
+			NewReconciler,
+			NewRunner,
 		),
-		fx.Invoke(func(lc fx.Lifecycle, temporalClient client.Client, worker *worker.Worker, workflowManager *workflow.Manager) {
-			worker.RegisterWorkflow(workflowManager.ExecuteMastraWorkflow)
-			log.Info().Msg("Registered ExecuteMastraWorkflow")
-			worker.RegisterActivity(workflowManager.CallMastraAPI)
-			worker.RegisterActivity(workflowManager.StoreWorkflowResult)
+		fx.Invoke(func(
+			lc fx.Lifecycle,
+			temporalClient client.Client,
+			workers []*worker.Worker,
+			workflowManager *workflow.Manager,
+			activityManager *activities.Manager,
+		) {
+			// Every worker serves every type; the task queue decides which pool runs what
+			for _, w := range workers {
+				for name, fn := range workflowManager.Workflows() {
+					w.RegisterWorkflow(name, fn)
+				}
+				for name, fn := range activityManager.Activities() {
+					w.RegisterActivity(name, fn)
+				}
+			}
 
 			lc.Append(fx.Hook{
 				OnStart: func(ctx context.Context) error {
-					go func() {
-						if err := worker.Start(); err != nil {
-							log.Error().Err(err).Msg("Failed to start Temporal worker")
+					// A worker that cannot start fails the app; OnStop is not called for this hook, so
+					// the workers already started are stopped here
+					for i, w := range workers {
+						if err := w.Start(); err != nil {
+							for _, started := range workers[:i] {
+								started.Stop()
+							}
+							return fmt.Errorf("failed to start Temporal worker on %s: %w", w.TaskQueue(), err)
 						}
-					}()
+					}
 					return nil
 				},
 				OnStop: func(ctx context.Context) error {
-					log.Info().Msg("Stopping Temporal worker")
-					worker.Stop()
+					log.Info().Int("workers", len(workers)).Msg("Stopping Temporal workers")
+					for _, w := range workers {
+						w.Stop()
+					}
 					temporalClient.Close()
 					return nil
 				},
 			})
 		}),
+		fx.Invoke(func(lc fx.Lifecycle, reconciler *Reconciler) {
+			lc.Append(fx.Hook{
+				OnStart: func(ctx context.Context) error {
+					reconciler.Start()
+					return nil
+				},
+				OnStop: func(ctx context.Context) error {
+					reconciler.Stop()
+					return nil
+				},
+			})
+		}),
 	)
 }
@@ -0,0 +1,422 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/sdk/client"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+// Kinds of drift between a user workflow row and its Temporal schedule
+const (
+	DriftMissing         = "missing"           // ON row without a schedule
+	DriftSpecMismatch    = "spec_mismatch"     // schedule fires at other times than the row says
+	DriftTaskQueue       = "task_queue"        // schedule starts runs on another task queue than its catalog workflow declares
+	DriftShouldBePaused  = "should_be_paused"  // OFF row with a running schedule
+	DriftShouldBeRunning = "should_be_running" // ON row with a paused schedule
+	DriftOrphaned        = "orphaned"          // schedule of a deleted user or of no row at all
+	DriftNoSchedule      = "no_schedule"       // ON row with neither cron nor preset, needs a human
+	DriftInvalidSchedule = "invalid_schedule"  // stored cron, preset or zone no longer resolves, needs a human
+)
+
+// Actions the reconciler takes for a drift
+const (
+	ReconcileActionCreate  = "create"
+	ReconcileActionUpdate  = "update"
+	ReconcileActionPause   = "pause"
+	ReconcileActionUnpause = "unpause"
+	ReconcileActionDelete  = "delete"
+	ReconcileActionNone    = "none"
+)
+
+const reconcileTimeout = 5 * time.Minute
+
+type Drift struct {
+	ScheduleID     string `json:"schedule_id"`
+	UserWorkflowID string `json:"user_workflow_id,omitempty"`
+	Kind           string `json:"kind"`
+	Action         string `json:"action"`
+	Detail         string `json:"detail,omitempty"`
+	Fixed          bool   `json:"fixed"`
+	Error          string `json:"error,omitempty"`
+}
+
+type ReconcileReport struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DryRun     bool      `json:"dry_run"`
+	Workflows  int       `json:"workflows"`
+	Schedules  int       `json:"schedules"`
+	Drift      []Drift   `json:"drift"`
+}
+
+// Reconciler makes the workflow-* Temporal schedules match kainos_user_workflow:
+// ON rows have a running schedule with their spec, OFF rows a paused one or none,
+// and schedules of deleted users or unknown rows are deleted.
+type Reconciler struct {
+	store          db.Store
+	scheduleClient client.ScheduleClient
+	schedules      *ScheduleManager
+	cfg            *configs.AppConfig
+
+	// running serializes periodic and on-demand runs, and guards seenQueues
+	running    sync.Mutex
+	mu         sync.RWMutex
+	lastReport *ReconcileReport
+
+	// seenQueues - Task queue each schedule was last described with, so in-sync schedules
+	// are only described again once their catalog queue changes
+	seenQueues map[string]string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewReconciler(store db.Store, scheduleClient client.ScheduleClient, schedules *ScheduleManager, cfg *configs.AppConfig) *Reconciler {
+	return &Reconciler{
+		store:          store,
+		scheduleClient: scheduleClient,
+		schedules:      schedules,
+		cfg:            cfg,
+		seenQueues:     make(map[string]string),
+	}
+}
+
+// Start reconciles once right away, then on every APP_SCHEDULE_RECONCILE_INTERVAL until Stop is called
+func (r *Reconciler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+
+	log.Info().
+		Dur("interval", r.cfg.ScheduleReconcileInterval).
+		Bool("dry_run", r.cfg.ScheduleReconcileDryRun).
+		Msg("Schedule reconciler started")
+}
+
+// Stop stops the reconcile loop and waits for a run in progress to finish
+func (r *Reconciler) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	log.Info().Msg("Schedule reconciler stopped")
+}
+
+// LastReport - Result of the most recent run, nil before the first one finished
+func (r *Reconciler) LastReport() *ReconcileReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastReport
+}
+
+func (r *Reconciler) run(ctx context.Context) {
+	r.reconcileOnce(ctx)
+	if r.cfg.ScheduleReconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.cfg.ScheduleReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcileOnce(ctx)
+		}
+	}
+}
+
+func (r *Reconciler) reconcileOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+
+	if _, err := r.Reconcile(ctx, r.cfg.ScheduleReconcileDryRun); err != nil {
+		log.Error().Err(err).Msg("Schedule reconcile failed")
+	}
+}
+
+// Reconcile compares every user workflow with the Temporal schedules and, unless dryRun,
+// creates, updates, pauses or deletes schedules to match. Drift is logged and returned.
+func (r *Reconciler) Reconcile(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	report := &ReconcileReport{StartedAt: time.Now().UTC(), DryRun: dryRun, Drift: []Drift{}}
+
+	// Schedules are listed before the rows: a user workflow created in between then has its
+	// row listed and is never taken for an orphan
+	existing, err := r.listSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.store.ListUserWorkflowSchedules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user workflows: %w", err)
+	}
+	report.Workflows = len(rows)
+	report.Schedules = len(existing)
+
+	listed := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		scheduleID := ScheduleID(row.KainosUserWorkflow.ID)
+		entry, found := existing[scheduleID]
+		delete(existing, scheduleID)
+		listed[scheduleID] = true
+
+		// A schedule drifted otherwise is described again, so the report names its queue as it is now
+		refresh := diffSchedule(row, entry, found, taskQueueCheck{}) != nil
+		drift := diffSchedule(row, entry, found, r.taskQueueCheck(ctx, row, entry, found, refresh))
+		if drift == nil {
+			continue
+		}
+		report.Drift = append(report.Drift, r.resolve(ctx, *drift, entry, found, dryRun))
+	}
+
+	// Whatever is left had no row when the rows were listed
+	for scheduleID, entry := range existing {
+		report.Drift = append(report.Drift, r.resolve(ctx, orphanDrift(scheduleID), entry, true, dryRun))
+	}
+
+	for scheduleID := range r.seenQueues {
+		if !listed[scheduleID] {
+			delete(r.seenQueues, scheduleID)
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+
+	r.mu.Lock()
+	r.lastReport = report
+	r.mu.Unlock()
+
+	log.Info().
+		Bool("dry_run", dryRun).
+		Int("workflows", report.Workflows).
+		Int("schedules", report.Schedules).
+		Int("drift", len(report.Drift)).
+		Dur("took", report.FinishedAt.Sub(report.StartedAt)).
+		Msg("Schedule reconcile finished")
+
+	return report, nil
+}
+
+func (r *Reconciler) listSchedules(ctx context.Context) (map[string]*client.ScheduleListEntry, error) {
+	iter, err := r.scheduleClient.List(ctx, client.ScheduleListOptions{PageSize: 1000})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Temporal schedules: %w", err)
+	}
+
+	schedules := make(map[string]*client.ScheduleListEntry)
+	for iter.HasNext() {
+		entry, err := iter.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Temporal schedules: %w", err)
+		}
+		if strings.HasPrefix(entry.ID, SchedulePrefix) {
+			schedules[entry.ID] = entry
+		}
+	}
+	return schedules, nil
+}
+
+// taskQueueCheck - Task queue a schedule's action starts runs on, and the one it should.
+// Listed schedules do not carry their action, so it is described; zero when either is unknown.
+type taskQueueCheck struct {
+	actual, expected string
+}
+
+// taskQueueCheck describes the schedule only when refresh is set, it was never described or
+// its catalog queue is not the one it was last seen with; otherwise that queue is reused
+func (r *Reconciler) taskQueueCheck(ctx context.Context, row db.ListUserWorkflowSchedulesRow, entry *client.ScheduleListEntry, found bool, refresh bool) taskQueueCheck {
+	if !found || row.UserDeleted || row.WorkflowDeleted {
+		return taskQueueCheck{}
+	}
+	scheduleID := ScheduleID(row.KainosUserWorkflow.ID)
+	expected := r.schedules.taskQueues.Resolve(row.TaskQueue)
+
+	if seen, ok := r.seenQueues[scheduleID]; ok && seen == expected && !refresh {
+		return taskQueueCheck{actual: seen, expected: expected}
+	}
+
+	description, err := r.scheduleClient.GetHandle(ctx, scheduleID).Describe(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("schedule_id", scheduleID).Msg("Task queue of schedule not checked")
+		return taskQueueCheck{}
+	}
+	action, ok := description.Schedule.Action.(*client.ScheduleWorkflowAction)
+	if !ok {
+		return taskQueueCheck{}
+	}
+	r.seenQueues[scheduleID] = action.TaskQueue
+	return taskQueueCheck{actual: action.TaskQueue, expected: expected}
+}
+
+// diffSchedule returns the drift between a row and its schedule, nil when they agree
+func diffSchedule(row db.ListUserWorkflowSchedulesRow, entry *client.ScheduleListEntry, found bool, taskQueue taskQueueCheck) *Drift {
+	userWorkflow := row.KainosUserWorkflow
+	drift := &Drift{ScheduleID: ScheduleID(userWorkflow.ID), UserWorkflowID: userWorkflow.ID.String()}
+	active := IsActive(userWorkflow)
+
+	if row.UserDeleted {
+		if !found {
+			return nil
+		}
+		drift.Kind, drift.Action, drift.Detail = DriftOrphaned, ReconcileActionDelete, "owner was deleted"
+		return drift
+	}
+
+	// Users keep their status when a catalog entry is deleted, but the schedule stays paused
+	if row.WorkflowDeleted {
+		if found && !entry.Paused {
+			drift.Kind, drift.Action, drift.Detail = DriftShouldBePaused, ReconcileActionPause, "catalog workflow was deleted"
+			return drift
+		}
+		return nil
+	}
+
+	schedule, err := StoredSchedule(userWorkflow)
+	switch {
+	case errors.Is(err, ErrNoSchedule):
+		if active {
+			drift.Kind, drift.Action = DriftNoSchedule, ReconcileActionNone
+			return drift
+		}
+		if found && !entry.Paused {
+			drift.Kind, drift.Action = DriftShouldBePaused, ReconcileActionPause
+			return drift
+		}
+		return nil
+	case err != nil:
+		drift.Kind, drift.Action, drift.Detail = DriftInvalidSchedule, ReconcileActionNone, err.Error()
+		return drift
+	}
+
+	switch {
+	case !found && active:
+		drift.Kind, drift.Action = DriftMissing, ReconcileActionCreate
+	case !found:
+		return nil
+	case !schedule.Matches(entry.Spec):
+		drift.Kind, drift.Action = DriftSpecMismatch, ReconcileActionUpdate
+		drift.Detail = "expected " + schedule.Describe()
+	case taskQueue.expected != "" && taskQueue.actual != taskQueue.expected:
+		drift.Kind, drift.Action = DriftTaskQueue, ReconcileActionUpdate
+		drift.Detail = fmt.Sprintf("expected task queue %s, found %s", taskQueue.expected, taskQueue.actual)
+	case active && entry.Paused:
+		drift.Kind, drift.Action = DriftShouldBeRunning, ReconcileActionUnpause
+	case !active && !entry.Paused:
+		drift.Kind, drift.Action = DriftShouldBePaused, ReconcileActionPause
+	default:
+		return nil
+	}
+	return drift
+}
+
+// orphanDrift - Drift of a schedule with no user workflow row
+func orphanDrift(scheduleID string) Drift {
+	drift := Drift{ScheduleID: scheduleID, Kind: DriftOrphaned, Action: ReconcileActionDelete, Detail: "no user workflow with this id"}
+	if id, ok := UserWorkflowID(scheduleID); ok {
+		drift.UserWorkflowID = id.String()
+	}
+	return drift
+}
+
+// recheck re-reads the row of a drift right before it is fixed and diffs it again, so a row
+// changed since the listing is not overwritten with stale values. Only a row still absent
+// now makes the schedule an orphan. A nil drift means there is nothing left to fix.
+func (r *Reconciler) recheck(ctx context.Context, scheduleID string, entry *client.ScheduleListEntry, found bool) (*Drift, db.KainosUserWorkflow, error) {
+	id, ok := UserWorkflowID(scheduleID)
+	if !ok {
+		drift := orphanDrift(scheduleID)
+		return &drift, db.KainosUserWorkflow{}, nil
+	}
+
+	row, err := r.store.GetUserWorkflowSchedule(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if !found {
+			return nil, db.KainosUserWorkflow{}, nil
+		}
+		drift := orphanDrift(scheduleID)
+		return &drift, db.KainosUserWorkflow{}, nil
+	}
+	if err != nil {
+		return nil, db.KainosUserWorkflow{}, fmt.Errorf("failed to re-read user workflow %s: %w", id, err)
+	}
+
+	// The listing just described the schedule, so only a catalog queue changed since is looked up again
+	current := db.ListUserWorkflowSchedulesRow(row)
+	return diffSchedule(current, entry, found, r.taskQueueCheck(ctx, current, entry, found, false)), row.KainosUserWorkflow, nil
+}
+
+// resolve logs a drift and fixes it unless dryRun, acting on the row as it is at that moment
+func (r *Reconciler) resolve(ctx context.Context, drift Drift, entry *client.ScheduleListEntry, found bool, dryRun bool) Drift {
+	if dryRun || drift.Action == ReconcileActionNone {
+		logDrift(drift, dryRun).Msg("Schedule drift found")
+		return drift
+	}
+
+	current, userWorkflow, err := r.recheck(ctx, drift.ScheduleID, entry, found)
+	if err != nil {
+		drift.Error = err.Error()
+		logDrift(drift, dryRun).Err(err).Msg("Schedule drift could not be fixed")
+		return drift
+	}
+	if current == nil {
+		drift.Action, drift.Detail = ReconcileActionNone, "row changed since it was listed, nothing left to fix"
+		logDrift(drift, dryRun).Msg("Schedule drift gone before it was fixed")
+		return drift
+	}
+	drift = *current
+	if drift.Action == ReconcileActionNone {
+		logDrift(drift, dryRun).Msg("Schedule drift found")
+		return drift
+	}
+
+	switch drift.Action {
+	case ReconcileActionDelete:
+		err = r.schedules.Delete(ctx, drift.ScheduleID)
+	case ReconcileActionPause:
+		err = r.schedules.Pause(ctx, userWorkflow.ID, "Reconciled: "+drift.Kind)
+	default:
+		err = r.schedules.Apply(ctx, userWorkflow, "Reconciled: "+drift.Kind)
+	}
+
+	if err != nil {
+		drift.Error = err.Error()
+		logDrift(drift, dryRun).Err(err).Msg("Schedule drift could not be fixed")
+		return drift
+	}
+
+	// Whatever the fix did to the action, the next run describes the schedule again
+	delete(r.seenQueues, drift.ScheduleID)
+	drift.Fixed = true
+	logDrift(drift, dryRun).Msg("Schedule drift fixed")
+	return drift
+}
+
+func logDrift(drift Drift, dryRun bool) *zerolog.Event {
+	return log.Warn().
+		Str("schedule_id", drift.ScheduleID).
+		Str("kind", drift.Kind).
+		Str("action", drift.Action).
+		Str("detail", drift.Detail).
+		Bool("dry_run", dryRun)
+}
@@ -0,0 +1,364 @@
+package temporal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/mock"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/utils"
+)
+
+func scheduleRow(status string, cron string, userDeleted bool) db.ListUserWorkflowSchedulesRow {
+	timeZone := "UTC"
+	row := db.ListUserWorkflowSchedulesRow{
+		KainosUserWorkflow: db.KainosUserWorkflow{ID: uuid.New(), Status: &status, TimeZone: &timeZone},
+		UserDeleted:        userDeleted,
+	}
+	if cron != "" {
+		row.KainosUserWorkflow.CronTime = &cron
+	}
+	return row
+}
+
+func scheduleEntry(t *testing.T, cron string, paused bool) *client.ScheduleListEntry {
+	t.Helper()
+	schedule, err := utils.ResolveSchedule("", cron, "UTC")
+	if err != nil {
+		t.Fatalf("ResolveSchedule returned error: %v", err)
+	}
+	spec := schedule.Spec()
+	return &client.ScheduleListEntry{Spec: &spec, Paused: paused}
+}
+
+func deletedCatalogRow(status string) db.ListUserWorkflowSchedulesRow {
+	row := scheduleRow(status, "0 9 * * *", false)
+	row.WorkflowDeleted = true
+	return row
+}
+
+func TestDiffSchedule(t *testing.T) {
+	cases := []struct {
+		name   string
+		row    db.ListUserWorkflowSchedulesRow
+		entry  *client.ScheduleListEntry
+		kind   string
+		action string
+	}{
+		{"in sync", scheduleRow("ON", "0 9 * * *", false), scheduleEntry(t, "0 9 * * *", false), "", ""},
+		{"off and paused", scheduleRow("OFF", "0 9 * * *", false), scheduleEntry(t, "0 9 * * *", true), "", ""},
+		{"off without schedule", scheduleRow("OFF", "0 9 * * *", false), nil, "", ""},
+		{"missing", scheduleRow("ON", "0 9 * * *", false), nil, DriftMissing, ReconcileActionCreate},
+		{"spec", scheduleRow("ON", "0 9 * * *", false), scheduleEntry(t, "0 10 * * *", false), DriftSpecMismatch, ReconcileActionUpdate},
+		{"paused", scheduleRow("ON", "0 9 * * *", false), scheduleEntry(t, "0 9 * * *", true), DriftShouldBeRunning, ReconcileActionUnpause},
+		{"running", scheduleRow("OFF", "0 9 * * *", false), scheduleEntry(t, "0 9 * * *", false), DriftShouldBePaused, ReconcileActionPause},
+		{"deleted user", scheduleRow("ON", "0 9 * * *", true), scheduleEntry(t, "0 9 * * *", false), DriftOrphaned, ReconcileActionDelete},
+		{"no schedule", scheduleRow("ON", "", false), nil, DriftNoSchedule, ReconcileActionNone},
+		{"deleted catalog running", deletedCatalogRow("ON"), scheduleEntry(t, "0 9 * * *", false), DriftShouldBePaused, ReconcileActionPause},
+		{"deleted catalog paused", deletedCatalogRow("ON"), scheduleEntry(t, "0 9 * * *", true), "", ""},
+		{"deleted catalog missing", deletedCatalogRow("ON"), nil, "", ""},
+		{"every in sync", scheduleRow("ON", "@every 1h", false), scheduleEntry(t, "@every 1h", false), "", ""},
+		{"seconds and year in sync", scheduleRow("ON", "0 0 9 * * * *", false), scheduleEntry(t, "0 9 * * *", false), "", ""},
+		{"invalid", scheduleRow("ON", "every day", false), nil, DriftInvalidSchedule, ReconcileActionNone},
+	}
+
+	for _, tc := range cases {
+		drift := diffSchedule(tc.row, tc.entry, tc.entry != nil, taskQueueCheck{})
+		if tc.kind == "" {
+			if drift != nil {
+				t.Errorf("%s: expected no drift, got %+v", tc.name, drift)
+			}
+			continue
+		}
+		if drift == nil || drift.Kind != tc.kind || drift.Action != tc.action {
+			t.Errorf("%s: expected %s/%s, got %+v", tc.name, tc.kind, tc.action, drift)
+		}
+	}
+}
+
+func TestDiffSchedule_TaskQueue(t *testing.T) {
+	cases := []struct {
+		name      string
+		row       db.ListUserWorkflowSchedulesRow
+		taskQueue taskQueueCheck
+		kind      string
+	}{
+		{"same queue", scheduleRow("ON", "0 9 * * *", false), taskQueueCheck{actual: "heavy", expected: "heavy"}, ""},
+		{"moved queue", scheduleRow("ON", "0 9 * * *", false), taskQueueCheck{actual: "default", expected: "heavy"}, DriftTaskQueue},
+		{"moved queue while off", scheduleRow("OFF", "0 9 * * *", false), taskQueueCheck{actual: "default", expected: "heavy"}, DriftTaskQueue},
+		{"queue unknown", scheduleRow("ON", "0 9 * * *", false), taskQueueCheck{}, ""},
+	}
+
+	for _, tc := range cases {
+		paused := !IsActive(tc.row.KainosUserWorkflow)
+		drift := diffSchedule(tc.row, scheduleEntry(t, "0 9 * * *", paused), true, tc.taskQueue)
+		if tc.kind == "" {
+			if drift != nil {
+				t.Errorf("%s: expected no drift, got %+v", tc.name, drift)
+			}
+			continue
+		}
+		if drift == nil || drift.Kind != tc.kind || drift.Action != ReconcileActionUpdate {
+			t.Errorf("%s: expected %s/%s, got %+v", tc.name, tc.kind, ReconcileActionUpdate, drift)
+		}
+	}
+}
+
+type fakeStore struct {
+	db.Store
+	catalog db.KainosWorkflow
+	// rows is what ListUserWorkflowSchedules returns, current what GetUserWorkflowSchedule does
+	rows       []db.ListUserWorkflowSchedulesRow
+	current    map[uuid.UUID]db.ListUserWorkflowSchedulesRow
+	rowsListed bool
+}
+
+func (f *fakeStore) GetWorkflowByID(ctx context.Context, id uuid.UUID) (db.KainosWorkflow, error) {
+	return f.catalog, nil
+}
+
+func (f *fakeStore) ListUserWorkflowSchedules(ctx context.Context) ([]db.ListUserWorkflowSchedulesRow, error) {
+	f.rowsListed = true
+	return f.rows, nil
+}
+
+func (f *fakeStore) GetUserWorkflowSchedule(ctx context.Context, id uuid.UUID) (db.GetUserWorkflowScheduleRow, error) {
+	row, ok := f.current[id]
+	if !ok {
+		return db.GetUserWorkflowScheduleRow{}, pgx.ErrNoRows
+	}
+	return db.GetUserWorkflowScheduleRow(row), nil
+}
+
+// newTestReconciler lists entries as the Temporal schedules and returns the handle every
+// schedule id resolves to
+func newTestReconciler(t *testing.T, store *fakeStore, entries ...*client.ScheduleListEntry) (*Reconciler, *mocks.ScheduleHandle) {
+	t.Helper()
+
+	iter := mocks.NewScheduleListIterator(t)
+	for _, entry := range entries {
+		iter.On("HasNext").Return(true).Once()
+		iter.On("Next").Return(entry, nil).Once()
+	}
+	iter.On("HasNext").Return(false)
+
+	scheduleClient := mocks.NewScheduleClient(t)
+	scheduleClient.On("List", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			if store.rowsListed {
+				t.Error("schedules listed after the rows")
+			}
+		}).
+		Return(iter, nil)
+
+	handle := mocks.NewScheduleHandle(t)
+	scheduleClient.On("GetHandle", mock.Anything, mock.Anything).Return(handle).Maybe()
+	// Schedules start runs on the default queue unless a test says otherwise
+	handle.On("Describe", mock.Anything).Return(describedOn("default-queue"), nil).Maybe()
+
+	cfg := &configs.AppConfig{TemporalTaskQueue: "default-queue"}
+	schedules := NewScheduleManager(scheduleClient, NewTaskQueues(store, cfg))
+	return NewReconciler(store, scheduleClient, schedules, cfg), handle
+}
+
+func describedOn(taskQueue string) *client.ScheduleDescription {
+	return &client.ScheduleDescription{Schedule: client.Schedule{Action: &client.ScheduleWorkflowAction{TaskQueue: taskQueue}}}
+}
+
+func listedEntry(t *testing.T, row db.ListUserWorkflowSchedulesRow, cron string, paused bool) *client.ScheduleListEntry {
+	t.Helper()
+	entry := scheduleEntry(t, cron, paused)
+	entry.ID = ScheduleID(row.KainosUserWorkflow.ID)
+	return entry
+}
+
+func TestReconcile_OrphanDeletedOnlyWhenRowIsStillAbsent(t *testing.T) {
+	row := scheduleRow("ON", "0 9 * * *", false)
+	entry := listedEntry(t, row, "0 9 * * *", false)
+
+	t.Run("row still absent", func(t *testing.T) {
+		store := &fakeStore{}
+		reconciler, handle := newTestReconciler(t, store, entry)
+		handle.On("Delete", mock.Anything).Return(nil).Once()
+
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 1 || report.Drift[0].Kind != DriftOrphaned || !report.Drift[0].Fixed {
+			t.Errorf("drift = %+v, want one fixed orphan", report.Drift)
+		}
+	})
+
+	t.Run("row showed up since the listing", func(t *testing.T) {
+		store := &fakeStore{current: map[uuid.UUID]db.ListUserWorkflowSchedulesRow{row.KainosUserWorkflow.ID: row}}
+		// The handle mock fails the test if the schedule is deleted
+		reconciler, _ := newTestReconciler(t, store, entry)
+
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 1 || report.Drift[0].Action != ReconcileActionNone || report.Drift[0].Fixed {
+			t.Errorf("drift = %+v, want the orphan left alone", report.Drift)
+		}
+	})
+}
+
+func TestReconcile_ActsOnTheRowAsItIsNow(t *testing.T) {
+	// Listed OFF with a running schedule, so a pause is due
+	listed := scheduleRow("OFF", "0 9 * * *", false)
+	entry := listedEntry(t, listed, "0 9 * * *", false)
+
+	t.Run("row unchanged", func(t *testing.T) {
+		store := &fakeStore{rows: []db.ListUserWorkflowSchedulesRow{listed}, current: map[uuid.UUID]db.ListUserWorkflowSchedulesRow{listed.KainosUserWorkflow.ID: listed}}
+		reconciler, handle := newTestReconciler(t, store, entry)
+		handle.On("Pause", mock.Anything, mock.Anything).Return(nil).Once()
+
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 1 || report.Drift[0].Kind != DriftShouldBePaused || !report.Drift[0].Fixed {
+			t.Errorf("drift = %+v, want one fixed pause", report.Drift)
+		}
+	})
+
+	t.Run("turned ON since the listing", func(t *testing.T) {
+		current := listed
+		on := "ON"
+		current.KainosUserWorkflow.Status = &on
+		store := &fakeStore{rows: []db.ListUserWorkflowSchedulesRow{listed}, current: map[uuid.UUID]db.ListUserWorkflowSchedulesRow{listed.KainosUserWorkflow.ID: current}}
+		// The running schedule now matches the row, so the handle mock fails the test on any call
+		reconciler, _ := newTestReconciler(t, store, entry)
+
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 1 || report.Drift[0].Action != ReconcileActionNone {
+			t.Errorf("drift = %+v, want nothing left to fix", report.Drift)
+		}
+	})
+
+	t.Run("deleted since the listing", func(t *testing.T) {
+		store := &fakeStore{rows: []db.ListUserWorkflowSchedulesRow{listed}}
+		reconciler, handle := newTestReconciler(t, store, entry)
+		handle.On("Delete", mock.Anything).Return(nil).Once()
+
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 1 || report.Drift[0].Kind != DriftOrphaned || !report.Drift[0].Fixed {
+			t.Errorf("drift = %+v, want the schedule deleted as an orphan", report.Drift)
+		}
+	})
+}
+
+func TestReconcile_DryRunChangesNothing(t *testing.T) {
+	listed := scheduleRow("OFF", "0 9 * * *", false)
+	orphan := scheduleRow("ON", "0 9 * * *", false)
+	store := &fakeStore{rows: []db.ListUserWorkflowSchedulesRow{listed}}
+	reconciler, _ := newTestReconciler(t, store, listedEntry(t, listed, "0 9 * * *", false), listedEntry(t, orphan, "0 9 * * *", false))
+
+	report, err := reconciler.Reconcile(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if len(report.Drift) != 2 {
+		t.Fatalf("drift = %+v, want a pause and an orphan", report.Drift)
+	}
+	for _, drift := range report.Drift {
+		if drift.Fixed {
+			t.Errorf("dry run fixed %+v", drift)
+		}
+	}
+}
+
+func TestReconcile_MovesScheduleToTheDeclaredTaskQueue(t *testing.T) {
+	row := scheduleRow("ON", "0 9 * * *", false)
+	heavyQueue := "heavy-queue"
+	row.TaskQueue = &heavyQueue
+	store := &fakeStore{
+		catalog: db.KainosWorkflow{TaskQueue: &heavyQueue},
+		rows:    []db.ListUserWorkflowSchedulesRow{row},
+		current: map[uuid.UUID]db.ListUserWorkflowSchedulesRow{row.KainosUserWorkflow.ID: row},
+	}
+	reconciler, handle := newTestReconciler(t, store, listedEntry(t, row, "0 9 * * *", false))
+
+	var updated *client.ScheduleUpdate
+	handle.On("Update", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			options := args.Get(1).(client.ScheduleUpdateOptions)
+			updated, _ = options.DoUpdate(client.ScheduleUpdateInput{Description: *describedOn("default-queue")})
+		}).
+		Return(nil).Once()
+
+	report, err := reconciler.Reconcile(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if len(report.Drift) != 1 || report.Drift[0].Kind != DriftTaskQueue || !report.Drift[0].Fixed {
+		t.Fatalf("drift = %+v, want one fixed task queue drift", report.Drift)
+	}
+	action := updated.Schedule.Action.(*client.ScheduleWorkflowAction)
+	if action.TaskQueue != heavyQueue {
+		t.Errorf("schedule updated to task queue %s, want %s", action.TaskQueue, heavyQueue)
+	}
+}
+
+func TestReconcile_DescribesInSyncSchedulesOnce(t *testing.T) {
+	row := scheduleRow("ON", "0 9 * * *", false)
+	store := &fakeStore{rows: []db.ListUserWorkflowSchedulesRow{row}}
+	entry := listedEntry(t, row, "0 9 * * *", false)
+
+	iter := mocks.NewScheduleListIterator(t)
+	iter.On("HasNext").Return(true).Once()
+	iter.On("Next").Return(entry, nil).Once()
+	iter.On("HasNext").Return(false).Once()
+	iter.On("HasNext").Return(true).Once()
+	iter.On("Next").Return(entry, nil).Once()
+	iter.On("HasNext").Return(false).Once()
+
+	scheduleClient := mocks.NewScheduleClient(t)
+	scheduleClient.On("List", mock.Anything, mock.Anything).Return(iter, nil)
+	handle := mocks.NewScheduleHandle(t)
+	scheduleClient.On("GetHandle", mock.Anything, mock.Anything).Return(handle)
+	handle.On("Describe", mock.Anything).Return(describedOn("default-queue"), nil).Once()
+
+	cfg := &configs.AppConfig{TemporalTaskQueue: "default-queue"}
+	reconciler := NewReconciler(store, scheduleClient, NewScheduleManager(scheduleClient, NewTaskQueues(store, cfg)), cfg)
+
+	for i := 0; i < 2; i++ {
+		store.rowsListed = false
+		report, err := reconciler.Reconcile(context.Background(), false)
+		if err != nil {
+			t.Fatalf("Reconcile returned error: %v", err)
+		}
+		if len(report.Drift) != 0 {
+			t.Errorf("run %d drift = %+v, want none", i, report.Drift)
+		}
+	}
+
+	// The catalog moves to another queue: the schedule is described again and found behind
+	heavyQueue := "heavy-queue"
+	store.rows[0].TaskQueue = &heavyQueue
+	iter.On("HasNext").Return(true).Once()
+	iter.On("Next").Return(entry, nil).Once()
+	iter.On("HasNext").Return(false).Once()
+	handle.On("Describe", mock.Anything).Return(describedOn("default-queue"), nil).Once()
+
+	report, err := reconciler.Reconcile(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	if len(report.Drift) != 1 || report.Drift[0].Kind != DriftTaskQueue {
+		t.Errorf("drift = %+v, want a task queue drift", report.Drift)
+	}
+}
@@ -0,0 +1,144 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/execution/workflow"
+	"stock-agent.io/internal/types"
+)
+
+// RunRef identifies a started run
+type RunRef struct {
+	WorkflowID string
+	RunID      string
+}
+
+// Runner starts user workflows outside of their schedule
+type Runner struct {
+	temporalClient client.Client
+	taskQueues     *TaskQueues
+}
+
+func NewRunner(temporalClient client.Client, taskQueues *TaskQueues) *Runner {
+	return &Runner{
+		temporalClient: temporalClient,
+		taskQueues:     taskQueues,
+	}
+}
+
+// RunWorkflowID returns a new Temporal workflow id for an on-demand run of a user workflow
+func RunWorkflowID(userWorkflowID uuid.UUID) string {
+	return fmt.Sprintf("%s-run-%s", userWorkflowID, uuid.New())
+}
+
+// RunNow starts ExecuteMastraWorkflow right away under temporalWorkflowID, which the caller
+// reserved its execution row under. It does not go through the schedule, whose trigger
+// would pick a workflow id of its own.
+func (r *Runner) RunNow(ctx context.Context, temporalWorkflowID string, userWorkflowID, workflowID uuid.UUID, options types.WorkflowRunOptions) (RunRef, error) {
+	taskQueue, err := r.taskQueues.ForWorkflow(ctx, workflowID)
+	if err != nil {
+		return RunRef{}, err
+	}
+
+	run, err := r.temporalClient.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        temporalWorkflowID,
+		TaskQueue: taskQueue,
+		// The id is new for every run-now request, so a retried request does start a second run.
+		// Rejecting a duplicate only keeps the execution row reserved under the id bound to one run.
+		WorkflowIDReusePolicy: enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE,
+	}, workflow.ExecuteMastraWorkflowName, userWorkflowID.String(), workflowID.String(), options)
+	if err != nil {
+		return RunRef{}, fmt.Errorf("failed to start workflow: %w", err)
+	}
+
+	log.Info().
+		Str("user_workflow_id", userWorkflowID.String()).
+		Str("temporal_workflow_id", run.GetID()).
+		Str("temporal_run_id", run.GetRunID()).
+		Str("task_queue", taskQueue).
+		Msg("Workflow run started on demand")
+
+	return RunRef{WorkflowID: run.GetID(), RunID: run.GetRunID()}, nil
+}
+
+// Cancel requests cancellation of a run; ExecuteMastraWorkflow stops the Mastra call and
+// records the execution as CANCELLED with whatever output it had
+func (r *Runner) Cancel(ctx context.Context, workflowID, runID string) error {
+	if err := r.temporalClient.CancelWorkflow(ctx, workflowID, runID); err != nil {
+		return fmt.Errorf("failed to cancel workflow %s: %w", workflowID, err)
+	}
+
+	log.Info().Str("temporal_workflow_id", workflowID).Str("temporal_run_id", runID).Msg("Workflow cancellation requested")
+	return nil
+}
+
+// Terminate stops a run immediately without giving it a chance to clean up or record its outcome
+func (r *Runner) Terminate(ctx context.Context, workflowID, runID, reason string) error {
+	if err := r.temporalClient.TerminateWorkflow(ctx, workflowID, runID, reason); err != nil {
+		return fmt.Errorf("failed to terminate workflow %s: %w", workflowID, err)
+	}
+
+	log.Info().Str("temporal_workflow_id", workflowID).Str("temporal_run_id", runID).Msg("Workflow terminated")
+	return nil
+}
+
+// Progress - Where a run stands: the phase answered by its progress query and, while Mastra
+// runs, the steps reported by the CallMastraAPI heartbeats
+func (r *Runner) Progress(ctx context.Context, workflowID, runID string) (types.ExecutionProgress, error) {
+	progress := types.ExecutionProgress{CompletedSteps: []string{}}
+
+	value, err := r.temporalClient.QueryWorkflow(ctx, workflowID, runID, workflow.ProgressQueryName)
+	if err != nil {
+		return progress, fmt.Errorf("failed to query progress of workflow %s: %w", workflowID, err)
+	}
+	var workflowProgress types.WorkflowProgress
+	if err := value.Get(&workflowProgress); err != nil {
+		return progress, fmt.Errorf("failed to decode progress of workflow %s: %w", workflowID, err)
+	}
+	progress.Phase, progress.Status = workflowProgress.Phase, workflowProgress.Status
+
+	description, err := r.temporalClient.DescribeWorkflowExecution(ctx, workflowID, runID)
+	if err != nil {
+		return progress, fmt.Errorf("failed to describe workflow %s: %w", workflowID, err)
+	}
+	for _, pending := range description.GetPendingActivities() {
+		if pending.GetActivityType().GetName() != activities.CallMastraAPIName || pending.GetHeartbeatDetails() == nil {
+			continue
+		}
+		var mastraProgress types.MastraProgress
+		if err := converter.GetDefaultDataConverter().FromPayloads(pending.GetHeartbeatDetails(), &mastraProgress); err != nil {
+			// Heartbeats of workers predating the details carry none worth reporting
+			continue
+		}
+		progress.Step = mastraProgress.Step
+		if mastraProgress.CompletedSteps != nil {
+			progress.CompletedSteps = mastraProgress.CompletedSteps
+		}
+	}
+
+	return progress, nil
+}
+
+// Wait blocks until a run closes, whatever its outcome, or ctx is done
+func (r *Runner) Wait(ctx context.Context, workflowID, runID string) error {
+	err := r.temporalClient.GetWorkflow(ctx, workflowID, runID).Get(ctx, nil)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// A failed or cancelled run is closed all the same; only a run Temporal does not know is an error
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return err
+	}
+	return nil
+}
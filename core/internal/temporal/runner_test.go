@@ -0,0 +1,210 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/execution/activities"
+	"stock-agent.io/internal/execution/workflow"
+	"stock-agent.io/internal/types"
+)
+
+func newTestRunner(t *testing.T, taskQueue *string) (*Runner, *mocks.Client) {
+	t.Helper()
+
+	temporalClient := mocks.NewClient(t)
+	store := &fakeStore{catalog: db.KainosWorkflow{TaskQueue: taskQueue}}
+	taskQueues := NewTaskQueues(store, &configs.AppConfig{TemporalTaskQueue: "default-queue"})
+	return NewRunner(temporalClient, taskQueues), temporalClient
+}
+
+func TestRunNow(t *testing.T) {
+	heavyQueue := "heavy-queue"
+	tests := []struct {
+		name      string
+		taskQueue *string
+		want      string
+	}{
+		{name: "default queue", taskQueue: nil, want: "default-queue"},
+		{name: "declared queue", taskQueue: &heavyQueue, want: "heavy-queue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, temporalClient := newTestRunner(t, tt.taskQueue)
+			userWorkflowID, workflowID := uuid.New(), uuid.New()
+			temporalWorkflowID := RunWorkflowID(userWorkflowID)
+			options := types.WorkflowRunOptions{RequestedBy: "user_1"}
+
+			run := mocks.NewWorkflowRun(t)
+			run.On("GetID").Return(temporalWorkflowID)
+			run.On("GetRunID").Return("run-1")
+
+			temporalClient.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+				return o.ID == temporalWorkflowID &&
+					o.TaskQueue == tt.want &&
+					o.WorkflowIDReusePolicy == enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE
+			}), workflow.ExecuteMastraWorkflowName, userWorkflowID.String(), workflowID.String(), options).Return(run, nil)
+
+			ref, err := runner.RunNow(context.Background(), temporalWorkflowID, userWorkflowID, workflowID, options)
+			if err != nil {
+				t.Fatalf("RunNow returned error: %v", err)
+			}
+			if ref.WorkflowID != temporalWorkflowID || ref.RunID != "run-1" {
+				t.Errorf("RunNow = %+v, want %s/run-1", ref, temporalWorkflowID)
+			}
+		})
+	}
+}
+
+func TestRunNow_StartFails(t *testing.T) {
+	runner, temporalClient := newTestRunner(t, nil)
+	startErr := errors.New("unavailable")
+	temporalClient.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, startErr)
+
+	_, err := runner.RunNow(context.Background(), "wf-1", uuid.New(), uuid.New(), types.WorkflowRunOptions{})
+	if !errors.Is(err, startErr) {
+		t.Errorf("RunNow = %v, want the start error", err)
+	}
+}
+
+func TestRunWorkflowID(t *testing.T) {
+	userWorkflowID := uuid.New()
+	first, second := RunWorkflowID(userWorkflowID), RunWorkflowID(userWorkflowID)
+	if first == second {
+		t.Errorf("RunWorkflowID returned %s twice", first)
+	}
+}
+
+func payloads(t *testing.T, value interface{}) *commonpb.Payloads {
+	t.Helper()
+
+	p, err := converter.GetDefaultDataConverter().ToPayloads(value)
+	if err != nil {
+		t.Fatalf("ToPayloads returned error: %v", err)
+	}
+	return p
+}
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		name    string
+		pending []*workflowpb.PendingActivityInfo
+		want    types.ExecutionProgress
+	}{
+		{
+			name: "no activity running",
+			want: types.ExecutionProgress{Phase: "notifying", CompletedSteps: []string{}},
+		},
+		{
+			name: "mastra heartbeat",
+			pending: []*workflowpb.PendingActivityInfo{{
+				ActivityType:     &commonpb.ActivityType{Name: activities.CallMastraAPIName},
+				HeartbeatDetails: payloads(t, types.MastraProgress{Step: "analyze", CompletedSteps: []string{"fetch"}}),
+			}},
+			want: types.ExecutionProgress{Phase: "notifying", Step: "analyze", CompletedSteps: []string{"fetch"}},
+		},
+		{
+			name: "other activity and undecodable heartbeat",
+			pending: []*workflowpb.PendingActivityInfo{
+				{
+					ActivityType:     &commonpb.ActivityType{Name: "SendNotification"},
+					HeartbeatDetails: payloads(t, types.MastraProgress{Step: "ignored"}),
+				},
+				{
+					ActivityType:     &commonpb.ActivityType{Name: activities.CallMastraAPIName},
+					HeartbeatDetails: payloads(t, "not progress"),
+				},
+			},
+			want: types.ExecutionProgress{Phase: "notifying", CompletedSteps: []string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, temporalClient := newTestRunner(t, nil)
+			temporalClient.On("QueryWorkflow", mock.Anything, "wf-1", "run-1", workflow.ProgressQueryName).
+				Return(client.NewValue(payloads(t, types.WorkflowProgress{Phase: "notifying"})), nil)
+			temporalClient.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "run-1").
+				Return(&workflowservice.DescribeWorkflowExecutionResponse{PendingActivities: tt.pending}, nil)
+
+			progress, err := runner.Progress(context.Background(), "wf-1", "run-1")
+			if err != nil {
+				t.Fatalf("Progress returned error: %v", err)
+			}
+			if progress.Phase != tt.want.Phase || progress.Step != tt.want.Step ||
+				len(progress.CompletedSteps) != len(tt.want.CompletedSteps) {
+				t.Errorf("Progress = %+v, want %+v", progress, tt.want)
+			}
+			for i, step := range tt.want.CompletedSteps {
+				if progress.CompletedSteps[i] != step {
+					t.Errorf("CompletedSteps = %v, want %v", progress.CompletedSteps, tt.want.CompletedSteps)
+				}
+			}
+		})
+	}
+}
+
+func TestProgress_QueryFails(t *testing.T) {
+	runner, temporalClient := newTestRunner(t, nil)
+	queryErr := errors.New("no poller")
+	temporalClient.On("QueryWorkflow", mock.Anything, "wf-1", "run-1", workflow.ProgressQueryName).Return(nil, queryErr)
+
+	if _, err := runner.Progress(context.Background(), "wf-1", "run-1"); !errors.Is(err, queryErr) {
+		t.Errorf("Progress = %v, want the query error", err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	notFound := serviceerror.NewNotFound("workflow not found")
+	tests := []struct {
+		name    string
+		runErr  error
+		wantErr error
+	}{
+		{name: "completed", runErr: nil, wantErr: nil},
+		{name: "failed run is closed", runErr: errors.New("workflow execution error"), wantErr: nil},
+		{name: "unknown run", runErr: notFound, wantErr: notFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, temporalClient := newTestRunner(t, nil)
+			run := mocks.NewWorkflowRun(t)
+			run.On("Get", mock.Anything, nil).Return(tt.runErr)
+			temporalClient.On("GetWorkflow", mock.Anything, "wf-1", "run-1").Return(run)
+
+			if err := runner.Wait(context.Background(), "wf-1", "run-1"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWait_ContextDone(t *testing.T) {
+	runner, temporalClient := newTestRunner(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", ctx, nil).Return(context.Canceled)
+	temporalClient.On("GetWorkflow", ctx, "wf-1", "run-1").Return(run)
+
+	if err := runner.Wait(ctx, "wf-1", "run-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,189 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/execution/workflow"
+	"stock-agent.io/internal/types"
+	"stock-agent.io/utils"
+)
+
+// SchedulePrefix - Temporal schedules owned by a user workflow are named workflow-<user workflow id>
+const SchedulePrefix = "workflow-"
+
+// ErrNoSchedule is returned for user workflows with neither a cron nor a preset saved
+var ErrNoSchedule = errors.New("user workflow has no schedule")
+
+// ScheduleManager keeps the Temporal schedule of a user workflow in line with its row
+type ScheduleManager struct {
+	scheduleClient client.ScheduleClient
+	taskQueues     *TaskQueues
+}
+
+func NewScheduleManager(scheduleClient client.ScheduleClient, taskQueues *TaskQueues) *ScheduleManager {
+	return &ScheduleManager{
+		scheduleClient: scheduleClient,
+		taskQueues:     taskQueues,
+	}
+}
+
+// ScheduleID - Temporal schedule id of a user workflow
+func ScheduleID(userWorkflowID uuid.UUID) string {
+	return SchedulePrefix + userWorkflowID.String()
+}
+
+// UserWorkflowID - Inverse of ScheduleID, false for schedules not owned by a user workflow
+func UserWorkflowID(scheduleID string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(scheduleID, SchedulePrefix) {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(strings.TrimPrefix(scheduleID, SchedulePrefix))
+	return id, err == nil
+}
+
+// StoredSchedule - Resolve the preset or cron and time zone saved on a user workflow.
+// Rows scheduled before time zones existed have none and keep running in UTC.
+func StoredSchedule(userWorkflow db.KainosUserWorkflow) (*utils.Schedule, error) {
+	if userWorkflow.CronTime == nil && userWorkflow.SchedulePreset == nil {
+		return nil, ErrNoSchedule
+	}
+
+	var preset, cron string
+	if userWorkflow.SchedulePreset != nil {
+		preset = *userWorkflow.SchedulePreset
+	}
+	if userWorkflow.CronTime != nil {
+		cron = *userWorkflow.CronTime
+	}
+
+	timeZone := "UTC"
+	if userWorkflow.TimeZone != nil {
+		timeZone = *userWorkflow.TimeZone
+	}
+
+	return utils.ResolveSchedule(preset, cron, timeZone)
+}
+
+// IsActive - Whether the schedule of a user workflow should be firing
+func IsActive(userWorkflow db.KainosUserWorkflow) bool {
+	return userWorkflow.Status != nil && *userWorkflow.Status == "ON"
+}
+
+// Apply creates the schedule if missing, otherwise updates its spec and task queue in place,
+// then pauses or unpauses it to match the status. The note is kept on the schedule.
+func (m *ScheduleManager) Apply(ctx context.Context, userWorkflow db.KainosUserWorkflow, note string) error {
+	active := IsActive(userWorkflow)
+	scheduleID := ScheduleID(userWorkflow.ID)
+	handle := m.scheduleClient.GetHandle(ctx, scheduleID)
+
+	schedule, err := StoredSchedule(userWorkflow)
+	if errors.Is(err, ErrNoSchedule) && !active {
+		// Nothing to run; only make sure a leftover schedule is not firing
+		return m.pause(ctx, handle, note)
+	}
+	if err != nil {
+		return err
+	}
+
+	taskQueue, err := m.taskQueues.ForWorkflow(ctx, userWorkflow.WorkflowID)
+	if err != nil {
+		return err
+	}
+
+	description, err := handle.Describe(ctx)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		_, err = m.scheduleClient.Create(ctx, client.ScheduleOptions{
+			ID:     scheduleID,
+			Spec:   schedule.Spec(),
+			Action: m.action(userWorkflow, taskQueue),
+			Paused: !active,
+			Note:   note,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create schedule %s: %w", scheduleID, err)
+		}
+		log.Info().Str("schedule_id", scheduleID).Str("task_queue", taskQueue).Bool("paused", !active).Msg("Temporal schedule created")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe schedule %s: %w", scheduleID, err)
+	}
+
+	err = handle.Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			updated := input.Description.Schedule
+			spec := schedule.Spec()
+			updated.Spec = &spec
+			updated.Action = m.action(userWorkflow, taskQueue)
+			return &client.ScheduleUpdate{Schedule: &updated}, nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", scheduleID, err)
+	}
+
+	paused := description.Schedule.State != nil && description.Schedule.State.Paused
+	switch {
+	case active && paused:
+		if err := handle.Unpause(ctx, client.ScheduleUnpauseOptions{Note: note}); err != nil {
+			return fmt.Errorf("failed to unpause schedule %s: %w", scheduleID, err)
+		}
+	case !active && !paused:
+		if err := handle.Pause(ctx, client.SchedulePauseOptions{Note: note}); err != nil {
+			return fmt.Errorf("failed to pause schedule %s: %w", scheduleID, err)
+		}
+	}
+
+	log.Info().Str("schedule_id", scheduleID).Str("task_queue", taskQueue).Bool("paused", !active).Msg("Temporal schedule updated")
+	return nil
+}
+
+// Delete removes a schedule with its history; a missing schedule is not an error
+func (m *ScheduleManager) Delete(ctx context.Context, scheduleID string) error {
+	err := m.scheduleClient.GetHandle(ctx, scheduleID).Delete(ctx)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
+// Pause pauses the schedule of a user workflow without touching its spec; a missing schedule is not an error
+func (m *ScheduleManager) Pause(ctx context.Context, userWorkflowID uuid.UUID, note string) error {
+	scheduleID := ScheduleID(userWorkflowID)
+	if err := m.pause(ctx, m.scheduleClient.GetHandle(ctx, scheduleID), note); err != nil {
+		return fmt.Errorf("failed to pause schedule %s: %w", scheduleID, err)
+	}
+	return nil
+}
+
+func (m *ScheduleManager) pause(ctx context.Context, handle client.ScheduleHandle, note string) error {
+	err := handle.Pause(ctx, client.SchedulePauseOptions{Note: note})
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
+func (m *ScheduleManager) action(userWorkflow db.KainosUserWorkflow, taskQueue string) *client.ScheduleWorkflowAction {
+	return &client.ScheduleWorkflowAction{
+		ID:        userWorkflow.ID.String(),
+		Workflow:  workflow.ExecuteMastraWorkflowName,
+		TaskQueue: taskQueue,
+		Args: []interface{}{
+			userWorkflow.ID.String(),
+			userWorkflow.WorkflowID.String(),
+			types.WorkflowRunOptions{},
+		},
+	}
+}
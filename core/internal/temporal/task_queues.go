@@ -0,0 +1,45 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+// TaskQueues resolves the task queue a catalog workflow runs on
+type TaskQueues struct {
+	store        db.Store
+	defaultQueue string
+}
+
+func NewTaskQueues(store db.Store, cfg *configs.AppConfig) *TaskQueues {
+	return &TaskQueues{
+		store:        store,
+		defaultQueue: cfg.TemporalTaskQueue,
+	}
+}
+
+// Default - Task queue of catalog workflows that do not declare one
+func (q *TaskQueues) Default() string {
+	return q.defaultQueue
+}
+
+// Resolve - The declared task queue, or the default when none is declared
+func (q *TaskQueues) Resolve(taskQueue *string) string {
+	if taskQueue == nil || *taskQueue == "" {
+		return q.defaultQueue
+	}
+	return *taskQueue
+}
+
+// ForWorkflow - Task queue of a catalog workflow, read from its row
+func (q *TaskQueues) ForWorkflow(ctx context.Context, workflowID uuid.UUID) (string, error) {
+	catalogWorkflow, err := q.store.GetWorkflowByID(ctx, workflowID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get task queue of workflow %s: %w", workflowID, err)
+	}
+	return q.Resolve(catalogWorkflow.TaskQueue), nil
+}
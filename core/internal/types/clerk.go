@@ -1,6 +1,9 @@
 package types
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 type ClerkWebhookEvent struct {
 	Data            json.RawMessage `json:"data"`
@@ -29,6 +32,21 @@ type UserData struct {
 	Username        string         `json:"username"`
 	CreatedAt       int64          `json:"created_at"`
 	UpdatedAt       int64          `json:"updated_at"`
+	PublicMetadata  PublicMetadata `json:"public_metadata"`
+}
+
+// PublicMetadata - The part of the Clerk public metadata the backend reads
+type PublicMetadata struct {
+	Plan string `json:"plan"`
+}
+
+// PlanOrNil - The plan set in the Clerk metadata, nil when there is none
+func (u UserData) PlanOrNil() *string {
+	plan := strings.TrimSpace(u.PublicMetadata.Plan)
+	if plan == "" {
+		return nil
+	}
+	return &plan
 }
 
 type EmailAddress struct {
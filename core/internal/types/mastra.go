@@ -0,0 +1,30 @@
+package types
+
+import "encoding/json"
+
+// MastraWorkflowRequest is the body sent to the Mastra server to start a workflow run
+type MastraWorkflowRequest struct {
+	InputData json.RawMessage `json:"inputData"`
+}
+
+// MastraWorkflowResult is the outcome of a Mastra workflow run as returned by CallMastraAPI
+type MastraWorkflowResult struct {
+	MastraWorkflowID string                     `json:"mastra_workflow_id"`
+	RunID            string                     `json:"run_id"`
+	Status           string                     `json:"status"`
+	Result           json.RawMessage            `json:"result,omitempty"`
+	Steps            map[string]json.RawMessage `json:"steps,omitempty"`
+}
+
+// UnmarshalJSON also reads the plain text the first build of CallMastraAPI returned, which the
+// histories of its runs still hold; the text becomes Result
+func (r *MastraWorkflowResult) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*r = MastraWorkflowResult{Result: append(json.RawMessage(nil), data...)}
+		return nil
+	}
+
+	type plain MastraWorkflowResult
+	return json.Unmarshal(data, (*plain)(r))
+}
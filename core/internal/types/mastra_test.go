@@ -0,0 +1,44 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMastraWorkflowResult_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantStatus string
+		wantResult string
+	}{
+		{
+			name:       "mastra result",
+			data:       `{"mastra_workflow_id": "financialWorkflow", "run_id": "run-1", "status": "success", "result": {"summary": "up"}}`,
+			wantStatus: "success",
+			wantResult: `{"summary": "up"}`,
+		},
+		{
+			name:       "text of the first build",
+			data:       `"Mock result from Mastra AI"`,
+			wantResult: `"Mock result from Mastra AI"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result MastraWorkflowResult
+			if err := json.Unmarshal([]byte(tt.data), &result); err != nil {
+				t.Fatalf("Unmarshal returned error: %v", err)
+			}
+			if result.Status != tt.wantStatus || string(result.Result) != tt.wantResult {
+				t.Errorf("decoded %+v, want status %q and result %s", result, tt.wantStatus, tt.wantResult)
+			}
+		})
+	}
+
+	var result MastraWorkflowResult
+	if err := json.Unmarshal([]byte(`42`), &result); err == nil {
+		t.Error("Unmarshal of a number returned no error")
+	}
+}
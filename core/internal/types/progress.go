@@ -0,0 +1,50 @@
+package types
+
+import "time"
+
+// Phases of ExecuteMastraWorkflow answered by its progress query
+const (
+	ExecutionPhaseStarting       = "starting"
+	ExecutionPhaseResolvingInput = "resolving_input"
+	ExecutionPhaseRunning        = "running"
+	ExecutionPhaseStoringResult  = "storing_result"
+	ExecutionPhaseFinished       = "finished"
+)
+
+// Types of ExecutionStreamEvent, used as the SSE event names of the execution stream
+const (
+	StreamEventProgress = "progress"
+	StreamEventStep     = "step"
+	StreamEventToken    = "token"
+	StreamEventResult   = "result"
+)
+
+// WorkflowProgress - Answer of the progress query of ExecuteMastraWorkflow; Status is set once it finished
+type WorkflowProgress struct {
+	Phase  string `json:"phase"`
+	Status string `json:"status,omitempty"`
+}
+
+// MastraProgress - Heartbeat details of CallMastraAPI: the Mastra step running and the steps done
+type MastraProgress struct {
+	Step           string   `json:"step,omitempty"`
+	CompletedSteps []string `json:"completed_steps"`
+}
+
+// ExecutionProgress - Snapshot of a run sent first on the execution stream
+type ExecutionProgress struct {
+	Phase          string   `json:"phase"`
+	Status         string   `json:"status,omitempty"`
+	Step           string   `json:"step,omitempty"`
+	CompletedSteps []string `json:"completed_steps"`
+}
+
+// ExecutionStreamEvent - Live step transition or agent token of a run, relayed from the worker
+// running CallMastraAPI to the execution streams of every instance
+type ExecutionStreamEvent struct {
+	Type   string    `json:"type"`
+	Step   string    `json:"step,omitempty"`
+	Status string    `json:"status,omitempty"`
+	Token  string    `json:"token,omitempty"`
+	Time   time.Time `json:"time"`
+}
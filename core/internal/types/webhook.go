@@ -0,0 +1,45 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateWebhookEndpointRequest - URL receiving signed POSTs and the events it subscribes to
+type CreateWebhookEndpointRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+// UpdateWebhookEndpointRequest - Omitted fields keep their value
+type UpdateWebhookEndpointRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookEndpointResponse is a kainos_webhook_endpoint row; the secret is only shown when the endpoint is created
+type WebhookEndpointResponse struct {
+	ID        string     `json:"id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Active    bool       `json:"active"`
+	Secret    string     `json:"secret,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// WebhookDeliveryResponse is one entry of an endpoint's delivery log
+type WebhookDeliveryResponse struct {
+	ID             string          `json:"id"`
+	ExecutionID    string          `json:"execution_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	LastStatusCode *int32          `json:"last_status_code,omitempty"`
+	LastError      *string         `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload"`
+}
@@ -1,8 +1,162 @@
 package types
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type CreateUserScheduleRequest struct {
 	WorkflowType string `json:"workflow_type" binding:"required"`
 	Schedule     string `json:"schedule" binding:"required"`
 	Description  string `json:"description"`
 	TimeZone     string `json:"time_zone" default:"America/New_York"`
 }
+
+// UpdateScheduleRequest - Either a preset (e.g. "market-open", "weekdays-9am", "14:30") or a cron_time, in an IANA time_zone
+type UpdateScheduleRequest struct {
+	Preset   string `json:"preset"`
+	CronTime string `json:"cron_time"`
+	TimeZone string `json:"time_zone"`
+	Status   string `json:"status" binding:"required"` // "ON" or "OFF"
+}
+
+// SchedulePreviewRequest - Same schedule fields as UpdateScheduleRequest plus how many fire times to list
+type SchedulePreviewRequest struct {
+	Preset   string `json:"preset"`
+	CronTime string `json:"cron_time"`
+	TimeZone string `json:"time_zone"`
+	Count    int    `json:"count"`
+}
+
+type SchedulePreviewResponse struct {
+	Preset      string      `json:"preset,omitempty"`
+	CronTime    string      `json:"cron_time,omitempty"`
+	TimeZone    string      `json:"time_zone"`
+	Description string      `json:"description"`
+	NextRuns    []time.Time `json:"next_runs"`
+	Warnings    []string    `json:"warnings"`
+}
+
+// RunWorkflowRequest - Optional input replacing the user workflow's meta_data for this run only
+type RunWorkflowRequest struct {
+	Input json.RawMessage `json:"input"`
+}
+
+// WorkflowParams - A user workflow's parameters once validated against the catalog input schema,
+// with defaults filled in; this is what CallMastraAPI sends to Mastra as inputData
+type WorkflowParams map[string]interface{}
+
+// UpdateParamsRequest - New parameters for a user workflow, replacing its meta_data
+type UpdateParamsRequest struct {
+	Params json.RawMessage `json:"params" binding:"required"`
+}
+
+// CreateWorkflowInstanceRequest - A new user workflow for a catalog workflow; params default to {}
+// and the schedule to the catalog default, turned OFF
+type CreateWorkflowInstanceRequest struct {
+	WorkflowID string          `json:"workflow_id" binding:"required"`
+	Name       string          `json:"name"`
+	Params     json.RawMessage `json:"params"`
+}
+
+// CloneWorkflowRequest - Name of the copy; it defaults to the source name with " (copy)" appended
+type CloneWorkflowRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameWorkflowRequest - An empty name goes back to showing the catalog workflow name
+type RenameWorkflowRequest struct {
+	Name *string `json:"name" binding:"required"`
+}
+
+// Notification settings of a user workflow, stored in kainos_user_workflow.notify_on
+const (
+	NotifyOnAlways  = "always"
+	NotifyOnFailure = "failure"
+	NotifyOnNever   = "never"
+)
+
+// UpdateNotificationsRequest - When finished runs are emailed: always, failure (FAILED runs only) or never
+type UpdateNotificationsRequest struct {
+	NotifyOn string `json:"notify_on" binding:"required"`
+}
+
+// WorkflowRunOptions is the trailing ExecuteMastraWorkflow argument; runs started by a schedule pass the zero value
+type WorkflowRunOptions struct {
+	InputOverride json.RawMessage `json:"input_override,omitempty"`
+	RequestedBy   string          `json:"requested_by,omitempty"`
+}
+
+// Workflow execution statuses stored in kainos_workflow_execution.status
+const (
+	ExecutionStatusRunning   = "RUNNING"
+	ExecutionStatusSucceeded = "SUCCEEDED"
+	ExecutionStatusFailed    = "FAILED"
+	ExecutionStatusCancelled = "CANCELLED"
+)
+
+// WorkflowRunOutcome is what ExecuteMastraWorkflow hands to StoreWorkflowResult when a run finishes
+type WorkflowRunOutcome struct {
+	Status string                `json:"status"`
+	Result *MastraWorkflowResult `json:"result,omitempty"`
+	Error  string                `json:"error,omitempty"`
+}
+
+// UnmarshalJSON also reads the plain-text result the first worker passed to StoreWorkflowResult,
+// which its runs still in flight send as the outcome
+func (o *WorkflowRunOutcome) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*o = WorkflowRunOutcome{Status: ExecutionStatusSucceeded, Result: &MastraWorkflowResult{Result: append(json.RawMessage(nil), data...)}}
+		return nil
+	}
+
+	type plain WorkflowRunOutcome
+	return json.Unmarshal(data, (*plain)(o))
+}
+
+// WorkflowExecutionResponse is the API view of a kainos_workflow_execution row
+type WorkflowExecutionResponse struct {
+	ID                 string          `json:"id"`
+	UserWorkflowID     string          `json:"user_workflow_id"`
+	TemporalWorkflowID string          `json:"temporal_workflow_id"`
+	TemporalRunID      string          `json:"temporal_run_id"`
+	Status             string          `json:"status"`
+	StartedAt          time.Time       `json:"started_at"`
+	FinishedAt         *time.Time      `json:"finished_at,omitempty"`
+	Output             json.RawMessage `json:"output,omitempty"`
+	Error              *string         `json:"error,omitempty"`
+	Attempt            int32           `json:"attempt"`
+}
+
+// CatalogWorkflowRequest - Admin create or update of a kainos_workflow entry. On update omitted fields
+// keep their value; an empty default_preset and default_cron_time clear the default schedule and a
+// null input_schema clears the schema. An empty task_queue puts the workflow back on the default queue.
+type CatalogWorkflowRequest struct {
+	Name             *string         `json:"workflow_name"`
+	Description      *string         `json:"workflow_description"`
+	Price            *float64        `json:"price"`
+	MastraWorkflowID *string         `json:"mastra_workflow_id"`
+	DefaultPreset    *string         `json:"default_preset"`
+	DefaultCronTime  *string         `json:"default_cron_time"`
+	DefaultTimeZone  *string         `json:"default_time_zone"`
+	InputSchema      json.RawMessage `json:"input_schema"`
+	TaskQueue        *string         `json:"task_queue"`
+}
+
+// CatalogWorkflowResponse is the admin view of a kainos_workflow row; task_queue is null on the default queue
+type CatalogWorkflowResponse struct {
+	ID               string          `json:"id"`
+	Name             string          `json:"workflow_name"`
+	Description      string          `json:"workflow_description"`
+	Price            *float64        `json:"price"`
+	MastraWorkflowID *string         `json:"mastra_workflow_id"`
+	DefaultPreset    *string         `json:"default_preset"`
+	DefaultCronTime  *string         `json:"default_cron_time"`
+	DefaultTimeZone  *string         `json:"default_time_zone"`
+	InputSchema      json.RawMessage `json:"input_schema,omitempty"`
+	TaskQueue        *string         `json:"task_queue"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        *time.Time      `json:"updated_at,omitempty"`
+	DeletedAt        *time.Time      `json:"deleted_at,omitempty"`
+}
@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrBlockedAddress is returned when an endpoint resolves to an address inside our own network
+var ErrBlockedAddress = errors.New("endpoint address is not publicly routable")
+
+// Shared address space (RFC 6598), used by some clouds for their metadata service
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsBlockedAddress reports whether webhooks must not be sent to ip: loopback, private,
+// link-local (e.g. the 169.254.169.254 metadata service), multicast and unspecified addresses
+func IsBlockedAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsValid() ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// dialControl refuses connections to blocked addresses. It runs on the IP actually dialled,
+// after DNS resolution, so a hostname re-pointed at an internal address is caught as well.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil || IsBlockedAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+const (
+	// Delivery statuses of kainos_webhook_delivery
+	StatusPending   = "PENDING"
+	StatusDelivered = "DELIVERED"
+	StatusFailed    = "FAILED"
+
+	// maxDrainLength bounds how much of a response is read so its connection can be reused
+	maxDrainLength = 4096
+)
+
+// Dispatcher metrics, served with the other expvars on /api/v1/admin/debug/vars
+var (
+	dispatcherMetrics   = expvar.NewMap("webhook_dispatcher")
+	dispatcherDelivered = new(expvar.Int)
+	dispatcherRetried   = new(expvar.Int)
+	dispatcherFailed    = new(expvar.Int)
+)
+
+func init() {
+	dispatcherMetrics.Set("delivered_total", dispatcherDelivered)
+	dispatcherMetrics.Set("retried_total", dispatcherRetried)
+	dispatcherMetrics.Set("failed_total", dispatcherFailed)
+}
+
+// Dispatcher POSTs the rows of kainos_webhook_delivery to their endpoints, signed with the
+// endpoint's secret. A delivery is done once the endpoint answers 2xx; other answers are
+// retried with doubling backoff until WebhookMaxAttempts, after which it is marked FAILED.
+type Dispatcher struct {
+	store  db.Store
+	client *http.Client
+	cfg    *configs.AppConfig
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(store db.Store, cfg *configs.AppConfig) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout:   cfg.WebhookTimeout,
+			Transport: newTransport(cfg),
+			// A redirect is answered like any other non-2xx, so deliveries only go to the registered URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}
+}
+
+// newTransport dials endpoints directly, without a proxy, and unless private networks are
+// allowed refuses to connect to internal addresses
+func newTransport(cfg *configs.AppConfig) *http.Transport {
+	dialer := &net.Dialer{Timeout: cfg.WebhookTimeout, KeepAlive: 30 * time.Second}
+	if !cfg.WebhookAllowPrivateNetworks {
+		dialer.Control = dialControl
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// Start runs the dispatch loop in the background until Stop is called
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.run(ctx)
+	}()
+
+	log.Info().
+		Dur("poll_interval", d.cfg.WebhookPollInterval).
+		Int("max_attempts", d.cfg.WebhookMaxAttempts).
+		Msg("Webhook dispatcher started")
+}
+
+// Stop stops the dispatch loop and waits for the current batch to finish
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+	log.Info().Msg("Webhook dispatcher stopped")
+}
+
+func (d *Dispatcher) run(ctx context.Context) {
+	poll := time.NewTicker(d.cfg.WebhookPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+			d.DispatchBatch(ctx)
+		}
+	}
+}
+
+// DispatchBatch sends one batch of due deliveries concurrently and records the outcome of each
+func (d *Dispatcher) DispatchBatch(ctx context.Context) {
+	deliveries, err := d.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseSeconds: d.leaseSeconds(),
+		BatchSize:    int32(d.cfg.WebhookBatchSize),
+	})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Msg("Failed to claim webhook deliveries")
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery db.ClaimWebhookDeliveriesRow) {
+			defer wg.Done()
+			d.dispatch(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, delivery db.ClaimWebhookDeliveriesRow) {
+	statusCode, sendErr := d.send(ctx, delivery)
+
+	var code *int32
+	if statusCode != 0 {
+		c := int32(statusCode)
+		code = &c
+	}
+
+	if sendErr == nil {
+		err := d.store.MarkWebhookDeliveryDelivered(ctx, db.MarkWebhookDeliveryDeliveredParams{
+			LastStatusCode: code,
+			ID:             delivery.ID,
+		})
+		if err != nil {
+			// The lease expires and the delivery is sent again; receivers dedupe on the event id
+			log.Error().Err(err).Str("delivery_id", delivery.ID.String()).Msg("Failed to mark webhook delivered")
+			return
+		}
+
+		dispatcherDelivered.Add(1)
+		log.Info().
+			Str("delivery_id", delivery.ID.String()).
+			Str("event", delivery.EventType).
+			Int("status_code", statusCode).
+			Msg("Webhook delivered")
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	status := StatusPending
+	backoff := d.backoff(delivery.Attempts)
+	if int(attempts) >= d.cfg.WebhookMaxAttempts {
+		status = StatusFailed
+		backoff = 0
+		dispatcherFailed.Add(1)
+	} else {
+		dispatcherRetried.Add(1)
+	}
+
+	log.Warn().
+		Err(sendErr).
+		Str("delivery_id", delivery.ID.String()).
+		Str("event", delivery.EventType).
+		Int32("attempts", attempts).
+		Str("status", status).
+		Dur("retry_in", backoff).
+		Msg("Failed to deliver webhook")
+
+	errMsg := sendErr.Error()
+	err := d.store.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+		Status:         status,
+		LastStatusCode: code,
+		LastError:      &errMsg,
+		NextAttemptAt:  pgtype.Timestamp{Time: time.Now().UTC().Add(backoff), Valid: true},
+		ID:             delivery.ID,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("delivery_id", delivery.ID.String()).Msg("Failed to record webhook delivery failure")
+	}
+}
+
+// send POSTs the signed payload and returns the response status, 0 when no response came back
+func (d *Dispatcher) send(ctx context.Context, delivery db.ClaimWebhookDeliveriesRow) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid endpoint url: %w", err)
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Kainos-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID.String())
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// The body is never stored: it would hand whatever the endpoint answered back to its owner
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainLength))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles the retry delay with every failed attempt, up to WebhookMaxBackoff
+func (d *Dispatcher) backoff(attempts int32) time.Duration {
+	delay := d.cfg.WebhookRetryBackoff
+	for i := int32(0); i < attempts && delay < d.cfg.WebhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.WebhookMaxBackoff)
+}
+
+// leaseSeconds hides a claimed delivery from other dispatchers for longer than a request can take
+func (d *Dispatcher) leaseSeconds() int32 {
+	return int32(max(2*d.cfg.WebhookTimeout, 30*time.Second) / time.Second)
+}
@@ -0,0 +1,317 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"stock-agent.io/configs"
+	db "stock-agent.io/db/sqlc"
+)
+
+const testSecret = "whsec_test"
+
+type fakeStore struct {
+	db.Store
+
+	mu         sync.Mutex
+	due        []db.ClaimWebhookDeliveriesRow
+	deliveries map[uuid.UUID]*db.KainosWebhookDelivery
+}
+
+func newFakeStore(rows ...db.ClaimWebhookDeliveriesRow) *fakeStore {
+	f := &fakeStore{due: rows, deliveries: map[uuid.UUID]*db.KainosWebhookDelivery{}}
+	for _, row := range rows {
+		f.deliveries[row.ID] = &db.KainosWebhookDelivery{ID: row.ID, Status: StatusPending, Attempts: row.Attempts}
+	}
+	return f
+}
+
+func (f *fakeStore) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.ClaimWebhookDeliveriesRow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var claimed []db.ClaimWebhookDeliveriesRow
+	for _, row := range f.due {
+		if delivery := f.deliveries[row.ID]; delivery.Status == StatusPending {
+			row.Attempts = delivery.Attempts
+			claimed = append(claimed, row)
+		}
+	}
+	return claimed, nil
+}
+
+func (f *fakeStore) MarkWebhookDeliveryDelivered(ctx context.Context, arg db.MarkWebhookDeliveryDeliveredParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delivery := f.deliveries[arg.ID]
+	delivery.Status = StatusDelivered
+	delivery.Attempts++
+	delivery.LastStatusCode = arg.LastStatusCode
+	delivery.LastError = nil
+	return nil
+}
+
+func (f *fakeStore) MarkWebhookDeliveryFailed(ctx context.Context, arg db.MarkWebhookDeliveryFailedParams) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delivery := f.deliveries[arg.ID]
+	delivery.Status = arg.Status
+	delivery.Attempts++
+	delivery.LastStatusCode = arg.LastStatusCode
+	delivery.LastError = arg.LastError
+	delivery.NextAttemptAt = arg.NextAttemptAt
+	return nil
+}
+
+func newTestDispatcher(store db.Store) *Dispatcher {
+	return NewDispatcher(store, &configs.AppConfig{
+		WebhookBatchSize:    10,
+		WebhookTimeout:      2 * time.Second,
+		WebhookMaxAttempts:  3,
+		WebhookRetryBackoff: time.Second,
+		WebhookMaxBackoff:   time.Minute,
+		// The test receivers listen on loopback
+		WebhookAllowPrivateNetworks: true,
+	})
+}
+
+func testDelivery(t *testing.T, url string) db.ClaimWebhookDeliveriesRow {
+	t.Helper()
+
+	eventType, payload, err := NewExecutionEvent(db.KainosWorkflowExecution{
+		ID:                 uuid.New(),
+		UserWorkflowID:     uuid.New(),
+		TemporalWorkflowID: "wf-1",
+		TemporalRunID:      "run-1",
+		Status:             "SUCCEEDED",
+		Output:             []byte(`{"result":{"summary":"ok"}}`),
+		StartedAt:          pgtype.Timestamp{Time: time.Now().Add(-time.Minute), Valid: true},
+		FinishedAt:         pgtype.Timestamp{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("NewExecutionEvent: %v", err)
+	}
+
+	return db.ClaimWebhookDeliveriesRow{
+		ID:        uuid.New(),
+		EventType: eventType,
+		Payload:   payload,
+		Url:       url,
+		Secret:    testSecret,
+	}
+}
+
+func TestDispatchBatch_SignsAndDelivers(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery(t, receiver.URL)
+	store := newFakeStore(delivery)
+
+	newTestDispatcher(store).DispatchBatch(context.Background())
+
+	got := <-requests
+	if err := Verify(testSecret, got.header.Get(HeaderSignature), got.body, 5*time.Minute, time.Now()); err != nil {
+		t.Fatalf("signature does not verify: %v", err)
+	}
+	if got.header.Get(HeaderEvent) != EventWorkflowCompleted {
+		t.Errorf("event header = %q, want %q", got.header.Get(HeaderEvent), EventWorkflowCompleted)
+	}
+	if got.header.Get(HeaderDelivery) != delivery.ID.String() {
+		t.Errorf("delivery header = %q, want %q", got.header.Get(HeaderDelivery), delivery.ID)
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.Type != EventWorkflowCompleted || payload.Data.Status != "SUCCEEDED" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	result := store.deliveries[delivery.ID]
+	if result.Status != StatusDelivered || result.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts, want DELIVERED after 1", result.Status, result.Attempts)
+	}
+	if result.LastStatusCode == nil || *result.LastStatusCode != http.StatusNoContent {
+		t.Errorf("last status code = %v, want 204", result.LastStatusCode)
+	}
+}
+
+func TestDispatchBatch_RetriesWithBackoffThenFails(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery(t, receiver.URL)
+	store := newFakeStore(delivery)
+	dispatcher := newTestDispatcher(store)
+
+	// Each attempt pushes the next one out twice as far
+	var lastDelay time.Duration
+	for attempt := 1; attempt < 3; attempt++ {
+		before := time.Now()
+		dispatcher.DispatchBatch(context.Background())
+
+		result := store.deliveries[delivery.ID]
+		if result.Status != StatusPending || int(result.Attempts) != attempt {
+			t.Fatalf("attempt %d: delivery = %s after %d attempts", attempt, result.Status, result.Attempts)
+		}
+		delay := result.NextAttemptAt.Time.Sub(before)
+		if delay <= lastDelay {
+			t.Errorf("attempt %d: retry in %s, want more than %s", attempt, delay, lastDelay)
+		}
+		lastDelay = delay
+	}
+
+	dispatcher.DispatchBatch(context.Background())
+
+	result := store.deliveries[delivery.ID]
+	if result.Status != StatusFailed || result.Attempts != 3 {
+		t.Fatalf("delivery = %s after %d attempts, want FAILED after 3", result.Status, result.Attempts)
+	}
+	if result.LastStatusCode == nil || *result.LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("last status code = %v, want 503", result.LastStatusCode)
+	}
+	if result.LastError == nil || *result.LastError != "endpoint answered 503" {
+		t.Errorf("last error = %v", result.LastError)
+	}
+
+	// A FAILED delivery is not claimed again
+	dispatcher.DispatchBatch(context.Background())
+	if result.Attempts != 3 {
+		t.Errorf("failed delivery was sent again")
+	}
+}
+
+func TestDispatchBatch_DoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect was followed")
+	}))
+	defer target.Close()
+
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	delivery := testDelivery(t, receiver.URL)
+	store := newFakeStore(delivery)
+
+	newTestDispatcher(store).DispatchBatch(context.Background())
+
+	result := store.deliveries[delivery.ID]
+	if result.Status != StatusPending || result.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts, want PENDING after 1", result.Status, result.Attempts)
+	}
+}
+
+func TestDispatchBatch_RefusesInternalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivery reached a loopback endpoint")
+	}))
+	defer receiver.Close()
+
+	delivery := testDelivery(t, receiver.URL)
+	store := newFakeStore(delivery)
+
+	dispatcher := newTestDispatcher(store)
+	dispatcher.client.Transport = newTransport(&configs.AppConfig{WebhookTimeout: time.Second})
+	dispatcher.DispatchBatch(context.Background())
+
+	result := store.deliveries[delivery.ID]
+	if result.Status != StatusPending || result.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts, want PENDING after 1", result.Status, result.Attempts)
+	}
+	if result.LastStatusCode != nil {
+		t.Errorf("last status code = %v, want none", *result.LastStatusCode)
+	}
+	if result.LastError == nil || !strings.Contains(*result.LastError, ErrBlockedAddress.Error()) {
+		t.Errorf("last error = %v, want the address to be refused", result.LastError)
+	}
+}
+
+func TestIsBlockedAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"224.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsBlockedAddress(netip.MustParseAddr(tt.addr)); got != tt.blocked {
+			t.Errorf("IsBlockedAddress(%s) = %v, want %v", tt.addr, got, tt.blocked)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(testSecret, now, body)
+
+	if err := Verify(testSecret, header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := Verify("whsec_other", header, body, 5*time.Minute, now); err != ErrSignatureMismatch {
+		t.Errorf("other secret: err = %v, want %v", err, ErrSignatureMismatch)
+	}
+	if err := Verify(testSecret, header, []byte(`{"id":"2"}`), 5*time.Minute, now); err != ErrSignatureMismatch {
+		t.Errorf("tampered body: err = %v, want %v", err, ErrSignatureMismatch)
+	}
+	if err := Verify(testSecret, header, body, 5*time.Minute, now.Add(10*time.Minute)); err != ErrSignatureExpired {
+		t.Errorf("old timestamp: err = %v, want %v", err, ErrSignatureExpired)
+	}
+	if err := Verify(testSecret, "v1=abc", body, 0, now); err != ErrInvalidSignatureHeader {
+		t.Errorf("missing timestamp: err = %v, want %v", err, ErrInvalidSignatureHeader)
+	}
+}
+
+func TestEventTypeFor(t *testing.T) {
+	cases := map[string]string{
+		"SUCCEEDED": EventWorkflowCompleted,
+		"CANCELLED": EventWorkflowCompleted,
+		"FAILED":    EventWorkflowFailed,
+	}
+	for status, want := range cases {
+		if got := EventTypeFor(status); got != want {
+			t.Errorf("EventTypeFor(%s) = %s, want %s", status, got, want)
+		}
+	}
+}
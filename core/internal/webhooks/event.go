@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	db "stock-agent.io/db/sqlc"
+	"stock-agent.io/internal/types"
+)
+
+// Event types an endpoint can subscribe to
+const (
+	EventWorkflowCompleted = "workflow.completed"
+	EventWorkflowFailed    = "workflow.failed"
+)
+
+// EventTypes lists every event type an endpoint can subscribe to
+var EventTypes = []string{EventWorkflowCompleted, EventWorkflowFailed}
+
+// Payload is the JSON body POSTed to webhook endpoints
+type Payload struct {
+	// ID is the same for every delivery and redelivery of the event, so receivers can dedupe on it
+	ID        string        `json:"id"`
+	Type      string        `json:"type"`
+	CreatedAt time.Time     `json:"created_at"`
+	Data      ExecutionData `json:"data"`
+}
+
+// ExecutionData describes the finished execution an event is about
+type ExecutionData struct {
+	ExecutionID        string          `json:"execution_id"`
+	UserWorkflowID     string          `json:"user_workflow_id"`
+	TemporalWorkflowID string          `json:"temporal_workflow_id"`
+	TemporalRunID      string          `json:"temporal_run_id"`
+	Status             string          `json:"status"`
+	Output             json.RawMessage `json:"output,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	Attempt            int32           `json:"attempt"`
+	StartedAt          time.Time       `json:"started_at"`
+	FinishedAt         time.Time       `json:"finished_at"`
+}
+
+// EventTypeFor maps an execution status to its event type; a cancelled run counts as completed
+func EventTypeFor(status string) string {
+	if status == types.ExecutionStatusFailed {
+		return EventWorkflowFailed
+	}
+	return EventWorkflowCompleted
+}
+
+// IsEventType reports whether endpoints can subscribe to eventType
+func IsEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NewExecutionEvent builds the event of a finished execution; it fits FinishWorkflowExecutionTxParams.WebhookEvent
+func NewExecutionEvent(execution db.KainosWorkflowExecution) (string, []byte, error) {
+	eventType := EventTypeFor(execution.Status)
+
+	payload := Payload{
+		ID:        uuid.NewSHA1(uuid.NameSpaceURL, []byte(eventType+":"+execution.ID.String())).String(),
+		Type:      eventType,
+		CreatedAt: execution.FinishedAt.Time.UTC(),
+		Data: ExecutionData{
+			ExecutionID:        execution.ID.String(),
+			UserWorkflowID:     execution.UserWorkflowID.String(),
+			TemporalWorkflowID: execution.TemporalWorkflowID,
+			TemporalRunID:      execution.TemporalRunID,
+			Status:             execution.Status,
+			Output:             execution.Output,
+			Attempt:            execution.Attempt,
+			StartedAt:          execution.StartedAt.Time.UTC(),
+			FinishedAt:         execution.FinishedAt.Time.UTC(),
+		},
+	}
+	if execution.Error != nil {
+		payload.Data.Error = *execution.Error
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	return eventType, body, nil
+}
@@ -94,45 +94,81 @@ func (c *Client) ExecuteWithCB(ctx context.Context, req *resty.Request) (*resty.
 
 // Convenience methods - FIXED VERSION
 func (c *Client) Get(ctx context.Context, url string) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx)
+	req := c.newRequest(ctx, resty.MethodGet, url)
 	return c.ExecuteWithCB(ctx, req.SetDoNotParseResponse(false).SetResult(nil))
 }
 
 func (c *Client) GetWithResult(ctx context.Context, url string, result interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetResult(result)
+	req := c.newRequest(ctx, resty.MethodGet, url).SetResult(result)
 	return c.ExecuteWithCB(ctx, req)
 }
 
 func (c *Client) Post(ctx context.Context, url string, body interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetBody(body)
+	req := c.newRequest(ctx, resty.MethodPost, url).SetBody(body)
 	return c.ExecuteWithCB(ctx, req)
 }
 
 func (c *Client) PostWithResult(ctx context.Context, url string, body interface{}, result interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetBody(body).SetResult(result)
+	req := c.newRequest(ctx, resty.MethodPost, url).SetBody(body).SetResult(result)
 	return c.ExecuteWithCB(ctx, req)
 }
 
+// PostStream posts body and leaves the response unread so it can be consumed as it arrives.
+// The caller must close RawBody() of the returned response.
+func (c *Client) PostStream(ctx context.Context, url string, body interface{}) (*resty.Response, error) {
+	req := c.newRequest(ctx, resty.MethodPost, url).SetBody(body).SetDoNotParseResponse(true)
+
+	result, err := c.circuitBreaker.Execute(func() (interface{}, error) {
+		resp, err := req.Execute(req.Method, req.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		// Consider 5xx errors as failures for circuit breaker
+		if resp.StatusCode() >= 500 {
+			resp.RawBody().Close()
+			return nil, fmt.Errorf("server error: %d", resp.StatusCode())
+		}
+
+		return resp, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*resty.Response), nil
+}
+
 func (c *Client) Put(ctx context.Context, url string, body interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetBody(body)
+	req := c.newRequest(ctx, resty.MethodPut, url).SetBody(body)
 	return c.ExecuteWithCB(ctx, req)
 }
 
 func (c *Client) PutWithResult(ctx context.Context, url string, body interface{}, result interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetBody(body).SetResult(result)
+	req := c.newRequest(ctx, resty.MethodPut, url).SetBody(body).SetResult(result)
 	return c.ExecuteWithCB(ctx, req)
 }
 
 func (c *Client) Delete(ctx context.Context, url string) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx)
+	req := c.newRequest(ctx, resty.MethodDelete, url)
 	return c.ExecuteWithCB(ctx, req)
 }
 
 func (c *Client) DeleteWithResult(ctx context.Context, url string, result interface{}) (*resty.Response, error) {
-	req := c.client.R().SetContext(ctx).SetResult(result)
+	req := c.newRequest(ctx, resty.MethodDelete, url).SetResult(result)
 	return c.ExecuteWithCB(ctx, req)
 }
 
+// newRequest builds a request with its method and URL set, since ExecuteWithCB
+// executes req.Method against req.URL
+func (c *Client) newRequest(ctx context.Context, method, url string) *resty.Request {
+	req := c.client.R().SetContext(ctx)
+	req.Method = method
+	req.URL = url
+	return req
+}
+
 // GetCircuitBreakerState returns the current state of the circuit breaker
 func (c *Client) GetCircuitBreakerState() gobreaker.State {
 	return c.circuitBreaker.State()
@@ -154,3 +190,9 @@ func (c *Client) SetHeaders(headers map[string]string) *Client {
 	c.client.SetHeaders(headers)
 	return c
 }
+
+// SetTimeout sets the per-request timeout for all requests
+func (c *Client) SetTimeout(timeout time.Duration) *Client {
+	c.client.SetTimeout(timeout)
+	return c
+}
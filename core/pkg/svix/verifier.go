@@ -0,0 +1,137 @@
+package svix
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID        = "svix-id"
+	HeaderTimestamp = "svix-timestamp"
+	HeaderSignature = "svix-signature"
+
+	// DefaultTolerance is how far the svix-timestamp may drift from the local clock
+	DefaultTolerance = 5 * time.Minute
+
+	secretPrefix     = "whsec_"
+	signatureVersion = "v1"
+)
+
+var (
+	ErrMissingHeaders    = errors.New("missing svix headers")
+	ErrInvalidTimestamp  = errors.New("invalid svix timestamp")
+	ErrTimestampTooOld   = errors.New("svix timestamp is too old")
+	ErrTimestampTooNew   = errors.New("svix timestamp is too new")
+	ErrNoMatchingSecret  = errors.New("no matching signature found")
+	ErrNoSecretsProvided = errors.New("at least one svix secret is required")
+)
+
+// Verifier checks Svix webhook signatures. It accepts several secrets so that
+// a rotated secret and its replacement are both valid during the rollover.
+type Verifier struct {
+	keys      [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+func NewVerifier(secrets ...string) (*Verifier, error) {
+	keys := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, secretPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid svix secret: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, ErrNoSecretsProvided
+	}
+
+	return &Verifier{
+		keys:      keys,
+		tolerance: DefaultTolerance,
+		now:       time.Now,
+	}, nil
+}
+
+// WithTolerance overrides DefaultTolerance
+func (v *Verifier) WithTolerance(tolerance time.Duration) *Verifier {
+	v.tolerance = tolerance
+	return v
+}
+
+// Verify checks the svix-id, svix-timestamp and svix-signature headers against the raw body
+func (v *Verifier) Verify(payload []byte, headers http.Header) error {
+	msgID := headers.Get(HeaderID)
+	msgTimestamp := headers.Get(HeaderTimestamp)
+	msgSignature := headers.Get(HeaderSignature)
+	if msgID == "" || msgTimestamp == "" || msgSignature == "" {
+		return ErrMissingHeaders
+	}
+
+	if err := v.verifyTimestamp(msgTimestamp); err != nil {
+		return err
+	}
+
+	signedContent := []byte(fmt.Sprintf("%s.%s.%s", msgID, msgTimestamp, payload))
+
+	// The header holds space separated "<version>,<base64 signature>" entries,
+	// one per active secret on the sender's side
+	for _, versioned := range strings.Split(msgSignature, " ") {
+		version, signature, found := strings.Cut(versioned, ",")
+		if !found || version != signatureVersion {
+			continue
+		}
+
+		expected, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+
+		for _, key := range v.keys {
+			if hmac.Equal(sign(key, signedContent), expected) {
+				return nil
+			}
+		}
+	}
+
+	return ErrNoMatchingSecret
+}
+
+func (v *Verifier) verifyTimestamp(header string) error {
+	seconds, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	timestamp := time.Unix(seconds, 0)
+	now := v.now()
+
+	if now.Sub(timestamp) > v.tolerance {
+		return ErrTimestampTooOld
+	}
+	if timestamp.Sub(now) > v.tolerance {
+		return ErrTimestampTooNew
+	}
+
+	return nil
+}
+
+func sign(key, content []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,179 @@
+package svix
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	oldKey = []byte("old-secret-key-0123456789abcdef")
+	newKey = []byte("new-secret-key-0123456789abcdef")
+)
+
+func secret(key []byte) string {
+	return secretPrefix + base64.StdEncoding.EncodeToString(key)
+}
+
+func signature(key []byte, msgID string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s.%d.%s", msgID, timestamp.Unix(), payload)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signedHeaders(timestamp time.Time, signatures string) http.Header {
+	headers := http.Header{}
+	headers.Set(HeaderID, "msg_1")
+	headers.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	headers.Set(HeaderSignature, signatures)
+	return headers
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	payload := []byte(`{"type":"user.created"}`)
+
+	cases := []struct {
+		name    string
+		secrets []string
+		headers http.Header
+		payload []byte
+		want    error
+	}{
+		{
+			name:    "valid",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now, signature(oldKey, "msg_1", now, payload)),
+			payload: payload,
+		},
+		{
+			name:    "tampered payload",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now, signature(oldKey, "msg_1", now, payload)),
+			payload: []byte(`{"type":"user.deleted"}`),
+			want:    ErrNoMatchingSecret,
+		},
+		{
+			name:    "wrong secret",
+			secrets: []string{secret(newKey)},
+			headers: signedHeaders(now, signature(oldKey, "msg_1", now, payload)),
+			payload: payload,
+			want:    ErrNoMatchingSecret,
+		},
+		{
+			name:    "unknown signature version",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now, "v2,"+signature(oldKey, "msg_1", now, payload)[3:]),
+			payload: payload,
+			want:    ErrNoMatchingSecret,
+		},
+		{
+			name:    "within tolerance",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now.Add(-4*time.Minute), signature(oldKey, "msg_1", now.Add(-4*time.Minute), payload)),
+			payload: payload,
+		},
+		{
+			name:    "too old",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now.Add(-6*time.Minute), signature(oldKey, "msg_1", now.Add(-6*time.Minute), payload)),
+			payload: payload,
+			want:    ErrTimestampTooOld,
+		},
+		{
+			name:    "too new",
+			secrets: []string{secret(oldKey)},
+			headers: signedHeaders(now.Add(6*time.Minute), signature(oldKey, "msg_1", now.Add(6*time.Minute), payload)),
+			payload: payload,
+			want:    ErrTimestampTooNew,
+		},
+		{
+			name:    "invalid timestamp",
+			secrets: []string{secret(oldKey)},
+			headers: func() http.Header {
+				headers := signedHeaders(now, signature(oldKey, "msg_1", now, payload))
+				headers.Set(HeaderTimestamp, "yesterday")
+				return headers
+			}(),
+			payload: payload,
+			want:    ErrInvalidTimestamp,
+		},
+		{
+			name:    "missing headers",
+			secrets: []string{secret(oldKey)},
+			headers: http.Header{},
+			payload: payload,
+			want:    ErrMissingHeaders,
+		},
+		// While a secret is rotated Svix signs with both, and we may only know one of them
+		{
+			name:    "rotation, sender signs with both",
+			secrets: []string{secret(newKey)},
+			headers: signedHeaders(now, signature(oldKey, "msg_1", now, payload)+" "+signature(newKey, "msg_1", now, payload)),
+			payload: payload,
+		},
+		{
+			name:    "rotation, receiver knows both",
+			secrets: []string{secret(newKey), secret(oldKey)},
+			headers: signedHeaders(now, signature(oldKey, "msg_1", now, payload)),
+			payload: payload,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier, err := NewVerifier(tc.secrets...)
+			if err != nil {
+				t.Fatalf("NewVerifier returned error: %v", err)
+			}
+			verifier.now = func() time.Time { return now }
+
+			if err := verifier.Verify(tc.payload, tc.headers); !errors.Is(err, tc.want) {
+				t.Errorf("Verify = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerify_WithTolerance(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	payload := []byte(`{}`)
+	sent := now.Add(-10 * time.Minute)
+
+	verifier, err := NewVerifier(secret(oldKey))
+	if err != nil {
+		t.Fatalf("NewVerifier returned error: %v", err)
+	}
+	verifier.WithTolerance(15 * time.Minute).now = func() time.Time { return now }
+
+	if err := verifier.Verify(payload, signedHeaders(sent, signature(oldKey, "msg_1", sent, payload))); err != nil {
+		t.Errorf("Verify = %v, want nil within the wider tolerance", err)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	cases := []struct {
+		name    string
+		secrets []string
+		wantErr bool
+	}{
+		{"prefixed", []string{secret(oldKey)}, false},
+		{"unprefixed", []string{base64.StdEncoding.EncodeToString(oldKey)}, false},
+		{"blanks skipped", []string{" ", secret(oldKey), ""}, false},
+		{"none", []string{""}, true},
+		{"not base64", []string{"whsec_not base64!"}, true},
+	}
+
+	for _, tc := range cases {
+		_, err := NewVerifier(tc.secrets...)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: NewVerifier error = %v, want error %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
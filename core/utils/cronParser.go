@@ -7,6 +7,8 @@ import (
 	"go.temporal.io/sdk/client"
 )
 
+var everyHour = []client.ScheduleRange{{Start: 0, End: 23}}
+
 func ParseHumanFriendlySchedule(schedule string) (*client.ScheduleCalendarSpec, error) {
 	defaultSpec := &client.ScheduleCalendarSpec{
 		Hour:   []client.ScheduleRange{{Start: 9}},
@@ -36,16 +38,20 @@ func ParseHumanFriendlySchedule(schedule string) (*client.ScheduleCalendarSpec,
 			Minute:    []client.ScheduleRange{{Start: 0}},
 			DayOfWeek: []client.ScheduleRange{{Start: 1}, {Start: 2}, {Start: 3}, {Start: 4}, {Start: 5}}, // Mon-Fri
 		}, nil
+	// An unset Hour means hour 0 to Temporal, so sub-daily presets list every hour
 	case "hourly":
 		return &client.ScheduleCalendarSpec{
+			Hour:   everyHour,
 			Minute: []client.ScheduleRange{{Start: 0}},
 		}, nil
 	case "every-30-minutes":
 		return &client.ScheduleCalendarSpec{
+			Hour:   everyHour,
 			Minute: []client.ScheduleRange{{Start: 0}, {Start: 30}},
 		}, nil
 	case "every-15-minutes":
 		return &client.ScheduleCalendarSpec{
+			Hour:   everyHour,
 			Minute: []client.ScheduleRange{{Start: 0}, {Start: 15}, {Start: 30}, {Start: 45}},
 		}, nil
 	case "weekly-monday-9am":
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InputSchema - The JSON Schema subset catalog workflows use to describe their parameters:
+// type, properties, required, additionalProperties, enum, default, minimum/maximum,
+// minLength/maxLength, pattern, items, minItems/maxItems and uniqueItems. The annotations
+// title, description, examples, $schema, $id and $comment are accepted and ignored; any other
+// keyword, such as format or oneOf, is rejected when a schema is saved.
+type InputSchema struct {
+	Type                 string                  `json:"type"`
+	Title                string                  `json:"title,omitempty"`
+	Description          string                  `json:"description,omitempty"`
+	Examples             []interface{}           `json:"examples,omitempty"`
+	Dialect              string                  `json:"$schema,omitempty"`
+	ID                   string                  `json:"$id,omitempty"`
+	Comment              string                  `json:"$comment,omitempty"`
+	Properties           map[string]*InputSchema `json:"properties,omitempty"`
+	Required             []string                `json:"required,omitempty"`
+	AdditionalProperties *bool                   `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}           `json:"enum,omitempty"`
+	Default              interface{}             `json:"default,omitempty"`
+	Minimum              *float64                `json:"minimum,omitempty"`
+	Maximum              *float64                `json:"maximum,omitempty"`
+	MinLength            *int                    `json:"minLength,omitempty"`
+	MaxLength            *int                    `json:"maxLength,omitempty"`
+	Pattern              string                  `json:"pattern,omitempty"`
+	Items                *InputSchema            `json:"items,omitempty"`
+	MinItems             *int                    `json:"minItems,omitempty"`
+	MaxItems             *int                    `json:"maxItems,omitempty"`
+	UniqueItems          bool                    `json:"uniqueItems,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// FieldError - One validation failure; Field is a path such as "tickers[2]" or "risk.level"
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true,
+}
+
+// ParseInputSchema parses and checks a stored catalog input schema. The top level must be an
+// object schema, since its value is sent to Mastra as inputData. Unknown keywords are ignored
+// so schemas saved before CheckInputSchema rejected them keep working.
+func ParseInputSchema(raw []byte) (*InputSchema, error) {
+	return parseInputSchema(raw, false)
+}
+
+// CheckInputSchema is ParseInputSchema for a schema about to be saved; it also rejects keywords
+// outside the supported subset, which would otherwise look enforced without being checked
+func CheckInputSchema(raw []byte) error {
+	_, err := parseInputSchema(raw, true)
+	return err
+}
+
+func parseInputSchema(raw []byte, strict bool) (*InputSchema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	var schema InputSchema
+	if err := decoder.Decode(&schema); err != nil {
+		if keyword, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return nil, fmt.Errorf("input_schema: unsupported keyword %s", keyword)
+		}
+		return nil, fmt.Errorf("input_schema is not a valid schema: %w", err)
+	}
+	if schema.Type != "object" {
+		return nil, errors.New(`input_schema must have "type": "object"`)
+	}
+	if err := schema.compile("input_schema"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+func (s *InputSchema) compile(path string) error {
+	if !schemaTypes[s.Type] {
+		return fmt.Errorf("%s: unsupported type %q", path, s.Type)
+	}
+
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", path, err)
+		}
+		s.pattern = pattern
+	}
+
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return fmt.Errorf("%s: required property %q is not defined", path, name)
+		}
+	}
+
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%s.%s: schema is empty", path, name)
+		}
+		if err := property.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+
+	if s.Type == "array" {
+		if s.Items == nil {
+			return fmt.Errorf("%s: arrays need an items schema", path)
+		}
+		if err := s.Items.compile(path + "[]"); err != nil {
+			return err
+		}
+	}
+
+	// A default that fails its own schema would make every run without the field invalid
+	if s.Default != nil {
+		if errs := s.validate(path, s.Default); len(errs) > 0 {
+			return fmt.Errorf("%s: default %s", path, errs[0].Message)
+		}
+	}
+	return nil
+}
+
+// Validate checks input against the schema and returns it with defaults filled in.
+// Errors are sorted by field so responses are stable.
+func (s *InputSchema) Validate(input []byte) (map[string]interface{}, []FieldError) {
+	if len(bytes.TrimSpace(input)) == 0 {
+		input = []byte("{}")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, []FieldError{{Field: "input", Message: "must be valid JSON"}}
+	}
+
+	value = s.applyDefaults(value)
+	if errs := s.validate("", value); len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+		return nil, errs
+	}
+
+	return value.(map[string]interface{}), nil
+}
+
+func (s *InputSchema) applyDefaults(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, property := range s.Properties {
+			if current, ok := v[name]; ok {
+				v[name] = property.applyDefaults(current)
+			} else if property.Default != nil {
+				v[name] = property.Default
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i := range v {
+				v[i] = s.Items.applyDefaults(v[i])
+			}
+		}
+	}
+	return value
+}
+
+func (s *InputSchema) validate(path string, value interface{}) []FieldError {
+	field := path
+	if field == "" {
+		field = "input"
+	}
+	fail := func(format string, args ...interface{}) []FieldError {
+		return []FieldError{{Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		return fail("must be one of: %s", describeValues(s.Enum))
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fail("must be an object")
+		}
+		return s.validateObject(path, object)
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fail("must be an array")
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			return fail("must have at most %d items", *s.MaxItems)
+		}
+		var errs []FieldError
+		for i, item := range items {
+			if s.UniqueItems && containsValue(items[:i], item) {
+				errs = append(errs, FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Message: "is a duplicate"})
+				continue
+			}
+			errs = append(errs, s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item)...)
+		}
+		return errs
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		length := len([]rune(text))
+		if s.MinLength != nil && length < *s.MinLength {
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(text) {
+			return fail("must match pattern %s", s.Pattern)
+		}
+
+	case "number", "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fail("must be a number")
+		}
+		n, err := number.Float64()
+		if err != nil {
+			return fail("must be a number")
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return fail("must be an integer")
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return fail("must be at least %s", formatNumber(*s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			return fail("must be at most %s", formatNumber(*s.Maximum))
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be true or false")
+		}
+	}
+
+	return nil
+}
+
+func (s *InputSchema) validateObject(path string, object map[string]interface{}) []FieldError {
+	var errs []FieldError
+	child := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, FieldError{Field: child(name), Message: "is required"})
+		}
+	}
+
+	for name, value := range object {
+		property, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, FieldError{Field: child(name), Message: "is not allowed"})
+			}
+			continue
+		}
+		errs = append(errs, property.validate(child(name), value)...)
+	}
+
+	return errs
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if equalValues(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalValues compares decoded JSON, treating 1 and 1.0 as the same number
+func equalValues(a, b interface{}) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, errA := an.Float64()
+		bf, errB := bn.Float64()
+		return errA == nil && errB == nil && af == bf
+	}
+
+	aj, errA := json.Marshal(a)
+	bj, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(aj, bj)
+}
+
+func describeValues(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if text, ok := v.(string); ok {
+			parts = append(parts, text)
+			continue
+		}
+		encoded, _ := json.Marshal(v)
+		parts = append(parts, string(encoded))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatNumber(n float64) string {
+	return fmt.Sprintf("%g", n)
+}
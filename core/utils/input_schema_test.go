@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testInputSchema = `{
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["tickers"],
+	"properties": {
+		"tickers": {"type": "array", "minItems": 1, "maxItems": 3, "uniqueItems": true,
+			"items": {"type": "string", "pattern": "^[A-Z]{1,5}$"}},
+		"risk_level": {"type": "string", "enum": ["low", "medium", "high"], "default": "medium"},
+		"report_depth": {"type": "integer", "minimum": 1, "maximum": 5, "default": 2}
+	}
+}`
+
+func TestParseInputSchema(t *testing.T) {
+	if _, err := ParseInputSchema([]byte(testInputSchema)); err != nil {
+		t.Fatalf("ParseInputSchema returned error: %v", err)
+	}
+
+	invalid := map[string]string{
+		"not an object":     `{"type": "string"}`,
+		"unknown type":      `{"type": "object", "properties": {"a": {"type": "date"}}}`,
+		"undefined require": `{"type": "object", "required": ["a"]}`,
+		"bad pattern":       `{"type": "object", "properties": {"a": {"type": "string", "pattern": "("}}}`,
+		"bad default":       `{"type": "object", "properties": {"a": {"type": "integer", "maximum": 3, "default": 9}}}`,
+		"array no items":    `{"type": "object", "properties": {"a": {"type": "array"}}}`,
+	}
+	for name, raw := range invalid {
+		if _, err := ParseInputSchema([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckInputSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "supported subset", raw: testInputSchema},
+		{name: "annotations", raw: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "$id": "report", "$comment": "v2",
+			"type": "object", "title": "Report", "properties": {"a": {"type": "string", "description": "A", "examples": ["x"]}}}`},
+		{name: "format", raw: `{"type": "object", "properties": {"a": {"type": "string", "format": "email"}}}`, wantErr: `unsupported keyword "format"`},
+		{name: "oneOf", raw: `{"type": "object", "oneOf": [{"required": ["a"]}]}`, wantErr: `unsupported keyword "oneOf"`},
+		{name: "in array items", raw: `{"type": "object", "properties": {"a": {"type": "array", "items": {"type": "number", "multipleOf": 2}}}}`, wantErr: `unsupported keyword "multipleOf"`},
+		{name: "still checks the schema", raw: `{"type": "string"}`, wantErr: `"type": "object"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckInputSchema([]byte(tt.raw))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckInputSchema returned error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckInputSchema = %v, want an error containing %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseInputSchema_IgnoresUnknownKeywords(t *testing.T) {
+	// Schemas saved before CheckInputSchema existed keep working
+	raw := `{"type": "object", "properties": {"a": {"type": "string", "format": "email"}}}`
+	if _, err := ParseInputSchema([]byte(raw)); err != nil {
+		t.Errorf("ParseInputSchema returned error: %v", err)
+	}
+}
+
+func TestInputSchemaValidate(t *testing.T) {
+	schema, err := ParseInputSchema([]byte(testInputSchema))
+	if err != nil {
+		t.Fatalf("ParseInputSchema returned error: %v", err)
+	}
+
+	params, errs := schema.Validate([]byte(`{"tickers": ["AAPL", "MSFT"]}`))
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors %+v", errs)
+	}
+	encoded, _ := json.Marshal(params)
+	if string(encoded) != `{"report_depth":2,"risk_level":"medium","tickers":["AAPL","MSFT"]}` {
+		t.Errorf("defaults not applied, got %s", encoded)
+	}
+
+	_, errs = schema.Validate([]byte(`{"tickers": ["AAPL", "aapl", "AAPL"], "risk_level": "extreme", "report_depth": 2.5, "color": "red"}`))
+	want := []FieldError{
+		{Field: "color", Message: "is not allowed"},
+		{Field: "report_depth", Message: "must be an integer"},
+		{Field: "risk_level", Message: "must be one of: low, medium, high"},
+		{Field: "tickers[1]", Message: "must match pattern ^[A-Z]{1,5}$"},
+		{Field: "tickers[2]", Message: "is a duplicate"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), errs)
+	}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("error %d: expected %+v, got %+v", i, want[i], errs[i])
+		}
+	}
+
+	if _, errs = schema.Validate(nil); len(errs) != 1 || errs[0].Field != "tickers" || errs[0].Message != "is required" {
+		t.Errorf("expected tickers to be required, got %+v", errs)
+	}
+	if _, errs = schema.Validate([]byte(`[1]`)); len(errs) != 1 || errs[0].Field != "input" {
+		t.Errorf("expected a root error for a non-object, got %+v", errs)
+	}
+}
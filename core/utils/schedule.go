@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the IANA database so zones resolve the same on every host
+	_ "time/tzdata"
+
+	"go.temporal.io/sdk/client"
+)
+
+// DefaultTimeZone is used when a schedule request does not name a zone
+const DefaultTimeZone = "America/New_York"
+
+// Presets tied to the US equity session always fire in exchange time
+var marketPresets = map[string]string{
+	"market-open":  "America/New_York",
+	"market-close": "America/New_York",
+}
+
+// Schedule - A validated user schedule, either a preset or a cron expression, in an IANA zone
+type Schedule struct {
+	Preset    string
+	Cron      string
+	TimeZone  string
+	Location  *time.Location
+	Calendars []client.ScheduleCalendarSpec
+	Intervals []client.ScheduleIntervalSpec
+}
+
+// ResolveSchedule validates a preset or cron expression (exactly one) with its time zone.
+// An empty time zone falls back to DefaultTimeZone; market presets ignore the requested zone,
+// and so do crons starting with CRON_TZ= or TZ=, which Temporal copies into the schedule's zone.
+func ResolveSchedule(preset, cron, timeZone string) (*Schedule, error) {
+	preset = strings.TrimSpace(preset)
+	cron = strings.TrimSpace(cron)
+
+	if (preset == "") == (cron == "") {
+		return nil, errors.New("exactly one of preset or cron_time is required")
+	}
+
+	cronZone, cronBody := splitCron(cron)
+	if cronZone != "" {
+		timeZone = cronZone
+	}
+	if zone, ok := marketPresets[preset]; ok {
+		timeZone = zone
+	}
+	if timeZone == "" {
+		timeZone = DefaultTimeZone
+	}
+	if timeZone == "Local" {
+		return nil, errors.New("time_zone must be an IANA zone name such as America/New_York")
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time_zone %q", timeZone)
+	}
+
+	schedule := &Schedule{Preset: preset, Cron: cron, TimeZone: timeZone, Location: location}
+
+	if preset != "" {
+		spec, err := ParseHumanFriendlySchedule(preset)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Calendars = []client.ScheduleCalendarSpec{*spec}
+		return schedule, nil
+	}
+
+	if every, ok := strings.CutPrefix(cronBody, "@every "); ok {
+		interval, err := parseEvery(every)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Intervals = []client.ScheduleIntervalSpec{interval}
+		return schedule, nil
+	}
+
+	schedule.Calendars, err = ParseCron(cronBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// e.g. "0 9 30 2 *" parses but never fires
+	if len(schedule.NextRuns(time.Now(), 1)) == 0 {
+		return nil, fmt.Errorf("cron expression %q never fires", cron)
+	}
+	return schedule, nil
+}
+
+// Spec - Temporal schedule spec firing on the calendars in the schedule's time zone
+func (s *Schedule) Spec() client.ScheduleSpec {
+	return client.ScheduleSpec{
+		Calendars:    s.Calendars,
+		Intervals:    s.Intervals,
+		TimeZoneName: s.TimeZone,
+	}
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// Bounds of the year field of 6 and 7-field crons
+const (
+	minCronYear = 1970
+	maxCronYear = 2999
+)
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// splitCron separates the CRON_TZ= or TZ= prefix and the trailing "# comment" Temporal allows
+// around a cron string, e.g. "CRON_TZ=Europe/Paris 0 9 * * * # daily report"
+func splitCron(expr string) (timeZone, body string) {
+	body, _, _ = strings.Cut(expr, "#")
+	body = strings.TrimSpace(body)
+
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if rest, ok := strings.CutPrefix(body, prefix); ok {
+			timeZone, body, _ = strings.Cut(rest, " ")
+			return timeZone, strings.TrimSpace(body)
+		}
+	}
+	return "", body
+}
+
+// parseEvery parses the "<interval>[/<phase>]" of "@every", e.g. "90m" or "1d/9h"
+func parseEvery(expr string) (client.ScheduleIntervalSpec, error) {
+	everyText, offsetText, hasOffset := strings.Cut(strings.TrimSpace(expr), "/")
+
+	every, err := parseCronDuration(everyText)
+	if err != nil || every <= 0 {
+		return client.ScheduleIntervalSpec{}, fmt.Errorf("invalid @every interval %q", everyText)
+	}
+
+	interval := client.ScheduleIntervalSpec{Every: every}
+	if hasOffset {
+		offset, err := parseCronDuration(offsetText)
+		if err != nil || offset < 0 || offset >= every {
+			return client.ScheduleIntervalSpec{}, fmt.Errorf("invalid @every phase %q", offsetText)
+		}
+		interval.Offset = offset
+	}
+	return interval, nil
+}
+
+// parseCronDuration is time.ParseDuration plus the "d" unit Temporal accepts for days
+func parseCronDuration(text string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(text, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(text)
+}
+
+// ParseCron converts a cron expression into Temporal calendar specs. Like Temporal it takes
+// 5 fields, 6 with a trailing year, or 7 with a leading second and a trailing year.
+// When both day-of-month and day-of-week are restricted cron fires on either, so two specs are returned.
+func ParseCron(expr string) ([]client.ScheduleCalendarSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	var second, year []client.ScheduleRange
+	switch len(fields) {
+	case 5:
+	case 6, 7:
+		if len(fields) == 7 {
+			var err error
+			if second, _, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+				return nil, fmt.Errorf("second: %w", err)
+			}
+			fields = fields[1:]
+		}
+		// "*" leaves the year unset, which Temporal reads as every year
+		if fields[5] != "*" {
+			var err error
+			if year, _, err = parseCronField(fields[5], minCronYear, maxCronYear, nil); err != nil {
+				return nil, fmt.Errorf("year: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("cron expression %q must have 5, 6 or 7 fields", expr)
+	}
+
+	minute, _, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	hour, _, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	dayOfMonth, domRestricted, err := parseCronField(fields[2], 1, 31, nil)
+	if err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	month, _, err := parseCronField(fields[3], 1, 12, monthNames)
+	if err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// 7 is accepted as Sunday like most cron implementations
+	dayOfWeek, dowRestricted, err := parseCronField(fields[4], 0, 7, dayNames)
+	if err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	dayOfWeek = foldSunday(dayOfWeek)
+
+	spec := client.ScheduleCalendarSpec{
+		Second:     second,
+		Minute:     minute,
+		Hour:       hour,
+		DayOfMonth: dayOfMonth,
+		Month:      month,
+		DayOfWeek:  dayOfWeek,
+		Year:       year,
+	}
+
+	if !domRestricted || !dowRestricted {
+		return []client.ScheduleCalendarSpec{spec}, nil
+	}
+
+	byDayOfMonth, byDayOfWeek := spec, spec
+	byDayOfMonth.DayOfWeek = []client.ScheduleRange{{Start: 0, End: 6}}
+	byDayOfWeek.DayOfMonth = []client.ScheduleRange{{Start: 1, End: 31}}
+	return []client.ScheduleCalendarSpec{byDayOfMonth, byDayOfWeek}, nil
+}
+
+// parseCronField parses lists, ranges, steps and names; restricted is false for "*" and "?"
+func parseCronField(field string, min, max int, names map[string]int) ([]client.ScheduleRange, bool, error) {
+	if field == "*" || field == "?" {
+		return []client.ScheduleRange{{Start: min, End: max}}, false, nil
+	}
+
+	var ranges []client.ScheduleRange
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return nil, false, fmt.Errorf("empty list item in %q", field)
+		}
+
+		base, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return nil, false, fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		start, end := min, max
+		switch {
+		case base == "*":
+		case strings.Contains(base, "-"):
+			lo, hi, _ := strings.Cut(base, "-")
+			var err error
+			if start, err = cronValue(lo, min, max, names); err != nil {
+				return nil, false, err
+			}
+			if end, err = cronValue(hi, min, max, names); err != nil {
+				return nil, false, err
+			}
+			if start > end {
+				return nil, false, fmt.Errorf("range %q is reversed", base)
+			}
+		default:
+			var err error
+			if start, err = cronValue(base, min, max, names); err != nil {
+				return nil, false, err
+			}
+			// "5/15" means from 5 to the end of the field every 15
+			end = start
+			if hasStep {
+				end = max
+			}
+		}
+
+		ranges = append(ranges, client.ScheduleRange{Start: start, End: end, Step: step})
+	}
+
+	return ranges, true, nil
+}
+
+// foldSunday rewrites day-of-week 7 as 0 since Temporal only knows 0-6
+func foldSunday(ranges []client.ScheduleRange) []client.ScheduleRange {
+	folded := make([]client.ScheduleRange, 0, len(ranges))
+	for _, r := range ranges {
+		end := r.End
+		if end == 0 {
+			end = r.Start
+		}
+		if end < 7 {
+			folded = append(folded, r)
+			continue
+		}
+		step := r.Step
+		if step == 0 {
+			step = 1
+		}
+		if r.Start < 7 {
+			folded = append(folded, client.ScheduleRange{Start: r.Start, End: 6, Step: step})
+		}
+		if r.Start > 0 && (7-r.Start)%step == 0 {
+			folded = append(folded, client.ScheduleRange{Start: 0})
+		}
+	}
+	return folded
+}
+
+func cronValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", n, min, max)
+	}
+	return n, nil
+}
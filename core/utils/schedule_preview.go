@@ -0,0 +1,349 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// How far ahead fire times are searched; a schedule with no run in this window is rejected
+const scheduleHorizonDays = 5 * 366
+
+var presetDescriptions = map[string]string{
+	"daily-9am":         "Every day at 09:00",
+	"daily-5pm":         "Every day at 17:00",
+	"weekdays-9am":      "Monday to Friday at 09:00",
+	"weekdays-5pm":      "Monday to Friday at 17:00",
+	"hourly":            "Every hour, on the hour",
+	"every-30-minutes":  "Every 30 minutes",
+	"every-15-minutes":  "Every 15 minutes",
+	"weekly-monday-9am": "Every Monday at 09:00",
+	"monthly-first-9am": "On the 1st of every month at 09:00",
+	"market-open":       "Monday to Friday at market open (09:30)",
+	"market-close":      "Monday to Friday at market close (16:00)",
+}
+
+// NextRuns returns up to n fire times strictly after the given time, in the schedule's zone.
+// Wall-clock times skipped by a DST change are left out.
+func (s *Schedule) NextRuns(after time.Time, n int) []time.Time {
+	if len(s.Intervals) > 0 {
+		return s.nextIntervalRuns(after, n)
+	}
+
+	var runs []time.Time
+	after = after.In(s.Location)
+	day := time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, s.Location)
+
+	for i := 0; i < scheduleHorizonDays && len(runs) < n; i++ {
+		date := day.AddDate(0, 0, i)
+
+		var times []time.Time
+		for _, calendar := range s.Calendars {
+			if !calendarMatchesDay(calendar, date) {
+				continue
+			}
+			for _, hour := range expandRanges(calendar.Hour, 0, 23) {
+				for _, minute := range expandRanges(calendar.Minute, 0, 59) {
+					for _, second := range expandRanges(calendar.Second, 0, 59) {
+						t := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, s.Location)
+						if t.Hour() != hour || t.Minute() != minute || !t.After(after) {
+							continue
+						}
+						times = append(times, t)
+					}
+				}
+			}
+		}
+
+		sort.Slice(times, func(a, b int) bool { return times[a].Before(times[b]) })
+		for j, t := range times {
+			if j > 0 && t.Equal(times[j-1]) {
+				continue
+			}
+			runs = append(runs, t)
+			if len(runs) == n {
+				break
+			}
+		}
+	}
+
+	return runs
+}
+
+// nextIntervalRuns follows Temporal: an interval fires at every multiple of Every since the
+// Unix epoch, shifted by Offset, whatever the time zone
+func (s *Schedule) nextIntervalRuns(after time.Time, n int) []time.Time {
+	var runs []time.Time
+	for _, interval := range s.Intervals {
+		since := after.Sub(time.Unix(0, 0)) - interval.Offset
+		next := time.Unix(0, 0).Add(interval.Offset + (since/interval.Every+1)*interval.Every)
+		for i := 0; i < n; i++ {
+			runs = append(runs, next.Add(time.Duration(i)*interval.Every).In(s.Location))
+		}
+	}
+
+	sort.Slice(runs, func(a, b int) bool { return runs[a].Before(runs[b]) })
+	if len(runs) > n {
+		runs = runs[:n]
+	}
+	return runs
+}
+
+// Describe - The schedule in words, e.g. "Monday to Friday at 09:30 (America/New_York)"
+func (s *Schedule) Describe() string {
+	if description, ok := presetDescriptions[s.Preset]; ok {
+		return fmt.Sprintf("%s (%s)", description, s.TimeZone)
+	}
+	if s.Preset != "" {
+		return fmt.Sprintf("Every day at %s (%s)", s.Preset, s.TimeZone)
+	}
+
+	parts := make([]string, 0, len(s.Calendars)+len(s.Intervals))
+	for _, interval := range s.Intervals {
+		parts = append(parts, "Every "+shortDuration(interval.Every))
+	}
+	for _, calendar := range s.Calendars {
+		parts = append(parts, describeCalendar(calendar))
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(parts, ", or "), s.TimeZone)
+}
+
+// Fire times Warnings looks at, whatever number of runs is previewed
+const warningSampleRuns = 50
+
+// Warnings flags fire times outside US market hours and runs closer together than minInterval,
+// judged on the next warningSampleRuns runs after the given time
+func (s *Schedule) Warnings(after time.Time, minInterval time.Duration) []string {
+	warnings := []string{}
+	runs := s.NextRuns(after, warningSampleRuns)
+
+	eastern, _ := time.LoadLocation("America/New_York")
+	for _, run := range runs {
+		if !duringMarketHours(run.In(eastern)) {
+			warnings = append(warnings, "Some runs fall outside US market hours (Mon-Fri 09:30-16:00 America/New_York)")
+			break
+		}
+	}
+
+	if minInterval > 0 {
+		for i := 1; i < len(runs); i++ {
+			if gap := runs[i].Sub(runs[i-1]); gap < minInterval {
+				warnings = append(warnings, fmt.Sprintf("Runs every %s, more often than the plan allows (every %s)", gap, minInterval))
+				break
+			}
+		}
+	}
+
+	return warnings
+}
+
+func duringMarketHours(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	minutes := t.Hour()*60 + t.Minute()
+	return minutes >= 9*60+30 && minutes <= 16*60
+}
+
+func calendarMatchesDay(calendar client.ScheduleCalendarSpec, date time.Time) bool {
+	return inRanges(calendar.Month, int(date.Month())) &&
+		inRanges(calendar.DayOfMonth, date.Day()) &&
+		inRanges(calendar.DayOfWeek, int(date.Weekday())) &&
+		inRanges(calendar.Year, date.Year())
+}
+
+// Unset ranges match every value except for hour and minute, which Temporal treats as 0
+func expandRanges(ranges []client.ScheduleRange, min, max int) []int {
+	if len(ranges) == 0 {
+		return []int{0}
+	}
+
+	var values []int
+	for v := min; v <= max; v++ {
+		if inRanges(ranges, v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func inRanges(ranges []client.ScheduleRange, value int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+
+	for _, r := range ranges {
+		start, end, step := normalizeRange(r)
+		if value >= start && value <= end && (value-start)%step == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeRange(r client.ScheduleRange) (start, end, step int) {
+	start, end, step = r.Start, r.End, r.Step
+	if end < start {
+		end = start
+	}
+	if step < 1 {
+		step = 1
+	}
+	return start, end, step
+}
+
+// shortDuration drops the zero units time.Duration prints, e.g. 1h0m0s becomes 1h
+func shortDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+var weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+func describeCalendar(calendar client.ScheduleCalendarSpec) string {
+	var description string
+
+	hours := expandRanges(calendar.Hour, 0, 23)
+	minutes := expandRanges(calendar.Minute, 0, 59)
+	switch {
+	case len(hours) == 1 && len(minutes) == 1:
+		description = fmt.Sprintf("At %02d:%02d", hours[0], minutes[0])
+	case len(minutes) == 60:
+		description = "Every minute"
+	case len(minutes) > 1 && isEvenStep(minutes, 60):
+		description = fmt.Sprintf("Every %d minutes", minutes[1]-minutes[0])
+	case len(minutes) == 1:
+		description = fmt.Sprintf("At minute %d", minutes[0])
+	default:
+		description = fmt.Sprintf("At minutes %s", joinInts(minutes, ""))
+	}
+	if len(hours) > 1 && len(hours) < 24 {
+		description += fmt.Sprintf(" during hours %s", joinInts(hours, ":00"))
+	}
+
+	if days := restrictedValues(calendar.DayOfWeek, 0, 6); days != nil {
+		names := make([]string, 0, len(days))
+		for _, day := range days {
+			names = append(names, weekdayNames[day])
+		}
+		description += " on " + strings.Join(names, ", ")
+	}
+	if days := restrictedValues(calendar.DayOfMonth, 1, 31); days != nil {
+		description += fmt.Sprintf(" on day %s of the month", joinInts(days, ""))
+	}
+	if months := restrictedValues(calendar.Month, 1, 12); months != nil {
+		names := make([]string, 0, len(months))
+		for _, month := range months {
+			names = append(names, time.Month(month).String())
+		}
+		description += " in " + strings.Join(names, ", ")
+	}
+
+	return description
+}
+
+// restrictedValues is nil when the ranges cover the whole field
+func restrictedValues(ranges []client.ScheduleRange, min, max int) []int {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	var values []int
+	for v := min; v <= max; v++ {
+		if inRanges(ranges, v) {
+			values = append(values, v)
+		}
+	}
+	if len(values) == max-min+1 {
+		return nil
+	}
+	return values
+}
+
+func isEvenStep(values []int, size int) bool {
+	step := values[1] - values[0]
+	for i := 2; i < len(values); i++ {
+		if values[i]-values[i-1] != step {
+			return false
+		}
+	}
+	return values[0] < step && size%step == 0
+}
+
+func joinInts(values []int, suffix string) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		parts = append(parts, fmt.Sprintf("%d%s", v, suffix))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Matches reports whether a spec read back from Temporal fires at the same times as the schedule.
+// Ranges are compared by the values they expand to, since the server normalizes them.
+func (s *Schedule) Matches(spec *client.ScheduleSpec) bool {
+	if spec == nil || spec.TimeZoneName != s.TimeZone || len(spec.Calendars) != len(s.Calendars) ||
+		len(spec.Intervals) != len(s.Intervals) {
+		return false
+	}
+	for i := range s.Intervals {
+		if spec.Intervals[i] != s.Intervals[i] {
+			return false
+		}
+	}
+
+	keys := func(calendars []client.ScheduleCalendarSpec) []string {
+		out := make([]string, 0, len(calendars))
+		for _, calendar := range calendars {
+			out = append(out, calendarKey(calendar))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	want, got := keys(s.Calendars), keys(spec.Calendars)
+	for i := range want {
+		if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func calendarKey(calendar client.ScheduleCalendarSpec) string {
+	days := func(ranges []client.ScheduleRange, min, max int) string {
+		if values := restrictedValues(ranges, min, max); values != nil {
+			return joinInts(values, "")
+		}
+		return "*"
+	}
+
+	years := "*"
+	if len(calendar.Year) > 0 {
+		parts := make([]string, 0, len(calendar.Year))
+		for _, r := range calendar.Year {
+			start, end, step := normalizeRange(r)
+			parts = append(parts, fmt.Sprintf("%d-%d/%d", start, end, step))
+		}
+		sort.Strings(parts)
+		years = strings.Join(parts, ",")
+	}
+
+	return strings.Join([]string{
+		joinInts(expandRanges(calendar.Second, 0, 59), ""),
+		joinInts(expandRanges(calendar.Minute, 0, 59), ""),
+		joinInts(expandRanges(calendar.Hour, 0, 23), ""),
+		days(calendar.DayOfMonth, 1, 31),
+		days(calendar.Month, 1, 12),
+		days(calendar.DayOfWeek, 0, 6),
+		years,
+	}, "|")
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+func TestResolveSchedule_MarketPresetPinnedToNewYork(t *testing.T) {
+	schedule, err := ResolveSchedule("market-open", "", "Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("ResolveSchedule returned error: %v", err)
+	}
+
+	spec := schedule.Spec()
+	if spec.TimeZoneName != "America/New_York" {
+		t.Errorf("expected America/New_York, got %q", spec.TimeZoneName)
+	}
+	if len(spec.Calendars) != 1 || spec.Calendars[0].Hour[0].Start != 9 || spec.Calendars[0].Minute[0].Start != 30 {
+		t.Errorf("expected 9:30 calendar, got %+v", spec.Calendars)
+	}
+}
+
+func TestResolveSchedule_Validation(t *testing.T) {
+	cases := []struct {
+		name                   string
+		preset, cron, timeZone string
+	}{
+		{"neither", "", "", "UTC"},
+		{"both", "daily-9am", "0 9 * * *", "UTC"},
+		{"unknown preset", "sometimes", "", "UTC"},
+		{"bad zone", "daily-9am", "", "Mars/Olympus"},
+		{"local zone", "", "0 9 * * *", "Local"},
+		{"bad cron", "", "61 * * * *", "UTC"},
+	}
+
+	for _, tc := range cases {
+		if _, err := ResolveSchedule(tc.preset, tc.cron, tc.timeZone); err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	calendars, err := ParseCron("*/15 9-17 * * mon-fri")
+	if err != nil {
+		t.Fatalf("ParseCron returned error: %v", err)
+	}
+
+	want := client.ScheduleCalendarSpec{
+		Minute:     []client.ScheduleRange{{Start: 0, End: 59, Step: 15}},
+		Hour:       []client.ScheduleRange{{Start: 9, End: 17, Step: 1}},
+		DayOfMonth: []client.ScheduleRange{{Start: 1, End: 31}},
+		Month:      []client.ScheduleRange{{Start: 1, End: 12}},
+		DayOfWeek:  []client.ScheduleRange{{Start: 1, End: 5, Step: 1}},
+	}
+	if len(calendars) != 1 || !reflect.DeepEqual(calendars[0], want) {
+		t.Errorf("unexpected calendars %+v", calendars)
+	}
+}
+
+func TestParseCron_DayOfMonthOrDayOfWeek(t *testing.T) {
+	calendars, err := ParseCron("0 9 1 * 7")
+	if err != nil {
+		t.Fatalf("ParseCron returned error: %v", err)
+	}
+
+	if len(calendars) != 2 {
+		t.Fatalf("expected a calendar per day field, got %d", len(calendars))
+	}
+	if got := calendars[1].DayOfWeek; len(got) != 1 || got[0].Start != 0 {
+		t.Errorf("expected 7 to be folded to Sunday, got %+v", got)
+	}
+}
+
+func TestNextRuns_MarketOpenAcrossDST(t *testing.T) {
+	schedule, err := ResolveSchedule("market-open", "", "")
+	if err != nil {
+		t.Fatalf("ResolveSchedule returned error: %v", err)
+	}
+
+	// Friday before US clocks move forward on 2026-03-08
+	after := time.Date(2026, 3, 6, 15, 0, 0, 0, time.UTC)
+	runs := schedule.NextRuns(after, 2)
+
+	want := []time.Time{
+		time.Date(2026, 3, 9, 13, 30, 0, 0, time.UTC),
+		time.Date(2026, 3, 10, 13, 30, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("expected %d runs, got %v", len(want), runs)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("run %d: expected %s, got %s", i, want[i], runs[i].UTC())
+		}
+	}
+	if warnings := schedule.Warnings(after, time.Hour); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestScheduleWarnings(t *testing.T) {
+	schedule, err := ResolveSchedule("", "*/5 * * * *", "UTC")
+	if err != nil {
+		t.Fatalf("ResolveSchedule returned error: %v", err)
+	}
+
+	if warnings := schedule.Warnings(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), 15*time.Minute); len(warnings) != 2 {
+		t.Errorf("expected market hours and frequency warnings, got %v", warnings)
+	}
+	if got := schedule.Describe(); got != "Every 5 minutes (UTC)" {
+		t.Errorf("unexpected description %q", got)
+	}
+}
+
+func TestScheduleWarnings_CloseRunsOnceADay(t *testing.T) {
+	// The next run alone looks fine; the one 5 minutes after it does not
+	schedule, err := ResolveSchedule("", "0,5 10 * * mon-fri", "America/New_York")
+	if err != nil {
+		t.Fatalf("ResolveSchedule returned error: %v", err)
+	}
+
+	after := time.Date(2026, 3, 9, 16, 0, 0, 0, time.UTC)
+	warnings := schedule.Warnings(after, 15*time.Minute)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "more often than the plan allows") {
+		t.Errorf("expected a frequency warning, got %v", warnings)
+	}
+}
+
+func TestResolveSchedule_NeverFires(t *testing.T) {
+	if _, err := ResolveSchedule("", "0 9 30 2 *", "UTC"); err == nil {
+		t.Error("expected an error for a schedule that never fires")
+	}
+}
+
+func TestResolveSchedule_TemporalCronForms(t *testing.T) {
+	after := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		cron     string
+		timeZone string
+		want     []time.Time
+	}{
+		{
+			name:     "every",
+			cron:     "@every 90m",
+			timeZone: "UTC",
+			want:     []time.Time{time.Date(2026, 3, 7, 1, 30, 0, 0, time.UTC), time.Date(2026, 3, 7, 3, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "every with a phase",
+			cron:     "@every 1d/9h",
+			timeZone: "UTC",
+			want:     []time.Time{time.Date(2026, 3, 7, 9, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "CRON_TZ wins over the stored zone",
+			cron:     "CRON_TZ=Asia/Tokyo 0 9 * * *",
+			timeZone: "UTC",
+			want:     []time.Time{time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "TZ with a comment",
+			cron:     "TZ=Europe/Paris 0 9 * * * # morning report",
+			timeZone: "",
+			want:     []time.Time{time.Date(2026, 3, 7, 8, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "six fields end with the year",
+			cron:     "0 9 1 * * 2027",
+			timeZone: "UTC",
+			want:     []time.Time{time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2027, 2, 1, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:     "seven fields start with the second",
+			cron:     "30 0 9 * * * *",
+			timeZone: "UTC",
+			want:     []time.Time{time.Date(2026, 3, 7, 9, 0, 30, 0, time.UTC), time.Date(2026, 3, 8, 9, 0, 30, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ResolveSchedule("", tt.cron, tt.timeZone)
+			if err != nil {
+				t.Fatalf("ResolveSchedule returned error: %v", err)
+			}
+
+			runs := schedule.NextRuns(after, len(tt.want))
+			if len(runs) != len(tt.want) {
+				t.Fatalf("expected %d runs, got %v", len(tt.want), runs)
+			}
+			for i := range tt.want {
+				if !runs[i].Equal(tt.want[i]) {
+					t.Errorf("run %d: expected %s, got %s", i, tt.want[i], runs[i].UTC())
+				}
+			}
+			if !schedule.Matches(&client.ScheduleSpec{Calendars: schedule.Calendars, Intervals: schedule.Intervals, TimeZoneName: schedule.TimeZone}) {
+				t.Error("expected the schedule to match its own spec")
+			}
+		})
+	}
+}
+
+func TestResolveSchedule_TemporalCronFormsValidation(t *testing.T) {
+	for _, cron := range []string{"@every 0m", "@every 1h/2h", "@every often", "CRON_TZ=Mars/Olympus 0 9 * * *", "0 9 * * * 1900", "0 0 9 * * * * *"} {
+		if _, err := ResolveSchedule("", cron, "UTC"); err == nil {
+			t.Errorf("%q: expected an error", cron)
+		}
+	}
+}
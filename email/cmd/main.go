@@ -37,10 +37,26 @@ func main() {
 		HTTPPort:          8082,
 		HTTPSPort:         8444,
 		EmailConfig: email.Config{
-			ResendAPIKey: configs.ResendAPIKey,
-			FromEmail:    configs.FromEmail,
-			FromName:     configs.FromName,
+			Provider:      configs.EmailProvider,
+			ResendAPIKey:  configs.ResendAPIKey,
+			ResendBaseURL: configs.ResendBaseURL,
+			SMTP: email.SMTPConfig{
+				Host:     configs.SMTPHost,
+				Port:     configs.SMTPPort,
+				Username: configs.SMTPUsername,
+				Password: configs.SMTPPassword,
+				StartTLS: configs.SMTPStartTLS,
+			},
+			FilePath:    configs.EmailFilePath,
+			TemplateDir: configs.TemplateDir,
+			FromEmail:   configs.FromEmail,
+			FromName:    configs.FromName,
 		},
+		UserEventsStream: configs.UserEventsStream,
+		WelcomeDedupeTTL: configs.WelcomeDedupeTTL,
+
+		WorkflowEventsStream: configs.WorkflowEventsStream,
+		SummaryDedupeTTL:     configs.SummaryDedupeTTL,
 	}
 
 	srv, err := server.NewServer(cfg)
@@ -73,30 +89,7 @@ func main() {
 }
 
 func setupEventHandlers(es *events.EventService) {
-	SingleErrorMust(es.Subscribe("user.created", func(event *events.Event) error {
-		log.Info().
-			Str("event_type", "user.created").
-			Interface("data", event.Data).
-			Msg("User created event received")
-
-		if email, ok := event.Data["email"].(string); ok {
-			name, _ := event.Data["name"].(string)
-			welcomePayload := map[string]interface{}{
-				"type":    "welcome",
-				"message": "Welcome to our platform! We're excited to have you on board.",
-				"info": map[string]interface{}{
-					"to":   email,
-					"name": name,
-				},
-			}
-			es.PublishAsync("email.send", &events.Event{
-				Type:   "email.send",
-				Source: "user-service",
-				Data:   welcomePayload,
-			})
-		}
-		return nil
-	}))
+	// user.created is consumed durably by server.WelcomeConsumer
 
 	SingleErrorMust(es.Subscribe("user.updated", func(event *events.Event) error {
 		log.Info().
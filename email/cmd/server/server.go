@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -10,19 +11,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nats-io/nats.go"
+	"stock-agent.io/internal/consumer"
 	"stock-agent.io/internal/email"
 	"stock-agent.io/internal/events"
+	"stock-agent.io/internal/templates"
 )
 
 type Server struct {
-	NatsConn     *nats.Conn
-	EmailService *email.EmailService
-	EventService *events.EventService
-	HealthAddr   string
-	udpConn      *net.UDPConn
-	httpServer   *http.Server
-	httpsServer  *http.Server
-	router       *gin.Engine
+	NatsConn        *nats.Conn
+	EmailService    *email.EmailService
+	EventService    *events.EventService
+	WelcomeConsumer *consumer.WelcomeConsumer
+	SummaryConsumer *consumer.SummaryConsumer
+	HealthAddr      string
+	udpConn         *net.UDPConn
+	httpServer      *http.Server
+	httpsServer     *http.Server
+	router          *gin.Engine
 }
 
 type ServerConfig struct {
@@ -34,6 +39,11 @@ type ServerConfig struct {
 	HTTPPort          int
 	HTTPSPort         int
 	EmailConfig       email.Config
+	UserEventsStream  string
+	WelcomeDedupeTTL  time.Duration
+	// WorkflowEventsStream holds workflow.completed, emailed by the summary consumer
+	WorkflowEventsStream string
+	SummaryDedupeTTL     time.Duration
 }
 
 func NewServer(cfg ServerConfig) (*Server, error) {
@@ -49,17 +59,38 @@ func NewServer(cfg ServerConfig) (*Server, error) {
 
 	eventService := events.NewEventService(nc)
 
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	deduper, err := consumer.NewKVDeduper(js, "email_welcome_sent", cfg.WelcomeDedupeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create welcome deduper: %w", err)
+	}
+
+	welcomeConsumer := consumer.NewWelcomeConsumer(js, cfg.UserEventsStream, deduper, emailService)
+
+	summaryDeduper, err := consumer.NewKVDeduper(js, "email_summary_sent", cfg.SummaryDedupeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create summary deduper: %w", err)
+	}
+
+	summaryConsumer := consumer.NewSummaryConsumer(js, cfg.WorkflowEventsStream, summaryDeduper, emailService)
+
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 
 	server := &Server{
-		NatsConn:     nc,
-		EmailService: emailService,
-		EventService: eventService,
-		HealthAddr:   cfg.HealthUDPAddr,
-		router:       router,
+		NatsConn:        nc,
+		EmailService:    emailService,
+		EventService:    eventService,
+		WelcomeConsumer: welcomeConsumer,
+		SummaryConsumer: summaryConsumer,
+		HealthAddr:      cfg.HealthUDPAddr,
+		router:          router,
 	}
 
 	server.setupRoutes()
@@ -206,6 +237,14 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start email listener: %w", err)
 	}
 
+	if err := s.WelcomeConsumer.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start welcome consumer: %w", err)
+	}
+
+	if err := s.SummaryConsumer.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start summary consumer: %w", err)
+	}
+
 	log.Println("Server started successfully")
 	return nil
 }
@@ -232,6 +271,18 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	if s.WelcomeConsumer != nil {
+		if err := s.WelcomeConsumer.Stop(); err != nil {
+			log.Printf("Error stopping welcome consumer: %v", err)
+		}
+	}
+
+	if s.SummaryConsumer != nil {
+		if err := s.SummaryConsumer.Stop(); err != nil {
+			log.Printf("Error stopping summary consumer: %v", err)
+		}
+	}
+
 	if s.EventService != nil {
 		if err := s.EventService.Stop(ctx); err != nil {
 			log.Printf("Error stopping event service: %v", err)
@@ -272,6 +323,38 @@ func (s *Server) setupRoutes() {
 			})
 		})
 
+		// Email templates and previews rendered with their sample data
+		api.GET("/templates", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"templates": s.EmailService.Templates().Names()})
+		})
+
+		api.GET("/templates/:name/preview", func(c *gin.Context) {
+			rendered, err := s.EmailService.Templates().Preview(c.Param("name"))
+			if err != nil {
+				if errors.Is(err, templates.ErrNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+					return
+				}
+				log.Printf("Failed to render template preview: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to render template",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			switch c.DefaultQuery("format", "html") {
+			case "html":
+				c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+			case "text":
+				c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(rendered.Text))
+			case "json":
+				c.JSON(http.StatusOK, rendered)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html, text or json"})
+			}
+		})
+
 		// Test email endpoint
 		api.POST("/send-test-email", func(c *gin.Context) {
 			var request struct {
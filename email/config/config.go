@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/caarlos0/env/v6"
 	"github.com/rs/zerolog/log"
 )
@@ -11,9 +13,28 @@ type Config struct {
 	NatsReconnectWait string `env:"NATS_RECONNECT_WAIT,required"`
 	NatsTimeout       string `env:"NATS_TIMEOUT,required"`
 	Topic             string `env:"TOPIC,required"`
-	ResendAPIKey      string `env:"RESEND_API_KEY,required"`
 	FromEmail         string `env:"FROM_EMAIL,required"`
 	FromName          string `env:"FROM_NAME,required"`
+
+	// EmailProvider is one of resend, smtp or file
+	EmailProvider string `env:"EMAIL_PROVIDER" envDefault:"resend"`
+	ResendAPIKey  string `env:"RESEND_API_KEY"`
+	ResendBaseURL string `env:"RESEND_BASE_URL" envDefault:"https://api.resend.com"`
+	SMTPHost      string `env:"SMTP_HOST"`
+	SMTPPort      int    `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername  string `env:"SMTP_USERNAME"`
+	SMTPPassword  string `env:"SMTP_PASSWORD"`
+	SMTPStartTLS  bool   `env:"SMTP_STARTTLS" envDefault:"true"`
+	EmailFilePath string `env:"EMAIL_FILE_PATH" envDefault:"/tmp/kainos-mail/outbox.mbox"`
+
+	// TemplateDir loads email templates from disk instead of the ones built into the binary
+	TemplateDir string `env:"EMAIL_TEMPLATE_DIR"`
+
+	UserEventsStream string        `env:"USER_EVENTS_STREAM" envDefault:"user_events"`
+	WelcomeDedupeTTL time.Duration `env:"WELCOME_DEDUPE_TTL" envDefault:"720h"`
+
+	WorkflowEventsStream string        `env:"WORKFLOW_EVENTS_STREAM" envDefault:"workflow_events"`
+	SummaryDedupeTTL     time.Duration `env:"SUMMARY_DEDUPE_TTL" envDefault:"720h"`
 }
 
 func NewConfig() *Config {
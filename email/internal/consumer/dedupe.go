@@ -0,0 +1,89 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Deduper remembers which events were already handled so redeliveries are skipped
+type Deduper interface {
+	// Claim reports false if the event id was claimed before
+	Claim(ctx context.Context, eventID string) (bool, error)
+	// Release forgets a claim so the event can be handled again after a failure
+	Release(ctx context.Context, eventID string) error
+}
+
+// KVDeduper keeps claims in a JetStream key-value bucket so they survive restarts
+// and are shared by every email service replica.
+type KVDeduper struct {
+	kv nats.KeyValue
+}
+
+func NewKVDeduper(js nats.JetStreamContext, bucket string, ttl time.Duration) (*KVDeduper, error) {
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  bucket,
+			TTL:     ttl,
+			Storage: nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dedupe bucket %s: %w", bucket, err)
+	}
+
+	return &KVDeduper{kv: kv}, nil
+}
+
+func (d *KVDeduper) Claim(ctx context.Context, eventID string) (bool, error) {
+	_, err := d.kv.Create(eventID, []byte(time.Now().UTC().Format(time.RFC3339)))
+	if errors.Is(err, nats.ErrKeyExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim event %s: %w", eventID, err)
+	}
+	return true, nil
+}
+
+func (d *KVDeduper) Release(ctx context.Context, eventID string) error {
+	// Purge instead of Delete so the key can be created again
+	if err := d.kv.Purge(eventID); err != nil {
+		return fmt.Errorf("failed to release event %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// MemoryDeduper keeps claims in memory, for tests and local runs
+type MemoryDeduper struct {
+	mu      sync.Mutex
+	claimed map[string]struct{}
+}
+
+func NewMemoryDeduper() *MemoryDeduper {
+	return &MemoryDeduper{claimed: make(map[string]struct{})}
+}
+
+func (d *MemoryDeduper) Claim(ctx context.Context, eventID string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.claimed[eventID]; ok {
+		return false, nil
+	}
+	d.claimed[eventID] = struct{}{}
+	return true, nil
+}
+
+func (d *MemoryDeduper) Release(ctx context.Context, eventID string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.claimed, eventID)
+	return nil
+}
@@ -0,0 +1,252 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"stock-agent.io/internal/email"
+	"stock-agent.io/internal/events"
+)
+
+const (
+	WorkflowCompletedSubject = "workflow.completed"
+	summaryDurable           = "email-workflow-summary"
+	summaryRetryDelay        = 30 * time.Second
+	// Longer outputs are cut so the email stays readable; the execution link has the rest
+	summaryMaxOutputLength = 10000
+)
+
+// Values of WorkflowCompletedData.NotifyOn
+const (
+	NotifyOnAlways  = "always"
+	NotifyOnFailure = "failure"
+	NotifyOnNever   = "never"
+)
+
+// ErrInvalidWorkflowEvent marks workflow.completed events that can never be turned into an email
+var ErrInvalidWorkflowEvent = errors.New("invalid workflow.completed event")
+
+// Keys of the Mastra output that hold a human-readable summary, in order of preference
+var summaryKeys = []string{"summary", "report", "text", "message", "analysis"}
+
+// SummaryConsumer durably consumes workflow.completed from JetStream and emails the run's
+// result as a daily summary, following the user workflow's notification setting.
+type SummaryConsumer struct {
+	js      nats.JetStreamContext
+	stream  string
+	deduper Deduper
+	sender  Sender
+	sub     *nats.Subscription
+}
+
+func NewSummaryConsumer(js nats.JetStreamContext, stream string, deduper Deduper, sender Sender) *SummaryConsumer {
+	return &SummaryConsumer{
+		js:      js,
+		stream:  stream,
+		deduper: deduper,
+		sender:  sender,
+	}
+}
+
+// Start binds the durable consumer, creating the workflow events stream if core-api has not yet
+func (c *SummaryConsumer) Start(ctx context.Context) error {
+	if err := ensureStream(c.js, c.stream, "workflow.>"); err != nil {
+		return err
+	}
+
+	sub, err := c.js.Subscribe(WorkflowCompletedSubject, func(msg *nats.Msg) {
+		c.onMessage(ctx, msg)
+	},
+		nats.BindStream(c.stream),
+		nats.Durable(summaryDurable),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.DeliverAll(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", WorkflowCompletedSubject, err)
+	}
+
+	c.sub = sub
+	log.Printf("Summary consumer listening on JetStream subject: %s with stream: %s", WorkflowCompletedSubject, c.stream)
+	return nil
+}
+
+// Stop stops receiving messages; the durable consumer keeps its position on the server
+func (c *SummaryConsumer) Stop() error {
+	if c.sub == nil {
+		return nil
+	}
+	return c.sub.Drain()
+}
+
+func (c *SummaryConsumer) onMessage(ctx context.Context, msg *nats.Msg) {
+	err := c.Handle(ctx, msg.Data)
+	switch {
+	case err == nil:
+		msg.Ack()
+	case errors.Is(err, ErrInvalidWorkflowEvent):
+		log.Printf("Dropping workflow.completed event: %v", err)
+		msg.Term()
+	default:
+		log.Printf("Failed to handle workflow.completed event, retrying in %s: %v", summaryRetryDelay, err)
+		msg.NakWithDelay(summaryRetryDelay)
+	}
+}
+
+// Handle emails the result of one workflow.completed message. A nil error means the message
+// is done with: the email was sent, was sent before, or is not wanted by the user.
+func (c *SummaryConsumer) Handle(ctx context.Context, data []byte) error {
+	var event events.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWorkflowEvent, err)
+	}
+
+	payload, err := SummaryPayload(&event)
+	if err != nil {
+		return err
+	}
+	if payload == nil {
+		log.Printf("Workflow run of event %s is not emailed per its notification setting", event.ID)
+		return nil
+	}
+
+	claimed, err := c.deduper.Claim(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		log.Printf("Summary email for event %s already sent, skipping", event.ID)
+		return nil
+	}
+
+	if err := c.sender.Send(payload); err != nil {
+		// Give the redelivery a chance to send it
+		if releaseErr := c.deduper.Release(ctx, event.ID); releaseErr != nil {
+			log.Printf("Failed to release event %s: %v", event.ID, releaseErr)
+		}
+		return fmt.Errorf("failed to send summary email: %w", err)
+	}
+
+	return nil
+}
+
+// SummaryPayload maps a workflow.completed event to the daily_summary email payload.
+// It returns nil when the notification setting says the run is not emailed.
+func SummaryPayload(event *events.Event) (*email.Payload, error) {
+	if event.ID == "" {
+		return nil, fmt.Errorf("%w: missing event id", ErrInvalidWorkflowEvent)
+	}
+	if event.Type != WorkflowCompletedSubject {
+		return nil, fmt.Errorf("%w: unexpected event type %q", ErrInvalidWorkflowEvent, event.Type)
+	}
+
+	var data events.WorkflowCompletedData
+	if err := event.DecodeData(&data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWorkflowEvent, err)
+	}
+	if data.Email == "" {
+		return nil, fmt.Errorf("%w: missing email", ErrInvalidWorkflowEvent)
+	}
+	if data.ExecutionURL == "" {
+		return nil, fmt.Errorf("%w: missing execution url", ErrInvalidWorkflowEvent)
+	}
+
+	if !ShouldNotify(data.NotifyOn, data.Status) {
+		return nil, nil
+	}
+
+	workflowName := data.WorkflowName
+	if workflowName == "" {
+		workflowName = "workflow"
+	}
+
+	message := fmt.Sprintf("Your %s run finished.", workflowName)
+	switch data.Status {
+	case "FAILED":
+		message = fmt.Sprintf("Your %s run failed.", workflowName)
+	case "CANCELLED":
+		message = fmt.Sprintf("Your %s run was cancelled; this is what it produced before stopping.", workflowName)
+	}
+
+	summary, output := summarizeOutput(data.Output)
+	info := map[string]interface{}{
+		"to":            data.Email,
+		"name":          data.FirstName,
+		"workflow_name": workflowName,
+		"status":        data.Status,
+		"failed":        data.Status == "FAILED",
+		"summary":       summary,
+		"output":        output,
+		"error":         data.Error,
+		"execution_url": data.ExecutionURL,
+		"event_id":      event.ID,
+	}
+	if !data.FinishedAt.IsZero() {
+		info["finished_at"] = data.FinishedAt.UTC().Format("Jan 2, 2006 15:04 UTC")
+	}
+
+	return &email.Payload{
+		Type:    "daily_summary",
+		Message: message,
+		Info:    info,
+	}, nil
+}
+
+// ShouldNotify applies a notification setting to a run status; an unknown setting counts as always
+func ShouldNotify(notifyOn, status string) bool {
+	switch notifyOn {
+	case NotifyOnNever:
+		return false
+	case NotifyOnFailure:
+		return status == "FAILED"
+	default:
+		return true
+	}
+}
+
+// summarizeOutput picks a readable summary out of the Mastra output and formats the whole of it
+func summarizeOutput(raw json.RawMessage) (summary, output string) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", truncate(string(raw))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return truncate(v), ""
+	case map[string]interface{}:
+		for _, key := range summaryKeys {
+			if text, ok := v[key].(string); ok && strings.TrimSpace(text) != "" {
+				summary = truncate(text)
+				break
+			}
+		}
+	}
+
+	indented, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return summary, truncate(string(raw))
+	}
+	return summary, truncate(string(indented))
+}
+
+func truncate(text string) string {
+	runes := []rune(text)
+	if len(runes) <= summaryMaxOutputLength {
+		return text
+	}
+	return string(runes[:summaryMaxOutputLength]) + "…"
+}
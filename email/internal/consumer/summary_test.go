@@ -0,0 +1,74 @@
+package consumer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func workflowCompleted(status, notifyOn string) []byte {
+	return []byte(`{
+	"id": "0c3f1b1e-9d7a-5c1e-8f3b-2a4d6e8f0a1b",
+	"type": "workflow.completed",
+	"source": "core-api",
+	"data": {
+		"user_id": "user_123", "email": "david@example.com", "first_name": "David",
+		"user_workflow_id": "5b0e7f0a-2c1d-4e8f-9a3b-6c7d8e9f0a1b", "workflow_name": "Tech watchlist",
+		"temporal_workflow_id": "wf-1", "temporal_run_id": "run-1",
+		"status": "` + status + `", "output": {"summary": "AAPL up 2%", "tickers": ["AAPL"]},
+		"finished_at": "2026-10-18T09:04:00Z", "notify_on": "` + notifyOn + `",
+		"execution_url": "https://app.example.com/workflows/5b0e7f0a-2c1d-4e8f-9a3b-6c7d8e9f0a1b/executions/run-1"
+	}
+}`)
+}
+
+func TestSummaryConsumer_SendsSummaryPayload(t *testing.T) {
+	sender := &fakeSender{}
+	consumer := NewSummaryConsumer(nil, "workflow_events", NewMemoryDeduper(), sender)
+
+	for i := 0; i < 2; i++ {
+		if err := consumer.Handle(context.Background(), workflowCompleted("SUCCEEDED", NotifyOnAlways)); err != nil {
+			t.Fatalf("delivery %d returned error: %v", i+1, err)
+		}
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 email for a redelivered event, got %d", len(sender.sent))
+	}
+	payload := sender.sent[0]
+	if payload.Type != "daily_summary" || payload.Info["to"] != "david@example.com" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+	if payload.Info["summary"] != "AAPL up 2%" {
+		t.Errorf("expected the output summary, got %v", payload.Info["summary"])
+	}
+	if url, _ := payload.Info["execution_url"].(string); !strings.HasSuffix(url, "/executions/run-1") {
+		t.Errorf("expected a link to the execution, got %v", payload.Info["execution_url"])
+	}
+}
+
+func TestSummaryConsumer_FollowsNotificationSetting(t *testing.T) {
+	cases := []struct {
+		status   string
+		notifyOn string
+		sent     bool
+	}{
+		{"SUCCEEDED", NotifyOnFailure, false},
+		{"FAILED", NotifyOnFailure, true},
+		{"CANCELLED", NotifyOnFailure, false},
+		{"FAILED", NotifyOnNever, false},
+		{"CANCELLED", NotifyOnAlways, true},
+	}
+
+	for _, tc := range cases {
+		sender := &fakeSender{}
+		consumer := NewSummaryConsumer(nil, "workflow_events", NewMemoryDeduper(), sender)
+
+		if err := consumer.Handle(context.Background(), workflowCompleted(tc.status, tc.notifyOn)); err != nil {
+			t.Fatalf("%s/%s: Handle returned error: %v", tc.status, tc.notifyOn, err)
+		}
+		if sent := len(sender.sent) == 1; sent != tc.sent {
+			t.Errorf("%s/%s: expected sent=%v, got %d emails", tc.status, tc.notifyOn, tc.sent, len(sender.sent))
+		}
+	}
+}
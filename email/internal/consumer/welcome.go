@@ -0,0 +1,184 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"stock-agent.io/internal/email"
+	"stock-agent.io/internal/events"
+)
+
+const (
+	UserCreatedSubject = "user.created"
+	welcomeDurable     = "email-welcome"
+	welcomeMessage     = "Welcome to our platform! We're excited to have you on board."
+	welcomeRetryDelay  = 30 * time.Second
+)
+
+// ErrInvalidEvent marks events that can never be turned into an email; they are not redelivered
+var ErrInvalidEvent = errors.New("invalid user.created event")
+
+// Sender sends a rendered email payload; *email.EmailService implements it
+type Sender interface {
+	Send(payload *email.Payload) error
+}
+
+// WelcomeConsumer durably consumes user.created from JetStream and sends one
+// welcome email per event id, however often the event is redelivered.
+type WelcomeConsumer struct {
+	js      nats.JetStreamContext
+	stream  string
+	deduper Deduper
+	sender  Sender
+	sub     *nats.Subscription
+}
+
+func NewWelcomeConsumer(js nats.JetStreamContext, stream string, deduper Deduper, sender Sender) *WelcomeConsumer {
+	return &WelcomeConsumer{
+		js:      js,
+		stream:  stream,
+		deduper: deduper,
+		sender:  sender,
+	}
+}
+
+// Start binds the durable consumer, creating the user events stream if core-api has not yet
+func (c *WelcomeConsumer) Start(ctx context.Context) error {
+	if err := c.ensureStream(); err != nil {
+		return err
+	}
+
+	sub, err := c.js.Subscribe(UserCreatedSubject, func(msg *nats.Msg) {
+		c.onMessage(ctx, msg)
+	},
+		nats.BindStream(c.stream),
+		nats.Durable(welcomeDurable),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.DeliverAll(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", UserCreatedSubject, err)
+	}
+
+	c.sub = sub
+	log.Printf("Welcome consumer listening on JetStream subject: %s with stream: %s", UserCreatedSubject, c.stream)
+	return nil
+}
+
+// Stop stops receiving messages; the durable consumer keeps its position on the server
+func (c *WelcomeConsumer) Stop() error {
+	if c.sub == nil {
+		return nil
+	}
+	return c.sub.Drain()
+}
+
+func (c *WelcomeConsumer) onMessage(ctx context.Context, msg *nats.Msg) {
+	err := c.Handle(ctx, msg.Data)
+	switch {
+	case err == nil:
+		msg.Ack()
+	case errors.Is(err, ErrInvalidEvent):
+		log.Printf("Dropping user.created event: %v", err)
+		msg.Term()
+	default:
+		log.Printf("Failed to handle user.created event, retrying in %s: %v", welcomeRetryDelay, err)
+		msg.NakWithDelay(welcomeRetryDelay)
+	}
+}
+
+// Handle sends the welcome email for one user.created message. A nil error means
+// the message is done with, either because the email was sent or it was already sent.
+func (c *WelcomeConsumer) Handle(ctx context.Context, data []byte) error {
+	var event events.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	payload, err := WelcomePayload(&event)
+	if err != nil {
+		return err
+	}
+
+	claimed, err := c.deduper.Claim(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		log.Printf("Welcome email for event %s already sent, skipping", event.ID)
+		return nil
+	}
+
+	if err := c.sender.Send(payload); err != nil {
+		// Give the redelivery a chance to send it
+		if releaseErr := c.deduper.Release(ctx, event.ID); releaseErr != nil {
+			log.Printf("Failed to release event %s: %v", event.ID, releaseErr)
+		}
+		return fmt.Errorf("failed to send welcome email: %w", err)
+	}
+
+	return nil
+}
+
+// WelcomePayload maps a core-api user.created event to the welcome email payload
+func WelcomePayload(event *events.Event) (*email.Payload, error) {
+	if event.ID == "" {
+		return nil, fmt.Errorf("%w: missing event id", ErrInvalidEvent)
+	}
+	if event.Type != UserCreatedSubject {
+		return nil, fmt.Errorf("%w: unexpected event type %q", ErrInvalidEvent, event.Type)
+	}
+
+	to, _ := event.Data["email"].(string)
+	if to == "" {
+		return nil, fmt.Errorf("%w: missing email", ErrInvalidEvent)
+	}
+
+	name, _ := event.Data["first_name"].(string)
+	if name == "" {
+		name, _ = event.Data["name"].(string)
+	}
+
+	return &email.Payload{
+		Type:    "welcome",
+		Message: welcomeMessage,
+		Info: map[string]interface{}{
+			"to":       to,
+			"name":     name,
+			"event_id": event.ID,
+		},
+	}, nil
+}
+
+func (c *WelcomeConsumer) ensureStream() error {
+	return ensureStream(c.js, c.stream, "user.>")
+}
+
+// ensureStream creates the stream if core-api has not yet; both services create it the same way
+func ensureStream(js nats.JetStreamContext, stream, subject string) error {
+	_, err := js.StreamInfo(stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("failed to get stream info: %w", err)
+	}
+
+	log.Printf("Creating JetStream stream: %s", stream)
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:       stream,
+		Subjects:   []string{subject},
+		Storage:    nats.FileStorage,
+		Duplicates: 2 * time.Minute,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream: %w", err)
+	}
+	return nil
+}
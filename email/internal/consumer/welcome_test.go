@@ -0,0 +1,109 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stock-agent.io/internal/email"
+)
+
+type fakeSender struct {
+	sent []*email.Payload
+	err  error
+}
+
+func (f *fakeSender) Send(payload *email.Payload) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, payload)
+	return nil
+}
+
+const userCreated = `{
+	"id": "6f1c1e4e-4f1b-4c89-9a43-4d1b0c1f2a10",
+	"type": "user.created",
+	"source": "core-api",
+	"data": {"user_id": "user_123", "email": "david@example.com", "name": "David Zaya", "first_name": "David", "last_name": "Zaya"}
+}`
+
+func TestWelcomeConsumer_SendsWelcomePayload(t *testing.T) {
+	sender := &fakeSender{}
+	consumer := NewWelcomeConsumer(nil, "user_events", NewMemoryDeduper(), sender)
+
+	if err := consumer.Handle(context.Background(), []byte(userCreated)); err != nil {
+		t.Fatalf("Handle returned error: %v", err)
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(sender.sent))
+	}
+	payload := sender.sent[0]
+	if payload.Type != "welcome" {
+		t.Errorf("unexpected payload type %q", payload.Type)
+	}
+	if payload.Info["to"] != "david@example.com" {
+		t.Errorf("unexpected recipient %v", payload.Info["to"])
+	}
+	if payload.Info["name"] != "David" {
+		t.Errorf("unexpected name %v", payload.Info["name"])
+	}
+}
+
+func TestWelcomeConsumer_RedeliveryDoesNotSendTwice(t *testing.T) {
+	sender := &fakeSender{}
+	consumer := NewWelcomeConsumer(nil, "user_events", NewMemoryDeduper(), sender)
+
+	for i := 0; i < 3; i++ {
+		if err := consumer.Handle(context.Background(), []byte(userCreated)); err != nil {
+			t.Fatalf("delivery %d returned error: %v", i+1, err)
+		}
+	}
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 email for a redelivered event, got %d", len(sender.sent))
+	}
+}
+
+func TestWelcomeConsumer_FailedSendIsRetried(t *testing.T) {
+	sender := &fakeSender{err: errors.New("resend unavailable")}
+	consumer := NewWelcomeConsumer(nil, "user_events", NewMemoryDeduper(), sender)
+
+	err := consumer.Handle(context.Background(), []byte(userCreated))
+	if err == nil || errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+
+	sender.err = nil
+	if err := consumer.Handle(context.Background(), []byte(userCreated)); err != nil {
+		t.Fatalf("redelivery returned error: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected the redelivery to send the email, got %d emails", len(sender.sent))
+	}
+}
+
+func TestWelcomeConsumer_InvalidEvents(t *testing.T) {
+	tests := map[string]string{
+		"malformed json": `{"id":`,
+		"missing id":     `{"type": "user.created", "data": {"email": "david@example.com"}}`,
+		"missing email":  `{"id": "evt-1", "type": "user.created", "data": {"first_name": "David"}}`,
+		"wrong type":     `{"id": "evt-1", "type": "user.deleted", "data": {"email": "david@example.com"}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			sender := &fakeSender{}
+			consumer := NewWelcomeConsumer(nil, "user_events", NewMemoryDeduper(), sender)
+
+			err := consumer.Handle(context.Background(), []byte(data))
+			if !errors.Is(err, ErrInvalidEvent) {
+				t.Fatalf("expected ErrInvalidEvent, got %v", err)
+			}
+			if len(sender.sent) != 0 {
+				t.Fatalf("expected no email, got %d", len(sender.sent))
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileProvider appends every email to a local mbox file instead of delivering it,
+// for development, CI and tests.
+type FileProvider struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileProvider(path string) (*FileProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("email file path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mailbox directory: %w", err)
+	}
+
+	return &FileProvider{path: path}, nil
+}
+
+func (p *FileProvider) Name() string {
+	return ProviderFile
+}
+
+func (p *FileProvider) Send(ctx context.Context, msg Message) error {
+	from, err := envelopeAddress(msg.From)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	data, err := buildMIME(msg, now)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	var entry bytes.Buffer
+	fmt.Fprintf(&entry, "From %s %s\n", from, now.UTC().Format(time.ANSIC))
+	for _, line := range bytes.Split(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), []byte("\n")) {
+		// mboxrd quoting so body lines are never read as a new message
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) {
+			entry.WriteByte('>')
+		}
+		entry.Write(line)
+		entry.WriteByte('\n')
+	}
+	entry.WriteByte('\n')
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open mailbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(entry.Bytes()); err != nil {
+		return fmt.Errorf("failed to write mailbox: %w", err)
+	}
+
+	log.Printf("Email to %v written to %s", msg.To, p.path)
+	return nil
+}
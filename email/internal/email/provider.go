@@ -0,0 +1,125 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderResend = "resend"
+	ProviderSMTP   = "smtp"
+	ProviderFile   = "file"
+)
+
+// Message is a rendered email ready to be handed to a Provider
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	// Text is the optional plain-text alternative of HTML
+	Text string
+}
+
+// Provider delivers rendered emails
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewProvider builds the provider selected by cfg.Provider, Resend by default
+func NewProvider(cfg Config) (Provider, error) {
+	switch cfg.Provider {
+	case "", ProviderResend:
+		return NewResendProvider(cfg.ResendAPIKey, cfg.ResendBaseURL)
+	case ProviderSMTP:
+		return NewSMTPProvider(cfg.SMTP)
+	case ProviderFile:
+		return NewFileProvider(cfg.FilePath)
+	default:
+		return nil, fmt.Errorf("unknown email provider: %s", cfg.Provider)
+	}
+}
+
+// buildMIME renders msg as an RFC 5322 message, multipart/alternative when it has a text part
+func buildMIME(msg Message, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", msg.From)
+	header.Set("To", strings.Join(msg.To, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", date.Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	if msg.Text == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, msg.HTML); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	header.Set("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" header value
+func envelopeAddress(value string) (string, error) {
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", value, err)
+	}
+	return addr.Address, nil
+}
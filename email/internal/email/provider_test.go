@@ -0,0 +1,90 @@
+package email
+
+import (
+	"context"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		name    string
+		wantErr bool
+	}{
+		{cfg: Config{ResendAPIKey: "key"}, name: ProviderResend},
+		{cfg: Config{Provider: ProviderResend}, wantErr: true},
+		{cfg: Config{Provider: ProviderSMTP, SMTP: SMTPConfig{Host: "smtp.example.com"}}, name: ProviderSMTP},
+		{cfg: Config{Provider: ProviderSMTP}, wantErr: true},
+		{cfg: Config{Provider: ProviderFile, FilePath: filepath.Join(t.TempDir(), "out.mbox")}, name: ProviderFile},
+		{cfg: Config{Provider: "carrier-pigeon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		provider, err := NewProvider(tt.cfg)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewProvider(%+v) expected an error", tt.cfg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewProvider(%+v) returned error: %v", tt.cfg, err)
+			continue
+		}
+		if provider.Name() != tt.name {
+			t.Errorf("expected provider %q, got %q", tt.name, provider.Name())
+		}
+	}
+}
+
+func TestFileProvider_WritesMbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail", "out.mbox")
+	provider, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("NewFileProvider returned error: %v", err)
+	}
+
+	msg := Message{
+		From:    "Kainos Team <noreply@kainos.it.com>",
+		To:      []string{"david@example.com"},
+		Subject: "Welcome to Kainos!",
+		HTML:    "<p>Hello David</p>",
+		Text:    "Hello David\nFrom the team",
+	}
+	for i := 0; i < 2; i++ {
+		if err := provider.Send(context.Background(), msg); err != nil {
+			t.Fatalf("Send returned error: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read mbox: %v", err)
+	}
+	mbox := string(data)
+
+	if got := strings.Count(mbox, "\nFrom noreply@kainos.it.com "); got != 1 || !strings.HasPrefix(mbox, "From noreply@kainos.it.com ") {
+		t.Fatalf("expected two mbox entries, got:\n%s", mbox)
+	}
+	if !strings.Contains(mbox, "\n>From the team") {
+		t.Errorf("expected body line starting with From to be quoted, got:\n%s", mbox)
+	}
+
+	// The first entry, without its mbox separator line, is a parseable message
+	first := strings.SplitN(mbox, "\n\nFrom ", 2)[0]
+	first = first[strings.Index(first, "\n")+1:]
+	parsed, err := mail.ReadMessage(strings.NewReader(first))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if parsed.Header.Get("To") != "david@example.com" {
+		t.Errorf("unexpected To header %q", parsed.Header.Get("To"))
+	}
+	if !strings.HasPrefix(parsed.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected multipart/alternative, got %q", parsed.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,95 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultResendBaseURL = "https://api.resend.com"
+
+type ResendEmail struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	HTML    string   `json:"html"`
+	Text    string   `json:"text,omitempty"`
+}
+
+type ResendResponse struct {
+	ID string `json:"id"`
+}
+
+// ResendProvider sends emails through the Resend HTTP API
+type ResendProvider struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewResendProvider(apiKey, baseURL string) (*ResendProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("Resend API key is required")
+	}
+	if baseURL == "" {
+		baseURL = defaultResendBaseURL
+	}
+
+	return &ResendProvider{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}, nil
+}
+
+func (p *ResendProvider) Name() string {
+	return ProviderResend
+}
+
+func (p *ResendProvider) Send(ctx context.Context, msg Message) error {
+	jsonData, err := json.Marshal(ResendEmail{
+		From:    msg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    msg.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal email: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/emails", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var buf bytes.Buffer
+		buf.ReadFrom(resp.Body)
+		return fmt.Errorf("resend API error: %d - %s", resp.StatusCode, buf.String())
+	}
+
+	var resendResp ResendResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resendResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	log.Printf("Email sent successfully with ID: %s", resendResp.ID)
+	return nil
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WorkflowCompletedData is the data of core-api's workflow.completed event, sent once per finished run
+type WorkflowCompletedData struct {
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+
+	UserWorkflowID     string `json:"user_workflow_id"`
+	WorkflowName       string `json:"workflow_name"`
+	TemporalWorkflowID string `json:"temporal_workflow_id"`
+	TemporalRunID      string `json:"temporal_run_id"`
+
+	// Status is SUCCEEDED, FAILED or CANCELLED; Output is the Mastra result, partial for a cancelled run
+	Status     string          `json:"status"`
+	Output     json.RawMessage `json:"output,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	FinishedAt time.Time       `json:"finished_at"`
+
+	// NotifyOn is the user workflow's notification setting: always, failure or never
+	NotifyOn     string `json:"notify_on"`
+	ExecutionURL string `json:"execution_url"`
+}
+
+// DecodeData decodes the event data into v, e.g. a *WorkflowCompletedData
+func (e *Event) DecodeData(v interface{}) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,181 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed files
+var embedded embed.FS
+
+const (
+	layoutName = "layout"
+	htmlExt    = ".html"
+	textExt    = ".txt"
+	sampleExt  = ".json"
+)
+
+var ErrNotFound = errors.New("email template not found")
+
+// Data is what every template is rendered with
+type Data struct {
+	Message string                 `json:"message"`
+	Info    map[string]interface{} `json:"info"`
+	// Year is filled in by Render when left empty
+	Year int `json:"year"`
+}
+
+// Rendered is a template rendered for one email
+type Rendered struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+type template struct {
+	html   *htmltemplate.Template
+	text   *texttemplate.Template
+	sample Data
+}
+
+// Engine renders named email templates. Each template <name> is a pair of files:
+// <name>.html defines "title" and "content" for the shared layout.html, and
+// <name>.txt defines "subject" and the plain-text "content" for layout.txt.
+// An optional <name>.json holds the sample data used for previews.
+type Engine struct {
+	templates map[string]*template
+}
+
+// NewEngine loads the templates from dir, or the ones built into the binary when dir is empty
+func NewEngine(dir string) (*Engine, error) {
+	if dir == "" {
+		files, err := fs.Sub(embedded, "files")
+		if err != nil {
+			return nil, err
+		}
+		return Load(files)
+	}
+	return Load(os.DirFS(dir))
+}
+
+// Load parses every template in fsys
+func Load(fsys fs.FS) (*Engine, error) {
+	htmlLayout, err := htmltemplate.ParseFS(fsys, layoutName+htmlExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html layout: %w", err)
+	}
+
+	textLayout, err := texttemplate.ParseFS(fsys, layoutName+textExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text layout: %w", err)
+	}
+
+	pages, err := fs.Glob(fsys, "*"+htmlExt)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{templates: make(map[string]*template)}
+	for _, page := range pages {
+		name := strings.TrimSuffix(page, htmlExt)
+		if name == layoutName {
+			continue
+		}
+
+		t := &template{}
+
+		html, err := htmlLayout.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if t.html, err = html.ParseFS(fsys, page); err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", page, err)
+		}
+
+		text, err := textLayout.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if t.text, err = text.ParseFS(fsys, name+textExt); err != nil {
+			return nil, fmt.Errorf("failed to parse plain-text alternative of %s: %w", name, err)
+		}
+
+		if sample, err := fs.ReadFile(fsys, name+sampleExt); err == nil {
+			if err := json.Unmarshal(sample, &t.sample); err != nil {
+				return nil, fmt.Errorf("failed to parse sample data %s: %w", name+sampleExt, err)
+			}
+		}
+
+		engine.templates[name] = t
+	}
+
+	return engine, nil
+}
+
+// Names lists the available templates in alphabetical order
+func (e *Engine) Names() []string {
+	names := make([]string, 0, len(e.templates))
+	for name := range e.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether a template with this name exists
+func (e *Engine) Has(name string) bool {
+	_, ok := e.templates[name]
+	return ok
+}
+
+// Render renders the subject, HTML and plain-text bodies of the named template.
+// Values in data are escaped for the context they appear in.
+func (e *Engine) Render(name string, data Data) (Rendered, error) {
+	t, ok := e.templates[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	if data.Year == 0 {
+		data.Year = time.Now().Year()
+	}
+	if data.Info == nil {
+		data.Info = map[string]interface{}{}
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, layoutName, data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, layoutName, data); err != nil {
+		return Rendered{}, fmt.Errorf("failed to render plain-text %s: %w", name, err)
+	}
+
+	return Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
+
+// Preview renders the named template with its sample data
+func (e *Engine) Preview(name string) (Rendered, error) {
+	t, ok := e.templates[name]
+	if !ok {
+		return Rendered{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return e.Render(name, t.sample)
+}
@@ -0,0 +1,102 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBuiltinTemplatesRender(t *testing.T) {
+	engine, err := NewEngine("")
+	if err != nil {
+		t.Fatalf("NewEngine returned error: %v", err)
+	}
+
+	names := engine.Names()
+	for _, want := range []string{"daily_summary", "general", "welcome"} {
+		if !engine.Has(want) {
+			t.Errorf("expected builtin template %q, got %v", want, names)
+		}
+	}
+
+	for _, name := range names {
+		rendered, err := engine.Preview(name)
+		if err != nil {
+			t.Errorf("Preview(%q) returned error: %v", name, err)
+			continue
+		}
+		if rendered.Subject == "" || rendered.HTML == "" || rendered.Text == "" {
+			t.Errorf("Preview(%q) rendered an empty part: %+v", name, rendered)
+		}
+		if strings.Contains(rendered.HTML+rendered.Text, "<no value>") {
+			t.Errorf("Preview(%q) references missing data", name)
+		}
+	}
+}
+
+func TestRenderEscapesUserInput(t *testing.T) {
+	engine, err := NewEngine("")
+	if err != nil {
+		t.Fatalf("NewEngine returned error: %v", err)
+	}
+
+	rendered, err := engine.Render("welcome", Data{
+		Message: `<script>alert("x")</script>`,
+		Info:    map[string]interface{}{"name": `<img src=x onerror=alert(1)>`},
+	})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+
+	if strings.Contains(rendered.HTML, "<script>") || strings.Contains(rendered.HTML, "<img") {
+		t.Fatalf("user input was not escaped:\n%s", rendered.HTML)
+	}
+	if !strings.Contains(rendered.HTML, "&lt;script&gt;") {
+		t.Errorf("expected escaped message in HTML")
+	}
+	if rendered.Subject != "Welcome to Kainos!" {
+		t.Errorf("unexpected subject %q", rendered.Subject)
+	}
+}
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`{{define "layout"}}<h1>{{template "title" .}}</h1>{{template "content" .}}{{end}}`)},
+		"layout.txt":  {Data: []byte(`{{define "layout"}}{{template "content" .}}{{end}}`)},
+		"report.html": {Data: []byte(`{{define "title"}}Report{{end}}{{define "content"}}<p>{{.Message}}</p>{{end}}`)},
+		"report.txt":  {Data: []byte(`{{define "subject"}}Your {{.Info.period}} report{{end}}{{define "content"}}{{.Message}}{{end}}`)},
+	}
+
+	engine, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	rendered, err := engine.Render("report", Data{Message: "AAPL & MSFT", Info: map[string]interface{}{"period": "daily"}})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if rendered.HTML != "<h1>Report</h1><p>AAPL &amp; MSFT</p>" {
+		t.Errorf("unexpected HTML %q", rendered.HTML)
+	}
+	if rendered.Text != "AAPL & MSFT" || rendered.Subject != "Your daily report" {
+		t.Errorf("unexpected text part %+v", rendered)
+	}
+
+	if _, err := engine.Render("missing", Data{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLoadRequiresPlainTextAlternative(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layout.html": {Data: []byte(`{{define "layout"}}{{template "content" .}}{{end}}`)},
+		"layout.txt":  {Data: []byte(`{{define "layout"}}{{template "content" .}}{{end}}`)},
+		"report.html": {Data: []byte(`{{define "title"}}Report{{end}}{{define "content"}}{{.Message}}{{end}}`)},
+	}
+
+	if _, err := Load(fsys); err == nil {
+		t.Fatal("expected an error for a template without a .txt alternative")
+	}
+}
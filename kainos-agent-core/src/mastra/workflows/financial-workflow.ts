@@ -95,7 +95,7 @@ const generateAnalysis = createStep({
   outputSchema: z.object({
     analysis: z.string(),
   }),
-  execute: async ({ inputData, mastra }) => {
+  execute: async ({ inputData, mastra, writer }) => {
     const stockData = inputData;
 
     if (!stockData) {
@@ -162,6 +162,8 @@ const generateAnalysis = createStep({
 
     for await (const chunk of response.textStream) {
       process.stdout.write(chunk);
+      // Streamed runs forward each chunk to the caller as a step output
+      await writer?.write(chunk);
       analysisText += chunk;
     }
 
@@ -0,0 +1,39 @@
+#!/bin/bash
+# Exports the histories of finished ExecuteMastraWorkflow runs from the dev cluster into the
+# replay tests' testdata. Run it once per worker build worth keeping, e.g. after letting the
+# previous release's worker finish a few runs:
+#
+#   scripts/export-replay-histories.sh baseline 3
+#
+# writes execute_mastra_workflow_baseline_<n>.json for the 3 latest closed runs.
+
+set -euo pipefail
+
+NAME=${1:?usage: $0 <name> [count]}
+COUNT=${2:-1}
+CONTAINER=${TEMPORAL_CONTAINER:-kainos-temporal}
+ADDRESS=${TEMPORAL_ADDRESS:-kainos-temporal:7233}
+OUT_DIR="$(cd "$(dirname "$0")/.." && pwd)/core/internal/execution/workflow/testdata/histories"
+
+temporal() {
+    docker exec "$CONTAINER" temporal "$@" --address "$ADDRESS"
+}
+
+IDS=$(temporal workflow list \
+    --query 'WorkflowType="ExecuteMastraWorkflow" AND ExecutionStatus!="Running"' \
+    --limit "$COUNT" --output json | jq -r '.[] | "\(.execution.workflowId) \(.execution.runId)"')
+
+if [ -z "$IDS" ]; then
+    echo "No closed ExecuteMastraWorkflow runs on $ADDRESS"
+    exit 1
+fi
+
+n=1
+while read -r workflow_id run_id; do
+    file="$OUT_DIR/execute_mastra_workflow_${NAME}_${n}.json"
+    temporal workflow show --workflow-id "$workflow_id" --run-id "$run_id" --output json > "$file"
+    echo "Exported $workflow_id/$run_id to $file"
+    n=$((n + 1))
+done <<< "$IDS"
+
+echo "Replay them with: cd core && go test ./internal/execution/workflow -run TestReplayHistories"